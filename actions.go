@@ -2,139 +2,18 @@ package chromedpundetected
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"math"
 	"math/rand"
-	"os"
 	"time"
 
 	"github.com/chromedp/cdproto/cdp"
-	"github.com/chromedp/cdproto/emulation"
 	"github.com/chromedp/cdproto/input"
-	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/chromedp"
 
 	"github.com/Davincible/chromedp-undetected/util/easyjson"
 )
 
-// Cookie is used to set browser cookies.
-type Cookie struct {
-	Name     string  `json:"name" yaml:"name"`
-	Value    string  `json:"value" yaml:"value"`
-	Domain   string  `json:"domain" yaml:"domain"`
-	Path     string  `json:"path" yaml:"path"`
-	Expires  float64 `json:"expires" yaml:"expires"`
-	HTTPOnly bool    `json:"httpOnly" yaml:"httpOnly"`
-	Secure   bool    `json:"secure" yaml:"secure"`
-}
-
-// UserAgentOverride overwrites the Chrome user agent.
-//
-// It's better to use this method than emulation.UserAgentOverride.
-func UserAgentOverride(userAgent string) chromedp.ActionFunc {
-	return func(ctx context.Context) error {
-		return cdp.Execute(ctx, "Network.setUserAgentOverride",
-			emulation.SetUserAgentOverride(userAgent), nil)
-	}
-}
-
-// LoadCookiesFromFile takes a file path to a json file containing cookies, and
-// loads in the cookies into the browser.
-func LoadCookiesFromFile(path string) chromedp.ActionFunc {
-	return chromedp.ActionFunc(func(ctx context.Context) error {
-		f, err := os.Open(path) //nolint:gosec
-		if err != nil {
-			return fmt.Errorf("failed to open file '%s': %w", path, err)
-		}
-
-		data, err := io.ReadAll(f)
-		if err != nil {
-			return err
-		}
-
-		if err := f.Close(); err != nil {
-			return err
-		}
-
-		var cookies []Cookie
-		if err := json.Unmarshal(data, &cookies); err != nil {
-			return fmt.Errorf("unmarshal cookies from json: %w", err)
-		}
-
-		return LoadCookies(cookies)(ctx)
-	})
-}
-
-// LoadCookies will load a set of cookies into the browser.
-func LoadCookies(cookies []Cookie) chromedp.ActionFunc {
-	return chromedp.ActionFunc(func(ctx context.Context) error {
-		for _, cookie := range cookies {
-			expiry := cdp.TimeSinceEpoch(time.Unix(int64(cookie.Expires), 0))
-			if err := network.SetCookie(cookie.Name, cookie.Value).
-				WithHTTPOnly(cookie.HTTPOnly).
-				WithSecure(cookie.Secure).
-				WithDomain(cookie.Domain).
-				WithPath(cookie.Path).
-				WithExpires(&expiry).
-				Do(ctx); err != nil {
-				return err
-			}
-		}
-
-		return nil
-	})
-}
-
-// SaveCookies extracts the cookies from the current URL and appends them to
-// provided array.
-func SaveCookies(cookies *[]Cookie) chromedp.ActionFunc {
-	return chromedp.ActionFunc(func(ctx context.Context) error {
-		c, err := network.GetCookies().Do(ctx)
-		if err != nil {
-			return err
-		}
-
-		for _, cookie := range c {
-			*cookies = append(*cookies, Cookie{
-				Name:     cookie.Name,
-				Value:    cookie.Value,
-				Domain:   cookie.Domain,
-				Path:     cookie.Path,
-				Expires:  cookie.Expires,
-				HTTPOnly: cookie.HTTPOnly,
-				Secure:   cookie.HTTPOnly,
-			})
-		}
-
-		return nil
-	})
-}
-
-// SaveCookiesTo extracts the cookies from the current page and saves them
-// as JSON to the provided path.
-func SaveCookiesTo(path string) chromedp.ActionFunc {
-	return chromedp.ActionFunc(func(ctx context.Context) error {
-		var c []Cookie
-
-		if err := SaveCookies(&c).Do(ctx); err != nil {
-			return err
-		}
-
-		b, err := json.MarshalIndent(c, "", "  ")
-		if err != nil {
-			return err
-		}
-
-		if err := os.WriteFile(path, b, 0644); err != nil { //nolint:gosec
-			return err
-		}
-
-		return nil
-	})
-}
-
 // RunCommandWithRes runs any Chrome Dev Tools command, with any params and
 // sets the result to the res parameter. Make sure it is a pointer.
 //
@@ -166,19 +45,16 @@ func BlockURLs(url ...string) chromedp.ActionFunc {
 	return RunCommand("Network.setBlockedURLs", map[string][]string{"urls": url})
 }
 
-// SendKeys does the same as chromedp.SendKeys excepts it randomly waits 100-500ms
-// between sending key presses.
+// SendKeys focuses the first element matching the selector and types the text
+// into it like a human would, see Type. To change the typing options, use
+// chromedp.Focus or Click followed by Type.
 func SendKeys(sel any, v string, opts ...chromedp.QueryOption) chromedp.ActionFunc {
 	return chromedp.ActionFunc(func(ctx context.Context) error {
-		for _, key := range v {
-			if err := chromedp.SendKeys(sel, string(key), opts...).Do(ctx); err != nil {
-				return err
-			}
-			s := rand.Int63n(100) + 100 //nolint:gosec
-			time.Sleep(time.Duration(s) * time.Millisecond)
+		if err := chromedp.Focus(sel, opts...).Do(ctx); err != nil {
+			return err
 		}
 
-		return nil
+		return Type(v).Do(ctx)
 	})
 }
 
@@ -224,29 +100,6 @@ var (
   window.addEventListener('mousemove', drawDotAtCoords);
   
 	`
-
-	// mouseTrackingJS adds a global state of the last mouse position so we can
-	// start moving from the current mouse position instead of 0,0.
-	mouseTrackingJS = `
-  // Global storage on window object for mouse position
-  window.globalMousePos = { x: 0, y: 0 };
-  
-  window.addEventListener('mousemove', (event) => {
-      const x = event.x;
-      const y = event.y;
-  
-  		if (x > 0 || y > 0) {
-        window.globalMousePos = { x, y };
-  		}
-  
-  		console.log(x, y, event);
-  });
-  
-  // Function to get the current mouse position or default to zero
-  function getCurrentMousePosition() {
-      return window.globalMousePos || { x: 0, y: 0 };
-  }
-  `
 )
 
 // MouseMoveOptions contains options for mouse movement.
@@ -256,21 +109,23 @@ type MouseMoveOptions struct {
 	delayMax       time.Duration
 	randomJitter   float64
 	visualizeMouse bool
+	generator      PathGenerator
+	targetWidth    float64
 }
 
 // Default values for mouse movement.
 var defaultMouseMoveOptions = MouseMoveOptions{
-	steps:          20,
-	delayMin:       5 * time.Millisecond,
-	delayMax:       50 * time.Millisecond,
-	randomJitter:   3,
+	randomJitter:   1,
 	visualizeMouse: false,
+	generator:      BezierPath{},
 }
 
 // MoveOptionSetter defines a function type to set mouse move options.
 type MoveOptionSetter func(*MouseMoveOptions)
 
 // WithSteps returns a MoveOptionSetter that sets the number of steps for the mouse movement.
+//
+// By default the number of steps follows from the duration of the movement.
 func WithSteps(s int) MoveOptionSetter {
 	return func(opt *MouseMoveOptions) {
 		opt.steps = s
@@ -278,6 +133,9 @@ func WithSteps(s int) MoveOptionSetter {
 }
 
 // WithDelayRange returns a MoveOptionSetter that sets the delay range between steps.
+//
+// By default the delays follow from the duration of the movement, which scales
+// with the distance and the target width.
 func WithDelayRange(min, max time.Duration) MoveOptionSetter {
 	return func(opt *MouseMoveOptions) {
 		opt.delayMin = min
@@ -286,6 +144,9 @@ func WithDelayRange(min, max time.Duration) MoveOptionSetter {
 }
 
 // WithRandomJitter returns a MoveOptionSetter that sets the random jitter to introduce in mouse movement.
+//
+// Every step but the last is offset by up to the jitter in pixels, in any
+// direction.
 func WithRandomJitter(jitter float64) MoveOptionSetter {
 	return func(opt *MouseMoveOptions) {
 		opt.randomJitter = jitter
@@ -299,9 +160,28 @@ func WithVisualizeMouse() MoveOptionSetter {
 	}
 }
 
+// WithPathGenerator returns a MoveOptionSetter that sets the generator of the
+// mouse path, e.g. BezierPath, WindMousePath or FittsPath. Defaults to
+// BezierPath.
+func WithPathGenerator(g PathGenerator) MoveOptionSetter {
+	return func(opt *MouseMoveOptions) {
+		opt.generator = g
+	}
+}
+
+// WithTargetWidth returns a MoveOptionSetter that sets the size of the target
+// in pixels. Smaller targets take longer to move to.
+func WithTargetWidth(width float64) MoveOptionSetter {
+	return func(opt *MouseMoveOptions) {
+		opt.targetWidth = width
+	}
+}
+
 // MoveMouseToPosition moves the mouse to the given position, mimic random human mouse movements.
 //
-// If desired you can tweak the mouse movement behavior, defaults are set to mimic human mouse movements.
+// The movement starts at the current cursor position, see CursorPosition, and
+// stays within the viewport. If desired you can tweak the mouse movement
+// behavior, defaults are set to mimic human mouse movements.
 func MoveMouseToPosition(x, y float64, setters ...MoveOptionSetter) chromedp.ActionFunc { //nolint:varnamelen
 	options := defaultMouseMoveOptions
 
@@ -310,82 +190,119 @@ func MoveMouseToPosition(x, y float64, setters ...MoveOptionSetter) chromedp.Act
 	}
 
 	return func(ctx context.Context) error {
-		var pos struct {
-			X float64 `json:"x"`
-			Y float64 `json:"y"`
-		}
-
-		if err := chromedp.Evaluate(`getCurrentMousePosition()`, &pos).Do(ctx); err != nil {
-			if err := chromedp.Evaluate(mouseTrackingJS, nil).Do(ctx); err != nil {
-				return fmt.Errorf("inject mouse position tracing js: %w", err)
-			}
-		}
-
 		// Add mouse visualization event listener if enabled.
 		if options.visualizeMouse {
-			if err := chromedp.Evaluate(addMouseVisualsJS, nil).Do(ctx); err != nil {
+			if err := EvaluateIsolated(addMouseVisualsJS, nil).Do(ctx); err != nil {
 				return fmt.Errorf("inject mouse visualization js: %w", err)
 			}
 
 			// Remove mouse visualization event listere after mouse movemvent is complete.
 			defer func() {
-				chromedp.Evaluate(removeMouseVisualsJS, nil).Do(ctx) //nolint:errcheck,gosec
+				EvaluateIsolated(removeMouseVisualsJS, nil).Do(ctx) //nolint:errcheck,gosec
 			}()
 		}
 
-		// Generate control points for Bezier curve.
-		control1 := point{
-			x: pos.X + rand.Float64()*math.Max(x-pos.X, (y-pos.Y)/2),
-			y: pos.Y + rand.Float64()*math.Max(y-pos.Y, (x-pos.X)/2),
+		return moveMouse(ctx, Point{X: x, Y: y}, options)
+	}
+}
+
+// moveMouse moves the cursor from its current position to the destination
+// along the path of the generator.
+func moveMouse(ctx context.Context, to Point, options MouseMoveOptions) error {
+	path, err := mousePath(ctx, to, options, randFor(ctx))
+	if err != nil {
+		return err
+	}
+
+	for _, p := range path {
+		if err := sleepContext(ctx, p.Delay); err != nil {
+			return err
 		}
 
-		control2 := point{
-			x: x - rand.Float64()*(x-pos.X),
-			y: y - rand.Float64()*(y-pos.Y),
+		if err := DispatchMouseEvent(&input.DispatchMouseEventParams{
+			Type:   input.MouseMoved,
+			X:      p.X,
+			Y:      p.Y,
+			Button: input.None,
+		}).Do(ctx); err != nil {
+			return err
 		}
+	}
 
-		start := point{x: pos.X, y: pos.Y}
-		end := point{x: x, y: y}
+	return nil
+}
 
-		for i := 0; i <= options.steps; i++ {
-			t := float64(i) / float64(options.steps)
-			point := bezierCubic(start, control1, control2, end, t)
+// mousePath returns the path of the generator from the cursor position to the
+// destination, with jitter and delays applied and clamped to the viewport.
+func mousePath(ctx context.Context, to Point, options MouseMoveOptions, rnd *rand.Rand) ([]PathPoint, error) {
+	// Start from where the last mouse event left the cursor.
+	var from Point
+	from.X, from.Y = CursorPosition(ctx)
 
-			targetX := point.x + rand.Float64()*options.randomJitter - options.randomJitter
-			targetY := point.y + rand.Float64()*options.randomJitter - options.randomJitter
+	width, height, err := viewportSize(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-			p := &input.DispatchMouseEventParams{
-				Type:   input.MouseMoved,
-				X:      targetX,
-				Y:      targetY,
-				Button: input.None,
-			}
+	to = clampPoint(to, width, height)
 
-			if err := p.Do(ctx); err != nil {
-				return err
-			}
+	path := options.generator.Path(PathRequest{
+		From:        from,
+		To:          to,
+		TargetWidth: options.targetWidth,
+		Steps:       options.steps,
+		Rand:        rnd,
+	})
 
-			sleepDuration := options.delayMin + time.Duration(rand.Int63n(int64(options.delayMax-options.delayMin)))
-			time.Sleep(sleepDuration)
+	// Callers move to the last point, often with a button held.
+	if len(path) == 0 {
+		return nil, ErrEmptyPath
+	}
+
+	for i := range path {
+		p := &path[i]
+
+		if i < len(path)-1 && options.randomJitter > 0 {
+			p.X += (2*rnd.Float64() - 1) * options.randomJitter
+			p.Y += (2*rnd.Float64() - 1) * options.randomJitter
 		}
 
-		return nil
+		p.Point = clampPoint(p.Point, width, height)
+
+		if options.delayMax > 0 {
+			p.Delay = options.delayMin
+			if options.delayMax > options.delayMin {
+				p.Delay += time.Duration(rnd.Int63n(int64(options.delayMax - options.delayMin)))
+			}
+		}
 	}
+
+	return path, nil
 }
 
-type point struct {
-	x, y float64
+// viewportSize returns the size of the layout viewport in CSS pixels.
+func viewportSize(ctx context.Context) (float64, float64, error) {
+	_, _, _, viewport, _, _, err := page.GetLayoutMetrics().Do(ctx) //nolint:dogsled
+	if err != nil {
+		return 0, 0, fmt.Errorf("get layout metrics: %w", err)
+	}
+
+	return float64(viewport.ClientWidth), float64(viewport.ClientHeight), nil
 }
 
-// Returns a point along a cubic Bézier curve.
-// t is the "progress" along the curve, should be between 0 and 1.
-func bezierCubic(p0, p1, p2, p3 point, t float64) point {
-	mt := 1 - t
-	mt2 := mt * mt
-	t2 := t * t
+// sleepContext sleeps for the duration, or until the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
 
-	return point{
-		x: mt2*mt*p0.x + 3*mt2*t*p1.x + 3*mt*t2*p2.x + t2*t*p3.x,
-		y: mt2*mt*p0.y + 3*mt2*t*p1.y + 3*mt*t2*p2.y + t2*t*p3.y,
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
 	}
 }
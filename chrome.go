@@ -4,13 +4,13 @@ package chromedpundetected
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"os"
 	"path"
 	"strconv"
 	"strings"
 
-	"github.com/Xuanwo/go-locale"
 	"github.com/chromedp/chromedp"
 	"github.com/google/uuid"
 	"golang.org/x/exp/slog"
@@ -22,6 +22,10 @@ var (
 )
 
 // New creates a context with an undetected Chrome executor.
+//
+// If the config requires every tab to be patched, e.g. with HeadlessNew, the
+// browser is started and the first tab is set up before New returns. Use
+// NewTab to open additional tabs with the same setup.
 func New(config Config) (context.Context, context.CancelFunc, error) {
 	var (
 		opts    []chromedp.ExecAllocatorOption
@@ -37,17 +41,40 @@ func New(config Config) (context.Context, context.CancelFunc, error) {
 		opts = append(opts, chromedp.ExecPath(config.ChromePath))
 	}
 
-	headlessOpts, closeFrameBuffer, err := headlessFlag(config)
+	if err := config.resolveGeoIP(); err != nil {
+		return nil, func() {}, err
+	}
+
+	langs, err := config.languages()
+	if err != nil {
+		return nil, func() {}, err
+	}
+
+	setup, err := config.targetSetup(langs)
 	if err != nil {
 		return nil, func() {}, err
 	}
 
-	if config.Language == "" {
-		opts = append(opts, localeFlag())
-	} else {
-		opts = append(opts, chromedp.Flag("lang", config.Language))
+	// Only the profile created here is modified, a user supplied profile
+	// keeps its own preferences.
+	if tempDir {
+		if err := writeLanguagePrefs(config.UserDataDir, langs); err != nil {
+			return nil, func() {}, fmt.Errorf("write language preferences: %w", err)
+		}
+	}
+
+	headlessOpts, closeFrameBuffer, err := headlessFlag(config)
+	if err != nil {
+		if tempDir {
+			_ = os.RemoveAll(config.UserDataDir) //nolint:errcheck
+		}
+
+		return nil, func() {}, err
 	}
 
+	opts = append(opts, localeFlags(langs)...)
+	opts = append(opts, geoFlags(config)...)
+
 	if len(config.Extensions) > 0 {
 		opts = append(opts, chromedp.Flag("load-extension", strings.Join(config.Extensions, ",")))
 	}
@@ -58,12 +85,13 @@ func New(config Config) (context.Context, context.CancelFunc, error) {
 	opts = append(opts, noSandboxFlag(config)...)
 	opts = append(opts, chromedp.UserDataDir(config.UserDataDir))
 	opts = append(opts, headlessOpts...)
+	opts = append(opts, personaFlags(config)...)
 	opts = append(opts, config.ChromeFlags...)
-	
+
 	if config.ChromePath != "" {
 		opts = append(opts, chromedp.ExecPath(config.ChromePath))
 	}
-	
+
 	ctx := context.Background()
 	if config.Ctx != nil {
 		ctx = config.Ctx
@@ -77,6 +105,12 @@ func New(config Config) (context.Context, context.CancelFunc, error) {
 	ctx, cancelA := chromedp.NewExecAllocator(ctx, opts...)
 	ctx, cancelC := chromedp.NewContext(ctx, config.ContextOptions...)
 
+	ctx = withTargetSetup(ctx, setup)
+
+	if config.RandomSeed != nil {
+		ctx = ContextWithRandomSeed(ctx, *config.RandomSeed)
+	}
+
 	cancel := func() {
 		cancelT()
 		cancelA()
@@ -91,6 +125,16 @@ func New(config Config) (context.Context, context.CancelFunc, error) {
 		}
 	}
 
+	if len(setup) > 0 {
+		if err := chromedp.Run(ctx, setup); err != nil {
+			cancel()
+
+			return nil, func() {}, fmt.Errorf("setup first tab: %w", err)
+		}
+
+		setupPopups(ctx)
+	}
+
 	return ctx, cancel, nil
 }
 
@@ -113,15 +157,6 @@ func debuggerAddrFlag(config Config) []chromedp.ExecAllocatorOption {
 	}
 }
 
-func localeFlag() chromedp.ExecAllocatorOption {
-	lang := "en-US"
-	if tag, err := locale.Detect(); err != nil && len(tag.String()) > 0 {
-		lang = tag.String()
-	}
-
-	return chromedp.Flag("lang", lang)
-}
-
 func noSandboxFlag(config Config) []chromedp.ExecAllocatorOption {
 	var opts []chromedp.ExecAllocatorOption
 
@@ -140,34 +175,6 @@ func logLevelFlag(config Config) chromedp.ExecAllocatorOption {
 	return chromedp.Flag("log-level", strconv.Itoa(config.LogLevel))
 }
 
-func headlessFlag(config Config) ([]chromedp.ExecAllocatorOption, func() error, error) {
-	var opts []chromedp.ExecAllocatorOption
-
-	cleanup := func() error { return nil }
-
-	if config.Headless {
-		var (
-			optx []chromedp.ExecAllocatorOption
-			err  error
-		)
-
-		optx, cleanup, err = headlessOpts()
-		if err != nil {
-			return nil, cleanup, err
-		}
-
-		opts = append(opts,
-			// chromedp.Flag("headless", true),
-			chromedp.Flag("window-size", "1920,1080"),
-			chromedp.Flag("start-maximized", true),
-			chromedp.Flag("no-sandbox", true),
-		)
-		opts = append(opts, optx...)
-	}
-
-	return opts, cleanup, nil
-}
-
 func getRandomPort() string {
 	l, err := net.Listen("tcp", "127.0.0.1:0")
 	if err == nil {
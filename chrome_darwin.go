@@ -10,6 +10,6 @@ import (
 	"github.com/chromedp/chromedp"
 )
 
-func headlessOpts() (opts []chromedp.ExecAllocatorOption, cleanup func() error, err error) {
+func headlessOpts(_ string) (opts []chromedp.ExecAllocatorOption, cleanup func() error, err error) {
 	return nil, nil, errors.New("headless mode not supported in darwin")
 }
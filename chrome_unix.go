@@ -12,9 +12,9 @@ import (
 	"github.com/chromedp/chromedp"
 )
 
-func headlessOpts() (opts []chromedp.ExecAllocatorOption, cleanup func() error, err error) {
+func headlessOpts(screenSize string) (opts []chromedp.ExecAllocatorOption, cleanup func() error, err error) {
 	// Create virtual display
-	frameBuffer, err := newFrameBuffer("1920x1080x24")
+	frameBuffer, err := newFrameBuffer(screenSize)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -0,0 +1,410 @@
+package chromedpundetected
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+)
+
+// Errors.
+var (
+	ErrNoNodes         = errors.New("selector matched no nodes")
+	ErrElementNotShown = errors.New("element has no box in the viewport")
+)
+
+// ClickOptions contains options for clicking an element.
+type ClickOptions struct {
+	button    input.MouseButton
+	count     int
+	modifiers []input.Modifier
+	hoverMin  time.Duration
+	hoverMax  time.Duration
+	holdMin   time.Duration
+	holdMax   time.Duration
+	move      []MoveOptionSetter
+	scroll    []ScrollOptionSetter
+	query     []chromedp.QueryOption
+}
+
+// Default values for clicking.
+var defaultClickOptions = ClickOptions{
+	button:   input.Left,
+	count:    1,
+	hoverMin: 80 * time.Millisecond,
+	hoverMax: 300 * time.Millisecond,
+	holdMin:  50 * time.Millisecond,
+	holdMax:  130 * time.Millisecond,
+}
+
+// ClickOptionSetter defines a function type to set click options.
+type ClickOptionSetter func(*ClickOptions)
+
+// WithDoubleClick returns a ClickOptionSetter that double clicks.
+func WithDoubleClick() ClickOptionSetter {
+	return func(opt *ClickOptions) {
+		opt.count = 2
+	}
+}
+
+// WithRightClick returns a ClickOptionSetter that clicks with the right button.
+func WithRightClick() ClickOptionSetter {
+	return WithClickButton(input.Right)
+}
+
+// WithClickButton returns a ClickOptionSetter that sets the mouse button.
+func WithClickButton(button input.MouseButton) ClickOptionSetter {
+	return func(opt *ClickOptions) {
+		opt.button = button
+	}
+}
+
+// WithClickModifiers returns a ClickOptionSetter that holds down the modifier
+// keys while clicking, e.g. input.ModifierCtrl to open a link in a new tab.
+func WithClickModifiers(modifiers ...input.Modifier) ClickOptionSetter {
+	return func(opt *ClickOptions) {
+		opt.modifiers = append(opt.modifiers, modifiers...)
+	}
+}
+
+// WithHoverDelay returns a ClickOptionSetter that sets the range of the time
+// the cursor rests on the element before pressing the button.
+func WithHoverDelay(min, max time.Duration) ClickOptionSetter {
+	return func(opt *ClickOptions) {
+		opt.hoverMin = min
+		opt.hoverMax = max
+	}
+}
+
+// WithHoldTime returns a ClickOptionSetter that sets the range of the time the
+// button is held down.
+func WithHoldTime(min, max time.Duration) ClickOptionSetter {
+	return func(opt *ClickOptions) {
+		opt.holdMin = min
+		opt.holdMax = max
+	}
+}
+
+// WithClickMoveOptions returns a ClickOptionSetter that sets the options of
+// the mouse movement to the element, e.g. the path generator.
+func WithClickMoveOptions(setters ...MoveOptionSetter) ClickOptionSetter {
+	return func(opt *ClickOptions) {
+		opt.move = append(opt.move, setters...)
+	}
+}
+
+// WithClickScrollOptions returns a ClickOptionSetter that sets the options of
+// scrolling the element into view.
+func WithClickScrollOptions(setters ...ScrollOptionSetter) ClickOptionSetter {
+	return func(opt *ClickOptions) {
+		opt.scroll = append(opt.scroll, setters...)
+	}
+}
+
+// WithClickQueryOptions returns a ClickOptionSetter that sets the options of
+// the element query, e.g. chromedp.ByQuery.
+func WithClickQueryOptions(opts ...chromedp.QueryOption) ClickOptionSetter {
+	return func(opt *ClickOptions) {
+		opt.query = append(opt.query, opts...)
+	}
+}
+
+// Click clicks the first element matching the selector like a human would.
+//
+// The element is scrolled into view with the mouse wheel if needed, see
+// ScrollIntoView, and the cursor moves to a random point in the visible part
+// of the element, most likely near its center. After resting on the element
+// for a moment, the button is pressed and released again.
+func Click(sel any, setters ...ClickOptionSetter) chromedp.ActionFunc {
+	options := defaultClickOptions
+
+	for _, setter := range setters {
+		setter(&options)
+	}
+
+	return func(ctx context.Context) error {
+		nodeID, err := queryNode(ctx, sel, options.query)
+		if err != nil {
+			return err
+		}
+
+		return clickNode(ctx, nodeID, options)
+	}
+}
+
+// clickNode scrolls the node into view, moves to it and clicks it.
+func clickNode(ctx context.Context, nodeID cdp.NodeID, options ClickOptions) (err error) {
+	rnd := randFor(ctx)
+
+	scroll := defaultScrollOptions
+	for _, setter := range options.scroll {
+		setter(&scroll)
+	}
+
+	box, err := scrollNodeIntoView(ctx, nodeID, scroll, rnd)
+	if err != nil {
+		return err
+	}
+
+	to := randomPointIn(box, rnd)
+
+	move := defaultMouseMoveOptions
+	move.targetWidth = math.Min(box.Width, box.Height)
+
+	for _, setter := range options.move {
+		setter(&move)
+	}
+
+	if err := moveMouse(ctx, to, move); err != nil {
+		return err
+	}
+
+	if err := sleepContext(ctx, randDuration(rnd, options.hoverMin, options.hoverMax)); err != nil {
+		return err
+	}
+
+	modifiers, err := pressModifiers(ctx, options.modifiers)
+	if err != nil {
+		return err
+	}
+
+	// Release the modifiers even if the click fails, so they don't stay held
+	// for the next input.
+	defer func() {
+		if releaseErr := releaseModifiers(ctx, options.modifiers); err == nil {
+			err = releaseErr
+		}
+	}()
+
+	return pressButton(ctx, to, modifiers, options, rnd)
+}
+
+// pressButton presses and releases the button at the point, as often as the
+// click count of the options.
+func pressButton(ctx context.Context, at Point, modifiers input.Modifier, options ClickOptions, rnd *rand.Rand) error {
+	button := options.button
+
+	for i := 1; i <= options.count; i++ {
+		if i > 1 {
+			// The pause between the clicks of a double click.
+			if err := sleepContext(ctx, randDuration(rnd, 60*time.Millisecond, 140*time.Millisecond)); err != nil {
+				return err
+			}
+		}
+
+		press := input.DispatchMouseEvent(input.MousePressed, at.X, at.Y).
+			WithButton(button).
+			WithButtons(buttonMask(button)).
+			WithClickCount(int64(i)).
+			WithModifiers(modifiers)
+
+		if err := DispatchMouseEvent(press).Do(ctx); err != nil {
+			return err
+		}
+
+		if err := sleepContext(ctx, randDuration(rnd, options.holdMin, options.holdMax)); err != nil {
+			return err
+		}
+
+		release := input.DispatchMouseEvent(input.MouseReleased, at.X, at.Y).
+			WithButton(button).
+			WithClickCount(int64(i)).
+			WithModifiers(modifiers)
+
+		if err := DispatchMouseEvent(release).Do(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// buttonMask returns the bit of the button in the buttons field of a mouse
+// event.
+func buttonMask(button input.MouseButton) int64 {
+	switch button {
+	case input.Left:
+		return 1
+	case input.Right:
+		return 2
+	case input.Middle:
+		return 4
+	case input.Back:
+		return 8
+	case input.Forward:
+		return 16
+	default:
+		return 0
+	}
+}
+
+// modifierKey is the key that sets a modifier.
+type modifierKey struct {
+	modifier input.Modifier
+	key      string
+	code     string
+	keyCode  int64
+}
+
+var modifierKeys = []modifierKey{ //nolint:gochecknoglobals
+	{input.ModifierAlt, "Alt", "AltLeft", 18},
+	{input.ModifierCtrl, "Control", "ControlLeft", 17},
+	{input.ModifierMeta, "Meta", "MetaLeft", 91},
+	{input.ModifierShift, "Shift", "ShiftLeft", 16},
+}
+
+func findModifierKey(modifier input.Modifier) (modifierKey, bool) {
+	for _, k := range modifierKeys {
+		if k.modifier == modifier {
+			return k, true
+		}
+	}
+
+	return modifierKey{}, false
+}
+
+// modifierKeyEvent returns the key event of the modifier key, with the held
+// modifiers.
+func modifierKeyEvent(typ input.KeyType, k modifierKey, held input.Modifier) *input.DispatchKeyEventParams {
+	return input.DispatchKeyEvent(typ).
+		WithKey(k.key).
+		WithCode(k.code).
+		WithWindowsVirtualKeyCode(k.keyCode).
+		WithNativeVirtualKeyCode(k.keyCode).
+		WithModifiers(held)
+}
+
+// pressModifiers presses the keys of the modifiers, and returns the combined
+// modifier bit field. On failure the keys pressed so far are released.
+func pressModifiers(ctx context.Context, modifiers []input.Modifier) (input.Modifier, error) {
+	var held input.Modifier
+
+	for i, m := range modifiers {
+		k, ok := findModifierKey(m)
+		if !ok {
+			releaseModifiers(ctx, modifiers[:i]) //nolint:errcheck,gosec
+			return 0, fmt.Errorf("unknown modifier %d", m)
+		}
+
+		held |= m
+
+		if err := modifierKeyEvent(input.KeyRawDown, k, held).Do(ctx); err != nil {
+			releaseModifiers(ctx, modifiers[:i]) //nolint:errcheck,gosec
+			return 0, err
+		}
+	}
+
+	return held, nil
+}
+
+// releaseModifiers releases the keys of the modifiers in reverse order.
+func releaseModifiers(ctx context.Context, modifiers []input.Modifier) error {
+	var held input.Modifier
+	for _, m := range modifiers {
+		held |= m
+	}
+
+	for i := len(modifiers) - 1; i >= 0; i-- {
+		k, _ := findModifierKey(modifiers[i])
+		held &^= modifiers[i]
+
+		if err := modifierKeyEvent(input.KeyUp, k, held).Do(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Box is a rectangle in CSS pixels, relative to the viewport.
+type Box struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Center returns the center of the box.
+func (b Box) Center() Point {
+	return Point{X: b.X + b.Width/2, Y: b.Y + b.Height/2}
+}
+
+// intersect returns the overlap of the boxes, with zero size if none.
+func (b Box) intersect(o Box) Box {
+	x1, y1 := math.Max(b.X, o.X), math.Max(b.Y, o.Y)
+	x2, y2 := math.Min(b.X+b.Width, o.X+o.Width), math.Min(b.Y+b.Height, o.Y+o.Height)
+
+	return Box{X: x1, Y: y1, Width: math.Max(0, x2-x1), Height: math.Max(0, y2-y1)}
+}
+
+// nodeBox returns the bounding box of the first content quad of the node.
+func nodeBox(ctx context.Context, nodeID cdp.NodeID) (Box, error) {
+	quads, err := dom.GetContentQuads().WithNodeID(nodeID).Do(ctx)
+	if err != nil {
+		return Box{}, fmt.Errorf("get content quads: %w", err)
+	}
+
+	if len(quads) == 0 || len(quads[0]) < 8 {
+		return Box{}, ErrElementNotShown
+	}
+
+	q := quads[0]
+	minX, minY, maxX, maxY := q[0], q[1], q[0], q[1]
+
+	for i := 2; i < len(q); i += 2 {
+		minX, maxX = math.Min(minX, q[i]), math.Max(maxX, q[i])
+		minY, maxY = math.Min(minY, q[i+1]), math.Max(maxY, q[i+1])
+	}
+
+	return Box{X: minX, Y: minY, Width: maxX - minX, Height: maxY - minY}, nil
+}
+
+// visibleBox returns the part of the box of the node within the viewport.
+func visibleBox(ctx context.Context, nodeID cdp.NodeID) (Box, error) {
+	box, err := nodeBox(ctx, nodeID)
+	if err != nil {
+		return Box{}, err
+	}
+
+	width, height, err := viewportSize(ctx)
+	if err != nil {
+		return Box{}, err
+	}
+
+	visible := box.intersect(Box{Width: width, Height: height})
+	if visible.Width < 1 || visible.Height < 1 {
+		return Box{}, ErrElementNotShown
+	}
+
+	return visible, nil
+}
+
+// randomPointIn returns a random point in the box, normally distributed
+// around the center and kept away from the edges.
+func randomPointIn(box Box, rnd *rand.Rand) Point {
+	c := box.Center()
+
+	offset := func(size float64) float64 {
+		limit := size * 0.4
+
+		return math.Max(-limit, math.Min(limit, rnd.NormFloat64()*size/6))
+	}
+
+	return Point{X: c.X + offset(box.Width), Y: c.Y + offset(box.Height)}
+}
+
+// randDuration returns a random duration between min and max.
+func randDuration(rnd *rand.Rand, min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+
+	return min + time.Duration(rnd.Int63n(int64(max-min)))
+}
@@ -0,0 +1,105 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandomPointIn(t *testing.T) {
+	box := Box{X: 100, Y: 200, Width: 80, Height: 30}
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+
+	var sumX, sumY float64
+
+	for i := 0; i < 1000; i++ {
+		p := randomPointIn(box, rnd)
+		require.True(t, p.X >= 108 && p.X <= 172, p)
+		require.True(t, p.Y >= 203 && p.Y <= 227, p)
+
+		sumX += p.X
+		sumY += p.Y
+	}
+
+	require.InDelta(t, 140, sumX/1000, 2)
+	require.InDelta(t, 215, sumY/1000, 1)
+}
+
+func TestBox(t *testing.T) {
+	require.Equal(t, Point{X: 50, Y: 25}, Box{Width: 100, Height: 50}.Center())
+	require.Equal(t, Box{X: 50, Y: 0, Width: 50, Height: 50}, Box{X: 50, Y: -50, Width: 100, Height: 100}.intersect(Box{Width: 100, Height: 100}))
+	require.Equal(t, 0.0, Box{X: 200, Width: 10, Height: 10}.intersect(Box{Width: 100, Height: 100}).Width)
+
+	require.Equal(t, int64(1), buttonMask(input.Left))
+	require.Equal(t, int64(2), buttonMask(input.Right))
+	require.Equal(t, int64(0), buttonMask(input.None))
+}
+
+func TestClick(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body>
+			<div style="height: 3000px"></div>
+			<button id="target" style="width: 120px; height: 40px">target</button>
+			<div style="height: 1000px"></div>
+			<script>
+				window.events = [];
+				const target = document.getElementById('target');
+				for (const type of ['mousedown', 'mouseup', 'click', 'dblclick', 'contextmenu']) {
+					target.addEventListener(type, (e) => {
+						if (type === 'contextmenu') e.preventDefault();
+						window.events.push(type + ':' + e.button + ':' + e.detail + ':' + e.ctrlKey + ':' + e.isTrusted);
+					});
+				}
+			</script>
+		</body></html>`)
+	}))
+	defer srv.Close()
+
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(30*time.Second),
+			WithHeadless(),
+		),
+		func(ctx context.Context) error {
+			var single, double, right []string
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate(srv.URL),
+				Click(`#target`, WithClickQueryOptions(chromedp.ByQuery), WithClickModifiers(input.ModifierCtrl)),
+				chromedp.Evaluate(`window.events.splice(0)`, &single),
+				Click(`#target`, WithClickQueryOptions(chromedp.ByQuery), WithDoubleClick()),
+				chromedp.Evaluate(`window.events.splice(0)`, &double),
+				Click(`#target`, WithClickQueryOptions(chromedp.ByQuery), WithRightClick()),
+				chromedp.Evaluate(`window.events.splice(0)`, &right),
+			); err != nil {
+				return err
+			}
+
+			want := [][]string{
+				{"mousedown:0:1:true:true", "mouseup:0:1:true:true", "click:0:1:true:true"},
+				{
+					"mousedown:0:1:false:true", "mouseup:0:1:false:true", "click:0:1:false:true",
+					"mousedown:0:2:false:true", "mouseup:0:2:false:true", "click:0:2:false:true", "dblclick:0:2:false:true",
+				},
+				{"mousedown:2:1:false:true", "contextmenu:2:1:false:true", "mouseup:2:1:false:true"},
+			}
+
+			for i, got := range [][]string{single, double, right} {
+				if fmt.Sprint(got) != fmt.Sprint(want[i]) {
+					return fmt.Errorf("got events %v, want %v", got, want[i])
+				}
+			}
+
+			return nil
+		},
+	)
+}
@@ -57,14 +57,67 @@ type Config struct {
 	//
 	// It will NOT use the '--headless' option, rather it will use a virtual display.
 	// Requires Xvfb to be installed, only available on Linux.
+	//
+	// Equivalent to setting HeadlessMode to HeadlessVirtualDisplay, which takes
+	// precedence when set.
 	Headless bool `json:"headless" yaml:"headless"`
 
+	// HeadlessMode selects how Chrome runs without a visible window. See the
+	// HeadlessMode constants for the available modes. If empty, the Headless
+	// field decides.
+	HeadlessMode HeadlessMode `json:"headlessMode" yaml:"headlessMode"`
+
 	// Extensions are the paths to the extensions to load.
 	Extensions []string `json:"extensions" yaml:"extensions"`
 
+	// Evasions are the JavaScript evasions injected into every document. See
+	// AllEvasions for the available evasions. None by default.
+	Evasions []Evasion `json:"evasions" yaml:"evasions"`
+
+	// Persona is the device the browser reports to be. If set, its user agent,
+	// client hints, languages, timezone, geometry and hardware are applied to
+	// every tab. See LoadPersona to load one from a file.
+	Persona *Persona `json:"persona,omitempty" yaml:"persona,omitempty"`
+
 	// language to be used otherwise system/OS defaults are used
 	// https://developer.chrome.com/docs/webstore/i18n/#localeTable
-	Language string
+	//
+	// Languages takes precedence when set.
+	Language string `json:"language" yaml:"language"`
+
+	// Languages are the preferred languages, most preferred first, each
+	// optionally weighted as in the Accept-Language header, e.g.
+	// ["nl-NL", "nl;q=0.9", "en;q=0.8"].
+	//
+	// They consistently drive the UI language, the Accept-Language header,
+	// navigator.language(s), the default Intl locale and, unless a
+	// UserDataDir is set, the accepted languages of the profile. By default
+	// the languages of the host are detected, falling back to DefaultLanguage.
+	Languages []string `json:"languages" yaml:"languages"`
+
+	// Timezone is the IANA timezone the browser reports, e.g.
+	// "Europe/Amsterdam". It is set for the browser process and overridden in
+	// every tab. Takes precedence over the persona timezone. By default the
+	// host timezone is used.
+	Timezone string `json:"timezone" yaml:"timezone"`
+
+	// Geolocation is the position reported through the geolocation API, with
+	// the permission granted. By default no position is available.
+	Geolocation *Geolocation `json:"geolocation,omitempty" yaml:"geolocation,omitempty"`
+
+	// GeoIPDatabase is the path to a MaxMind DB file, e.g. the GeoLite2 City
+	// database, used to look up the location of ExitIP.
+	GeoIPDatabase string `json:"geoIPDatabase" yaml:"geoIPDatabase"`
+
+	// ExitIP is the IP address the browser traffic leaves from, e.g. that of
+	// the proxy. If set together with GeoIPDatabase, the timezone, geolocation
+	// and languages are derived from its location, unless set explicitly.
+	ExitIP string `json:"exitIP" yaml:"exitIP"`
+
+	// RandomSeed seeds the random source the humanized actions draw from, so
+	// mouse paths, delays and typos repeat exactly between runs. By default
+	// every action is seeded with the time. See ContextWithRandomSeed.
+	RandomSeed *int64 `json:"randomSeed,omitempty" yaml:"randomSeed,omitempty"`
 }
 
 // NewConfig creates a new config object with defaults.
@@ -115,6 +168,14 @@ func WithHeadless() Option {
 	}
 }
 
+// WithHeadlessMode sets the headless mode, e.g. HeadlessNew to run without
+// a virtual display.
+func WithHeadlessMode(mode HeadlessMode) Option {
+	return func(c *Config) {
+		c.HeadlessMode = mode
+	}
+}
+
 // WithNoSandbox enable/disable sandbox. Disabled by default.
 func WithNoSandbox(b bool) Option {
 	return func(c *Config) {
@@ -151,3 +212,58 @@ func WithExtensions(extensions ...string) Option {
 		c.Extensions = append(c.Extensions, extensions...)
 	}
 }
+
+// WithEvasions adds JavaScript evasions to inject into every document, e.g.
+// WithEvasions(AllEvasions...).
+func WithEvasions(evasions ...Evasion) Option {
+	return func(c *Config) {
+		c.Evasions = append(c.Evasions, evasions...)
+	}
+}
+
+// WithPersona sets the device persona the browser reports to be.
+func WithPersona(p Persona) Option {
+	return func(c *Config) {
+		c.Persona = &p
+	}
+}
+
+// WithLanguages sets the preferred languages, each optionally weighted, e.g.
+// WithLanguages("nl-NL", "nl;q=0.9", "en;q=0.8").
+func WithLanguages(langs ...string) Option {
+	return func(c *Config) {
+		c.Languages = append(c.Languages, langs...)
+	}
+}
+
+// WithTimezone sets the IANA timezone, e.g. "Europe/Amsterdam".
+func WithTimezone(timezone string) Option {
+	return func(c *Config) {
+		c.Timezone = timezone
+	}
+}
+
+// WithGeolocation sets the position reported through the geolocation API,
+// with the accuracy in meters.
+func WithGeolocation(latitude, longitude, accuracy float64) Option {
+	return func(c *Config) {
+		c.Geolocation = &Geolocation{Latitude: latitude, Longitude: longitude, Accuracy: accuracy}
+	}
+}
+
+// WithGeoIP derives the timezone, geolocation and languages from the location
+// of the exit IP, looked up in a MaxMind DB file.
+func WithGeoIP(database, exitIP string) Option {
+	return func(c *Config) {
+		c.GeoIPDatabase = database
+		c.ExitIP = exitIP
+	}
+}
+
+// WithRandomSeed seeds the random source of the humanized actions, to make
+// runs reproducible.
+func WithRandomSeed(seed int64) Option {
+	return func(c *Config) {
+		c.RandomSeed = &seed
+	}
+}
@@ -0,0 +1,687 @@
+package chromedpundetected
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// Errors.
+var (
+	ErrUnknownCookieFormat = errors.New("unknown cookie format")
+)
+
+// CookieFormat is a file format for cookies.
+type CookieFormat string
+
+// Cookie formats.
+const (
+	// CookieFormatJSON is the JSON array of Cookie this package writes.
+	CookieFormatJSON CookieFormat = "json"
+
+	// CookieFormatNetscape is the cookies.txt format of curl and wget, and of
+	// browser extensions exporting for them.
+	CookieFormatNetscape CookieFormat = "netscape"
+
+	// CookieFormatEditThisCookie is the JSON export of the EditThisCookie and
+	// Cookie-Editor browser extensions.
+	CookieFormatEditThisCookie CookieFormat = "editthiscookie"
+
+	// CookieFormatPlaywright is the storage state file of Playwright. Only
+	// the cookies are read, and local storage is written empty.
+	CookieFormatPlaywright CookieFormat = "playwright"
+
+	// CookieFormatHAR is an HTTP archive. The cookies set by the responses
+	// are read, and those the requests sent that were not set in it.
+	CookieFormatHAR CookieFormat = "har"
+)
+
+// DetectCookieFormat returns the format of the cookie file contents.
+func DetectCookieFormat(data []byte) (CookieFormat, error) {
+	data = bytes.TrimSpace(data)
+
+	switch {
+	case bytes.HasPrefix(data, []byte("[")):
+		var objects []map[string]json.RawMessage
+		if err := json.Unmarshal(data, &objects); err != nil {
+			return "", fmt.Errorf("%w: %s", ErrUnknownCookieFormat, err.Error())
+		}
+
+		for _, object := range objects {
+			for _, key := range []string{"expirationDate", "hostOnly", "storeId"} {
+				if _, ok := object[key]; ok {
+					return CookieFormatEditThisCookie, nil
+				}
+			}
+		}
+
+		return CookieFormatJSON, nil
+	case bytes.HasPrefix(data, []byte("{")):
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(data, &object); err != nil {
+			return "", fmt.Errorf("%w: %s", ErrUnknownCookieFormat, err.Error())
+		}
+
+		if _, ok := object["log"]; ok {
+			return CookieFormatHAR, nil
+		}
+
+		if _, ok := object["cookies"]; ok {
+			return CookieFormatPlaywright, nil
+		}
+	default:
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "# Netscape HTTP Cookie File") || strings.HasPrefix(line, "# HTTP Cookie File") {
+				return CookieFormatNetscape, nil
+			}
+
+			if line != "" && (!strings.HasPrefix(line, "#") || strings.HasPrefix(line, httpOnlyNetscapePrefix)) {
+				if len(strings.Split(line, "\t")) == 7 {
+					return CookieFormatNetscape, nil
+				}
+
+				break
+			}
+		}
+	}
+
+	return "", ErrUnknownCookieFormat
+}
+
+// ReadCookies reads cookies in the format, or in the format detected from the
+// contents if empty.
+func ReadCookies(r io.Reader, format CookieFormat) ([]Cookie, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "" {
+		if format, err = DetectCookieFormat(data); err != nil {
+			return nil, err
+		}
+	}
+
+	var cookies []Cookie
+
+	switch format {
+	case CookieFormatJSON:
+		err = json.Unmarshal(data, &cookies)
+	case CookieFormatNetscape:
+		cookies, err = readNetscapeCookies(data)
+	case CookieFormatEditThisCookie:
+		cookies, err = readEditThisCookies(data)
+	case CookieFormatPlaywright:
+		cookies, err = readPlaywrightCookies(data)
+	case CookieFormatHAR:
+		cookies, err = readHARCookies(data)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCookieFormat, format)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read %s cookies: %w", format, err)
+	}
+
+	return cookies, nil
+}
+
+// ReadCookiesFile reads the cookies of a file in any of the formats.
+func ReadCookiesFile(path string) ([]Cookie, error) {
+	f, err := os.Open(path) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file '%s': %w", path, err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	return ReadCookies(f, "")
+}
+
+// WriteCookies writes the cookies in the format.
+func WriteCookies(w io.Writer, cookies []Cookie, format CookieFormat) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	switch format {
+	case CookieFormatJSON, "":
+		data, err = json.MarshalIndent(cookies, "", "  ")
+	case CookieFormatNetscape:
+		data = writeNetscapeCookies(cookies)
+	case CookieFormatEditThisCookie:
+		data, err = writeEditThisCookies(cookies)
+	case CookieFormatPlaywright:
+		data, err = writePlaywrightCookies(cookies)
+	case CookieFormatHAR:
+		data, err = writeHARCookies(cookies)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownCookieFormat, format)
+	}
+
+	if err != nil {
+		return fmt.Errorf("write %s cookies: %w", format, err)
+	}
+
+	_, err = w.Write(data)
+
+	return err
+}
+
+// WriteCookiesFile writes the cookies to a file in the format.
+func WriteCookiesFile(path string, cookies []Cookie, format CookieFormat) error {
+	var buf bytes.Buffer
+	if err := WriteCookies(&buf, cookies, format); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0644) //nolint:gosec
+}
+
+// withDomainDot returns the domain of a cookie that is sent to subdomains as
+// well, or of a host-only cookie, in the notation of Cookie.Domain.
+func withDomainDot(domain string, subdomains bool) string {
+	domain = strings.TrimPrefix(domain, ".")
+	if subdomains {
+		return "." + domain
+	}
+
+	return domain
+}
+
+// httpOnlyNetscapePrefix marks HTTP only cookies in cookies.txt files.
+const httpOnlyNetscapePrefix = "#HttpOnly_"
+
+// readNetscapeCookies parses a cookies.txt file, with lines of tab separated
+// domain, subdomain flag, path, secure flag, expiry, name and value.
+func readNetscapeCookies(data []byte) ([]Cookie, error) {
+	var cookies []Cookie
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly := strings.HasPrefix(text, httpOnlyNetscapePrefix)
+		if httpOnly {
+			text = strings.TrimPrefix(text, httpOnlyNetscapePrefix)
+		} else if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("line %d: want 7 fields, got %d", line, len(fields))
+		}
+
+		expires, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: expiry: %w", line, err)
+		}
+
+		cookies = append(cookies, Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Domain:   withDomainDot(fields[0], strings.EqualFold(fields[1], "TRUE")),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Expires:  expires,
+			Session:  expires <= 0,
+			HTTPOnly: httpOnly,
+		})
+	}
+
+	return cookies, scanner.Err()
+}
+
+// writeNetscapeCookies writes a cookies.txt file, session cookies with an
+// expiry of 0.
+func writeNetscapeCookies(cookies []Cookie) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString("# Netscape HTTP Cookie File\n\n")
+
+	flag := func(b bool) string {
+		if b {
+			return "TRUE"
+		}
+
+		return "FALSE"
+	}
+
+	for _, c := range cookies {
+		if c.HTTPOnly {
+			buf.WriteString(httpOnlyNetscapePrefix)
+		}
+
+		var expires int64
+		if !c.IsSession() {
+			expires = int64(c.Expires)
+		}
+
+		fmt.Fprintf(&buf, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			c.Domain, flag(!c.HostOnly()), c.Path, flag(c.Secure), expires, c.Name, c.Value)
+	}
+
+	return buf.Bytes()
+}
+
+// editThisCookie is a cookie as exported by EditThisCookie and Cookie-Editor,
+// which use the cookie format of the Chrome extension API.
+type editThisCookie struct {
+	Domain         string   `json:"domain"`
+	ExpirationDate *float64 `json:"expirationDate,omitempty"`
+	HostOnly       bool     `json:"hostOnly"`
+	HTTPOnly       bool     `json:"httpOnly"`
+	Name           string   `json:"name"`
+	Path           string   `json:"path"`
+	SameSite       *string  `json:"sameSite"`
+	Secure         bool     `json:"secure"`
+	Session        bool     `json:"session"`
+	StoreID        string   `json:"storeId,omitempty"`
+	Value          string   `json:"value"`
+}
+
+// extensionSameSite maps the SameSite values of the Chrome extension API.
+var extensionSameSite = map[string]network.CookieSameSite{ //nolint:gochecknoglobals
+	"no_restriction": network.CookieSameSiteNone,
+	"lax":            network.CookieSameSiteLax,
+	"strict":         network.CookieSameSiteStrict,
+	"unspecified":    "",
+}
+
+func readEditThisCookies(data []byte) ([]Cookie, error) {
+	var exported []editThisCookie
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return nil, err
+	}
+
+	cookies := make([]Cookie, 0, len(exported))
+
+	for _, e := range exported {
+		c := Cookie{
+			Name:     e.Name,
+			Value:    e.Value,
+			Domain:   withDomainDot(e.Domain, !e.HostOnly),
+			Path:     e.Path,
+			HTTPOnly: e.HTTPOnly,
+			Secure:   e.Secure,
+			Session:  e.Session || e.ExpirationDate == nil,
+		}
+
+		if e.ExpirationDate != nil {
+			c.Expires = *e.ExpirationDate
+		}
+
+		if e.SameSite != nil {
+			c.SameSite = extensionSameSite[strings.ToLower(*e.SameSite)]
+		}
+
+		cookies = append(cookies, c)
+	}
+
+	return cookies, nil
+}
+
+func writeEditThisCookies(cookies []Cookie) ([]byte, error) {
+	exported := make([]editThisCookie, 0, len(cookies))
+
+	for _, c := range cookies {
+		sameSite := "unspecified"
+
+		for k, v := range extensionSameSite {
+			if v == c.SameSite && v != "" {
+				sameSite = k
+			}
+		}
+
+		e := editThisCookie{
+			Domain:   c.Domain,
+			HostOnly: c.HostOnly(),
+			HTTPOnly: c.HTTPOnly,
+			Name:     c.Name,
+			Path:     c.Path,
+			SameSite: &sameSite,
+			Secure:   c.Secure,
+			Session:  c.IsSession(),
+			StoreID:  "0",
+			Value:    c.Value,
+		}
+
+		if !c.IsSession() {
+			expires := c.Expires
+			e.ExpirationDate = &expires
+		}
+
+		exported = append(exported, e)
+	}
+
+	return json.MarshalIndent(exported, "", "    ")
+}
+
+// playwrightCookie is a cookie of a Playwright storage state.
+type playwrightCookie struct {
+	Name     string                 `json:"name"`
+	Value    string                 `json:"value"`
+	Domain   string                 `json:"domain"`
+	Path     string                 `json:"path"`
+	Expires  float64                `json:"expires"`
+	HTTPOnly bool                   `json:"httpOnly"`
+	Secure   bool                   `json:"secure"`
+	SameSite network.CookieSameSite `json:"sameSite"`
+}
+
+// playwrightState is a Playwright storage state.
+type playwrightState struct {
+	Cookies []playwrightCookie `json:"cookies"`
+	Origins []json.RawMessage  `json:"origins"`
+}
+
+func readPlaywrightCookies(data []byte) ([]Cookie, error) {
+	var state playwrightState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+
+	cookies := make([]Cookie, 0, len(state.Cookies))
+
+	for _, p := range state.Cookies {
+		cookies = append(cookies, Cookie{
+			Name:     p.Name,
+			Value:    p.Value,
+			Domain:   p.Domain,
+			Path:     p.Path,
+			Expires:  p.Expires,
+			Session:  p.Expires <= 0,
+			HTTPOnly: p.HTTPOnly,
+			Secure:   p.Secure,
+			SameSite: p.SameSite,
+		})
+	}
+
+	return cookies, nil
+}
+
+// writePlaywrightCookies writes a storage state, with session cookies expiring
+// at -1. Playwright requires a SameSite value, unspecified is written as Lax,
+// the default the browser applies.
+func writePlaywrightCookies(cookies []Cookie) ([]byte, error) {
+	state := playwrightState{
+		Cookies: make([]playwrightCookie, 0, len(cookies)),
+		Origins: []json.RawMessage{},
+	}
+
+	for _, c := range cookies {
+		p := playwrightCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  c.Expires,
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+			SameSite: c.SameSite,
+		}
+
+		if c.IsSession() {
+			p.Expires = -1
+		}
+
+		if p.SameSite == "" {
+			p.SameSite = network.CookieSameSiteLax
+		}
+
+		state.Cookies = append(state.Cookies, p)
+	}
+
+	return json.MarshalIndent(state, "", "  ")
+}
+
+// harCookie is a cookie of an HTTP archive request or response.
+type harCookie struct {
+	Name     string                 `json:"name"`
+	Value    string                 `json:"value"`
+	Path     string                 `json:"path,omitempty"`
+	Domain   string                 `json:"domain,omitempty"`
+	Expires  string                 `json:"expires,omitempty"`
+	HTTPOnly bool                   `json:"httpOnly,omitempty"`
+	Secure   bool                   `json:"secure,omitempty"`
+	SameSite network.CookieSameSite `json:"sameSite,omitempty"`
+}
+
+// harMessage is the part of a request or response with its cookies.
+type harMessage struct {
+	Method      string            `json:"method,omitempty"`
+	URL         string            `json:"url,omitempty"`
+	Status      int               `json:"status,omitempty"`
+	StatusText  *string           `json:"statusText,omitempty"`
+	HTTPVersion string            `json:"httpVersion"`
+	Cookies     []harCookie       `json:"cookies"`
+	Headers     []json.RawMessage `json:"headers"`
+	QueryString *[]any            `json:"queryString,omitempty"`
+	Content     *harContent       `json:"content,omitempty"`
+	RedirectURL *string           `json:"redirectURL,omitempty"`
+	HeadersSize int               `json:"headersSize"`
+	BodySize    int               `json:"bodySize"`
+}
+
+// harContent is the content of a response.
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+// harEntry is a request of an HTTP archive.
+type harEntry struct {
+	StartedDateTime time.Time          `json:"startedDateTime"`
+	Time            float64            `json:"time"`
+	Request         harMessage         `json:"request"`
+	Response        harMessage         `json:"response"`
+	Cache           struct{}           `json:"cache"`
+	Timings         map[string]float64 `json:"timings"`
+}
+
+// harFile is an HTTP archive.
+type harFile struct {
+	Log struct {
+		Version string `json:"version"`
+		Creator struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+// readHARCookies collects the cookies of an HTTP archive in the order they
+// appear, later cookies replacing earlier ones of the same name, domain and
+// path. Cookies set by a response are taken with all their attributes, cookies
+// only seen in requests are taken as host-only cookies of the request URL.
+func readHARCookies(data []byte) ([]Cookie, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, err
+	}
+
+	var cookies []Cookie
+
+	index := make(map[string]int)
+	set := func(c Cookie, replace bool) {
+		key := c.Name + "\x00" + c.Domain + "\x00" + c.Path
+		if i, ok := index[key]; ok {
+			if replace {
+				cookies[i] = c
+			}
+
+			return
+		}
+
+		index[key] = len(cookies)
+		cookies = append(cookies, c)
+	}
+
+	for _, entry := range har.Log.Entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("request url: %w", err)
+		}
+
+		for _, h := range entry.Response.Cookies {
+			c := Cookie{
+				Name:     h.Name,
+				Value:    h.Value,
+				Domain:   h.Domain,
+				Path:     h.Path,
+				HTTPOnly: h.HTTPOnly,
+				Secure:   h.Secure,
+				SameSite: h.SameSite,
+				Session:  h.Expires == "",
+			}
+
+			if c.Domain == "" {
+				c.Domain = u.Hostname()
+			} else if !strings.HasPrefix(c.Domain, ".") {
+				// A domain attribute makes a domain cookie.
+				c.Domain = "." + c.Domain
+			}
+
+			if c.Path == "" {
+				c.Path = "/"
+			}
+
+			if h.Expires != "" {
+				expires, err := time.Parse(time.RFC3339Nano, h.Expires)
+				if err != nil {
+					return nil, fmt.Errorf("cookie %q expiry: %w", h.Name, err)
+				}
+
+				c.Expires = float64(expires.UnixNano()) / float64(time.Second)
+			}
+
+			set(c, true)
+		}
+
+		for _, h := range entry.Request.Cookies {
+			if sentCookie(cookies, h.Name, u.Hostname()) {
+				continue
+			}
+
+			set(Cookie{
+				Name:    h.Name,
+				Value:   h.Value,
+				Domain:  u.Hostname(),
+				Path:    "/",
+				Secure:  u.Scheme == "https",
+				Session: true,
+			}, false)
+		}
+	}
+
+	return cookies, nil
+}
+
+// sentCookie returns whether one of the cookies of the name is sent to the
+// host.
+func sentCookie(cookies []Cookie, name, host string) bool {
+	for _, c := range cookies {
+		if c.Name != name {
+			continue
+		}
+
+		if c.Domain == host || (!c.HostOnly() && (host == c.Domain[1:] || strings.HasSuffix(host, c.Domain))) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeHARCookies writes an HTTP archive with a request to each domain, whose
+// response sets the cookies of the domain.
+func writeHARCookies(cookies []Cookie) ([]byte, error) {
+	var har harFile
+
+	har.Log.Version = "1.2"
+	har.Log.Creator.Name = "chromedp-undetected"
+	har.Log.Creator.Version = "1"
+	har.Log.Entries = []harEntry{}
+
+	byDomain := make(map[string][]Cookie)
+	for _, c := range cookies {
+		domain := strings.TrimPrefix(c.Domain, ".")
+		byDomain[domain] = append(byDomain[domain], c)
+	}
+
+	domains := make([]string, 0, len(byDomain))
+	for domain := range byDomain {
+		domains = append(domains, domain)
+	}
+
+	sort.Strings(domains)
+
+	empty, queryString := "", []any{}
+
+	for _, domain := range domains {
+		entry := harEntry{
+			StartedDateTime: time.Now().UTC(),
+			Request: harMessage{
+				Method:      "GET",
+				URL:         "https://" + domain + "/",
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []harCookie{},
+				Headers:     []json.RawMessage{},
+				QueryString: &queryString,
+				HeadersSize: -1,
+				BodySize:    -1,
+			},
+			Response: harMessage{
+				Status:      200,
+				StatusText:  &empty,
+				HTTPVersion: "HTTP/1.1",
+				Headers:     []json.RawMessage{},
+				Content:     &harContent{},
+				RedirectURL: &empty,
+				HeadersSize: -1,
+				BodySize:    -1,
+			},
+			Timings: map[string]float64{"send": 0, "wait": 0, "receive": 0},
+		}
+
+		for _, c := range byDomain[domain] {
+			h := harCookie{
+				Name:     c.Name,
+				Value:    c.Value,
+				Path:     c.Path,
+				HTTPOnly: c.HTTPOnly,
+				Secure:   c.Secure,
+				SameSite: c.SameSite,
+			}
+
+			// Host-only cookies are set without a domain attribute.
+			if !c.HostOnly() {
+				h.Domain = c.Domain
+			}
+
+			if !c.IsSession() {
+				h.Expires = time.Unix(0, int64(c.Expires*float64(time.Second))).UTC().Format(time.RFC3339Nano)
+			}
+
+			entry.Response.Cookies = append(entry.Response.Cookies, h)
+		}
+
+		har.Log.Entries = append(har.Log.Entries, entry)
+	}
+
+	return json.MarshalIndent(har, "", "  ")
+}
@@ -0,0 +1,124 @@
+package chromedpundetected
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectCookieFormat(t *testing.T) {
+	for file, want := range map[string]CookieFormat{
+		"testdata/cookies.txt":                 CookieFormatNetscape,
+		"testdata/cookies-editthiscookie.json": CookieFormatEditThisCookie,
+		"testdata/cookies-playwright.json":     CookieFormatPlaywright,
+		"testdata/cookies.har":                 CookieFormatHAR,
+	} {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+
+		format, err := DetectCookieFormat(data)
+		require.NoError(t, err, file)
+		require.Equal(t, want, format, file)
+	}
+
+	format, err := DetectCookieFormat([]byte(`[{"name": "a", "value": "1", "expires": -1}]`))
+	require.NoError(t, err)
+	require.Equal(t, CookieFormatJSON, format)
+
+	_, err = DetectCookieFormat([]byte("name=value; Path=/"))
+	require.ErrorIs(t, err, ErrUnknownCookieFormat)
+}
+
+func TestReadCookiesFile(t *testing.T) {
+	for _, file := range []string{
+		"testdata/cookies.txt",
+		"testdata/cookies-editthiscookie.json",
+		"testdata/cookies-playwright.json",
+		"testdata/cookies.har",
+	} {
+		cookies, err := ReadCookiesFile(file)
+		require.NoError(t, err, file)
+		require.GreaterOrEqual(t, len(cookies), 2, file)
+
+		sid, csrf := cookies[0], cookies[1]
+
+		require.Equal(t, "sid", sid.Name, file)
+		require.Equal(t, "abc123", sid.Value, file)
+		require.Equal(t, ".example.com", sid.Domain, file)
+		require.True(t, sid.Secure, file)
+		require.False(t, sid.IsSession(), file)
+		require.InDelta(t, 1893456000, sid.Expires, 1, file)
+
+		require.Equal(t, "csrf", csrf.Name, file)
+		require.Equal(t, "www.example.com", csrf.Domain, file)
+		require.Equal(t, "/account", csrf.Path, file)
+		require.True(t, csrf.HostOnly(), file)
+		require.True(t, csrf.IsSession(), file)
+		require.NoError(t, csrf.Validate(), file)
+	}
+}
+
+func TestReadHARCookies(t *testing.T) {
+	cookies, err := ReadCookiesFile("testdata/cookies.har")
+	require.NoError(t, err)
+	require.Len(t, cookies, 3)
+
+	// The response replaced the cookie the request sent.
+	require.Equal(t, network.CookieSameSiteNone, cookies[0].SameSite)
+	require.InDelta(t, 1893456000.25, cookies[0].Expires, 1e-3)
+
+	// Cookies only sent are taken as session cookies of the request host.
+	require.Equal(t, Cookie{Name: "tracker", Value: "t1", Domain: "www.example.com", Path: "/", Secure: true, Session: true}, cookies[2])
+}
+
+func TestWriteCookies(t *testing.T) {
+	cookies := []Cookie{
+		{
+			Name: "sid", Value: "abc123", Domain: ".example.com", Path: "/", Expires: 1893456000,
+			HTTPOnly: true, Secure: true, SameSite: network.CookieSameSiteStrict,
+		},
+		{Name: "csrf", Value: "xyz", Domain: "www.example.com", Path: "/account", Expires: -1, Session: true},
+	}
+
+	for _, format := range []CookieFormat{
+		CookieFormatJSON,
+		CookieFormatNetscape,
+		CookieFormatEditThisCookie,
+		CookieFormatPlaywright,
+		CookieFormatHAR,
+	} {
+		path := filepath.Join(t.TempDir(), "cookies")
+		require.NoError(t, WriteCookiesFile(path, cookies, format), format)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		detected, err := DetectCookieFormat(data)
+		require.NoError(t, err, format)
+		require.Equal(t, format, detected)
+
+		got, err := ReadCookies(bytes.NewReader(data), "")
+		require.NoError(t, err, format)
+		require.Len(t, got, 2, format)
+
+		for i, want := range cookies {
+			require.Equal(t, want.Name, got[i].Name, format)
+			require.Equal(t, want.Value, got[i].Value, format)
+			require.Equal(t, want.Domain, got[i].Domain, format)
+			require.Equal(t, want.Path, got[i].Path, format)
+			require.Equal(t, want.HTTPOnly, got[i].HTTPOnly, format)
+			require.Equal(t, want.Secure, got[i].Secure, format)
+			require.Equal(t, want.IsSession(), got[i].IsSession(), format)
+
+			if !want.IsSession() {
+				require.InDelta(t, want.Expires, got[i].Expires, 1e-3, format)
+			}
+		}
+	}
+
+	require.ErrorIs(t, WriteCookies(&bytes.Buffer{}, nil, "xml"), ErrUnknownCookieFormat)
+}
@@ -0,0 +1,469 @@
+package chromedpundetected
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+)
+
+// Errors.
+var (
+	ErrInvalidCookie = errors.New("invalid cookie")
+)
+
+// Cookie is a browser cookie, with everything the browser reports about it,
+// so a saved cookie is restored exactly as it was.
+type Cookie struct {
+	Name  string `json:"name" yaml:"name"`
+	Value string `json:"value" yaml:"value"`
+
+	// Domain is the domain the cookie is sent to. Domain cookies start with a
+	// dot and are sent to subdomains too, host-only cookies do not.
+	Domain string `json:"domain" yaml:"domain"`
+	Path   string `json:"path" yaml:"path"`
+
+	// Expires is the expiry as seconds since the UNIX epoch. Cookies that
+	// have no expiry, or an expiry of 0 or less, are session cookies.
+	Expires  float64 `json:"expires" yaml:"expires"`
+	Session  bool    `json:"session,omitempty" yaml:"session,omitempty"`
+	HTTPOnly bool    `json:"httpOnly" yaml:"httpOnly"`
+	Secure   bool    `json:"secure" yaml:"secure"`
+
+	SameSite  network.CookieSameSite `json:"sameSite,omitempty" yaml:"sameSite,omitempty"`
+	Priority  network.CookiePriority `json:"priority,omitempty" yaml:"priority,omitempty"`
+	SameParty bool                   `json:"sameParty,omitempty" yaml:"sameParty,omitempty"`
+
+	// SourceScheme and SourcePort are the scheme and port of the origin that
+	// set the cookie. A port of -1 or 0 is unspecified.
+	SourceScheme network.CookieSourceScheme `json:"sourceScheme,omitempty" yaml:"sourceScheme,omitempty"`
+	SourcePort   int64                      `json:"sourcePort,omitempty" yaml:"sourcePort,omitempty"`
+
+	// PartitionKey is the top-level site of a partitioned cookie, e.g.
+	// "https://example.com". Cookies with an opaque partition key belong to
+	// a context that can not be recreated, and are not loaded.
+	PartitionKey       string `json:"partitionKey,omitempty" yaml:"partitionKey,omitempty"`
+	PartitionKeyOpaque bool   `json:"partitionKeyOpaque,omitempty" yaml:"partitionKeyOpaque,omitempty"`
+}
+
+// Cookie name prefixes that restrict how a cookie can be set.
+const (
+	hostCookiePrefix   = "__Host-"
+	secureCookiePrefix = "__Secure-"
+)
+
+// CookieFromNetwork returns the cookie as reported by the browser.
+func CookieFromNetwork(c *network.Cookie) Cookie {
+	return Cookie{
+		Name:               c.Name,
+		Value:              c.Value,
+		Domain:             c.Domain,
+		Path:               c.Path,
+		Expires:            c.Expires,
+		Session:            c.Session,
+		HTTPOnly:           c.HTTPOnly,
+		Secure:             c.Secure,
+		SameSite:           c.SameSite,
+		Priority:           c.Priority,
+		SameParty:          c.SameParty,
+		SourceScheme:       c.SourceScheme,
+		SourcePort:         c.SourcePort,
+		PartitionKey:       c.PartitionKey,
+		PartitionKeyOpaque: c.PartitionKeyOpaque,
+	}
+}
+
+// IsSession returns whether the cookie lasts until the browser closes.
+func (c Cookie) IsSession() bool {
+	return c.Session || c.Expires <= 0
+}
+
+// HostOnly returns whether the cookie is only sent to its exact domain.
+func (c Cookie) HostOnly() bool {
+	return !strings.HasPrefix(c.Domain, ".")
+}
+
+// Validate checks that the browser would accept the cookie: cookies with the
+// __Secure- prefix must be secure, __Host- cookies must also be host-only with
+// path "/", and partitioned cookies must be secure.
+func (c Cookie) Validate() error {
+	fail := func(reason string) error {
+		return fmt.Errorf("%w: %q: %s", ErrInvalidCookie, c.Name, reason)
+	}
+
+	switch {
+	case c.Name == "" && c.Value == "":
+		return fail("no name or value")
+	case strings.TrimPrefix(c.Domain, ".") == "":
+		return fail("no domain")
+	case strings.HasPrefix(c.Name, secureCookiePrefix) && !c.Secure:
+		return fail("__Secure- cookie must be secure")
+	case strings.HasPrefix(c.Name, hostCookiePrefix) && !c.Secure:
+		return fail("__Host- cookie must be secure")
+	case strings.HasPrefix(c.Name, hostCookiePrefix) && !c.HostOnly():
+		return fail("__Host- cookie must not have a domain")
+	case strings.HasPrefix(c.Name, hostCookiePrefix) && c.Path != "/":
+		return fail("__Host- cookie must have path /")
+	case c.PartitionKey != "" && !c.Secure:
+		return fail("partitioned cookie must be secure")
+	case c.SameSite == network.CookieSameSiteNone && !c.Secure:
+		return fail("SameSite=None cookie must be secure")
+	}
+
+	return nil
+}
+
+// Param returns the parameters to set the cookie with. Host-only cookies are
+// set for the URL of their origin, as setting a domain would make them domain
+// cookies.
+func (c Cookie) Param() *network.CookieParam {
+	param := &network.CookieParam{
+		Name:         c.Name,
+		Value:        c.Value,
+		Path:         c.Path,
+		Secure:       c.Secure,
+		HTTPOnly:     c.HTTPOnly,
+		SameSite:     c.SameSite,
+		Priority:     c.Priority,
+		SameParty:    c.SameParty,
+		SourceScheme: c.SourceScheme,
+		PartitionKey: c.PartitionKey,
+	}
+
+	if c.SourcePort > 0 {
+		param.SourcePort = c.SourcePort
+	}
+
+	if c.HostOnly() {
+		param.URL = c.url()
+	} else {
+		param.Domain = c.Domain
+	}
+
+	if !c.IsSession() {
+		sec, frac := math.Modf(c.Expires)
+		expires := cdp.TimeSinceEpoch(time.Unix(int64(sec), int64(frac*float64(time.Second))))
+		param.Expires = &expires
+	}
+
+	return param
+}
+
+// url returns the URL of the origin of the cookie, with its path.
+func (c Cookie) url() string {
+	scheme := "http"
+	if c.SourceScheme == network.CookieSourceSchemeSecure ||
+		(c.SourceScheme != network.CookieSourceSchemeNonSecure && c.Secure) {
+		scheme = "https"
+	}
+
+	host := c.Domain
+	if port := c.SourcePort; port > 0 && !(scheme == "https" && port == 443) && !(scheme == "http" && port == 80) {
+		host += ":" + strconv.FormatInt(port, 10)
+	}
+
+	path := c.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/"
+	}
+
+	return (&url.URL{Scheme: scheme, Host: host, Path: path}).String()
+}
+
+// LoadCookiesFromFile takes a file path to a file containing cookies, in any
+// of the formats of ReadCookies, and loads in the cookies into the browser.
+func LoadCookiesFromFile(path string) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		cookies, err := ReadCookiesFile(path)
+		if err != nil {
+			return err
+		}
+
+		return LoadCookies(cookies)(ctx)
+	})
+}
+
+// LoadCookies will load a set of cookies into the browser. Session cookies
+// stay session cookies, and cookies with an opaque partition key are skipped.
+// Nothing is loaded if any cookie is invalid, see Cookie.Validate.
+func LoadCookies(cookies []Cookie) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		params, err := cookieParams(cookies)
+		if err != nil || len(params) == 0 {
+			return err
+		}
+
+		if err := network.SetCookies(params).Do(ctx); err != nil {
+			return fmt.Errorf("set cookies: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// cookieParams returns the parameters to set the cookies with, skipping those
+// with an opaque partition key.
+func cookieParams(cookies []Cookie) ([]*network.CookieParam, error) {
+	params := make([]*network.CookieParam, 0, len(cookies))
+
+	for _, cookie := range cookies {
+		if cookie.PartitionKeyOpaque {
+			continue
+		}
+
+		if err := cookie.Validate(); err != nil {
+			return nil, err
+		}
+
+		params = append(params, cookie.Param())
+	}
+
+	return params, nil
+}
+
+// SaveCookies extracts the cookies from the current URL and appends them to
+// provided array. Use ExportCookies for the cookies of all sites.
+func SaveCookies(cookies *[]Cookie) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		c, err := network.GetCookies().Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, cookie := range c {
+			*cookies = append(*cookies, CookieFromNetwork(cookie))
+		}
+
+		return nil
+	})
+}
+
+// SaveCookiesTo extracts the cookies from the current page and saves them
+// as JSON to the provided path.
+func SaveCookiesTo(path string) chromedp.ActionFunc {
+	return SaveCookiesAs(path, CookieFormatJSON)
+}
+
+// SaveCookiesAs extracts the cookies from the current page and saves them in
+// the format to the provided path.
+func SaveCookiesAs(path string, format CookieFormat) chromedp.ActionFunc {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var c []Cookie
+
+		if err := SaveCookies(&c).Do(ctx); err != nil {
+			return err
+		}
+
+		return WriteCookiesFile(path, c, format)
+	})
+}
+
+// CookieOptions contains options for exporting and importing all cookies of
+// the browser.
+type CookieOptions struct {
+	domains      []string
+	name         *regexp.Regexp
+	expiresAfter time.Time
+	noSession    bool
+	clear        bool
+}
+
+// CookieOptionSetter defines a function type to set cookie options.
+type CookieOptionSetter func(*CookieOptions)
+
+// WithCookieDomains returns a CookieOptionSetter that only keeps cookies of
+// the domains and their subdomains, e.g. "example.com" keeps the cookies of
+// "login.example.com" too.
+func WithCookieDomains(domains ...string) CookieOptionSetter {
+	return func(opt *CookieOptions) {
+		opt.domains = append(opt.domains, domains...)
+	}
+}
+
+// WithCookieNamePattern returns a CookieOptionSetter that only keeps cookies
+// with a name matching the pattern.
+func WithCookieNamePattern(pattern *regexp.Regexp) CookieOptionSetter {
+	return func(opt *CookieOptions) {
+		opt.name = pattern
+	}
+}
+
+// WithCookiesExpiringAfter returns a CookieOptionSetter that drops persistent
+// cookies expiring before the time, e.g. time.Now() to drop expired cookies.
+func WithCookiesExpiringAfter(t time.Time) CookieOptionSetter {
+	return func(opt *CookieOptions) {
+		opt.expiresAfter = t
+	}
+}
+
+// WithoutSessionCookies returns a CookieOptionSetter that drops session
+// cookies.
+func WithoutSessionCookies() CookieOptionSetter {
+	return func(opt *CookieOptions) {
+		opt.noSession = true
+	}
+}
+
+// WithClearCookies returns a CookieOptionSetter that deletes all cookies of
+// the browser before importing.
+func WithClearCookies() CookieOptionSetter {
+	return func(opt *CookieOptions) {
+		opt.clear = true
+	}
+}
+
+// keep returns whether the cookie passes the filters of the options.
+func (o CookieOptions) keep(c Cookie) bool {
+	if len(o.domains) > 0 {
+		domain := strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+		match := false
+
+		for _, d := range o.domains {
+			d = strings.ToLower(strings.TrimPrefix(d, "."))
+			if domain == d || strings.HasSuffix(domain, "."+d) {
+				match = true
+
+				break
+			}
+		}
+
+		if !match {
+			return false
+		}
+	}
+
+	if o.name != nil && !o.name.MatchString(c.Name) {
+		return false
+	}
+
+	if c.IsSession() {
+		return !o.noSession
+	}
+
+	return o.expiresAfter.IsZero() || c.Expires >= float64(o.expiresAfter.UnixNano())/float64(time.Second)
+}
+
+// filter returns the cookies passing the filters of the options.
+func (o CookieOptions) filter(cookies []Cookie) []Cookie {
+	kept := make([]Cookie, 0, len(cookies))
+
+	for _, c := range cookies {
+		if o.keep(c) {
+			kept = append(kept, c)
+		}
+	}
+
+	return kept
+}
+
+// browserStorage returns the context to run storage commands with on the
+// browser, and the browser context of the tab, empty for the default one.
+func browserStorage(ctx context.Context) (context.Context, cdp.BrowserContextID) {
+	c := chromedp.FromContext(ctx)
+	if c == nil || c.Browser == nil {
+		return ctx, ""
+	}
+
+	return cdp.WithExecutor(ctx, c.Browser), c.BrowserContextID
+}
+
+// ExportCookies appends all cookies of the browser context of the tab, of
+// every site and not only of the current page, to the provided array. The
+// options filter the cookies exported.
+func ExportCookies(cookies *[]Cookie, setters ...CookieOptionSetter) chromedp.ActionFunc {
+	var options CookieOptions
+
+	for _, setter := range setters {
+		setter(&options)
+	}
+
+	return func(ctx context.Context) error {
+		ctx, id := browserStorage(ctx)
+
+		all, err := storage.GetCookies().WithBrowserContextID(id).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("get all cookies: %w", err)
+		}
+
+		for _, c := range all {
+			if cookie := CookieFromNetwork(c); options.keep(cookie) {
+				*cookies = append(*cookies, cookie)
+			}
+		}
+
+		return nil
+	}
+}
+
+// ExportCookiesTo saves all cookies of the browser context of the tab in the
+// format to the provided path, see ExportCookies.
+func ExportCookiesTo(path string, format CookieFormat, setters ...CookieOptionSetter) chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		var c []Cookie
+
+		if err := ExportCookies(&c, setters...).Do(ctx); err != nil {
+			return err
+		}
+
+		return WriteCookiesFile(path, c, format)
+	}
+}
+
+// ImportCookies loads the cookies into the browser context of the tab, for
+// every site. The options filter the cookies imported, and WithClearCookies
+// deletes the existing cookies first.
+func ImportCookies(cookies []Cookie, setters ...CookieOptionSetter) chromedp.ActionFunc {
+	var options CookieOptions
+
+	for _, setter := range setters {
+		setter(&options)
+	}
+
+	return func(ctx context.Context) error {
+		params, err := cookieParams(options.filter(cookies))
+		if err != nil {
+			return err
+		}
+
+		ctx, id := browserStorage(ctx)
+
+		if options.clear {
+			if err := storage.ClearCookies().WithBrowserContextID(id).Do(ctx); err != nil {
+				return fmt.Errorf("clear cookies: %w", err)
+			}
+		}
+
+		if len(params) == 0 {
+			return nil
+		}
+
+		if err := storage.SetCookies(params).WithBrowserContextID(id).Do(ctx); err != nil {
+			return fmt.Errorf("set cookies: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// ImportCookiesFromFile loads the cookies of a file, in any of the formats of
+// ReadCookies, into the browser context of the tab, see ImportCookies.
+func ImportCookiesFromFile(path string, setters ...CookieOptionSetter) chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		cookies, err := ReadCookiesFile(path)
+		if err != nil {
+			return err
+		}
+
+		return ImportCookies(cookies, setters...).Do(ctx)
+	}
+}
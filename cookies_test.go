@@ -0,0 +1,241 @@
+package chromedpundetected
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCookieValidate(t *testing.T) {
+	host := Cookie{Name: "__Host-id", Value: "1", Domain: "example.com", Path: "/", Secure: true}
+	require.NoError(t, host.Validate())
+
+	for _, c := range []Cookie{
+		{Name: "__Host-id", Value: "1", Domain: "example.com", Path: "/"},
+		{Name: "__Host-id", Value: "1", Domain: ".example.com", Path: "/", Secure: true},
+		{Name: "__Host-id", Value: "1", Domain: "example.com", Path: "/app", Secure: true},
+		{Name: "__Secure-id", Value: "1", Domain: ".example.com", Path: "/"},
+		{Name: "chips", Value: "1", Domain: "example.com", Path: "/", PartitionKey: "https://example.com"},
+		{Name: "none", Value: "1", Domain: "example.com", Path: "/", SameSite: network.CookieSameSiteNone},
+		{Name: "id", Value: "1"},
+	} {
+		require.ErrorIs(t, c.Validate(), ErrInvalidCookie, c.Name)
+	}
+}
+
+func TestCookieParam(t *testing.T) {
+	// Host-only cookies are set through the URL of their origin.
+	host := Cookie{
+		Name: "__Host-id", Value: "1", Domain: "example.com", Path: "/", Secure: true,
+		Expires: 1893456000.5, SourceScheme: network.CookieSourceSchemeSecure, SourcePort: 8443,
+	}
+	p := host.Param()
+	require.Equal(t, "https://example.com:8443/", p.URL)
+	require.Empty(t, p.Domain)
+	require.InDelta(t, 1893456000.5, float64(p.Expires.Time().UnixNano())/1e9, 1e-3)
+
+	domain := Cookie{Name: "id", Value: "1", Domain: ".example.com", Path: "/a", Session: true, Expires: -1, SourcePort: -1}
+	p = domain.Param()
+	require.Equal(t, ".example.com", p.Domain)
+	require.Empty(t, p.URL)
+	require.Nil(t, p.Expires)
+	require.Zero(t, p.SourcePort)
+
+	require.Equal(t, "http://example.com/a", Cookie{Domain: "example.com", Path: "/a", SourcePort: 80}.url())
+}
+
+func TestCookieJSON(t *testing.T) {
+	// Files written before sessions were tracked have an expiry of 0.
+	var cookies []Cookie
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"name": "a", "value": "1", "domain": ".example.com", "path": "/", "expires": 0, "httpOnly": true, "secure": false},
+		{"name": "b", "value": "2", "domain": ".example.com", "path": "/", "expires": 1893456000, "httpOnly": false, "secure": true}
+	]`), &cookies))
+
+	require.True(t, cookies[0].IsSession())
+	require.Nil(t, cookies[0].Param().Expires)
+	require.False(t, cookies[1].IsSession())
+	require.NotNil(t, cookies[1].Param().Expires)
+
+	want := CookieFromNetwork(&network.Cookie{
+		Name: "c", Value: "3", Domain: "example.com", Path: "/", Expires: -1, Session: true, Secure: true,
+		SameSite: network.CookieSameSiteStrict, Priority: network.CookiePriorityHigh,
+		SourceScheme: network.CookieSourceSchemeSecure, SourcePort: 443, PartitionKey: "https://example.com",
+	})
+
+	b, err := json.Marshal(want)
+	require.NoError(t, err)
+
+	var got Cookie
+	require.NoError(t, json.Unmarshal(b, &got))
+	require.Equal(t, want, got)
+}
+
+func TestCookiesRoundTrip(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/echo" {
+			names := make([]string, 0)
+			for _, c := range r.Cookies() {
+				names = append(names, c.Name)
+			}
+
+			sort.Strings(names)
+			fmt.Fprint(w, strings.Join(names, ","))
+
+			return
+		}
+
+		expires := time.Now().Add(24 * time.Hour).UTC().Format(http.TimeFormat)
+		for _, c := range []string{
+			"session=1; Path=/",
+			"persistent=2; Path=/; Expires=" + expires + "; HttpOnly",
+			"__Host-id=3; Path=/; Secure; SameSite=Strict",
+			"__Secure-token=4; Path=/; Secure; Priority=High; Expires=" + expires,
+			"chips=5; Path=/; Secure; SameSite=None; Partitioned",
+		} {
+			w.Header().Add("Set-Cookie", c)
+		}
+
+		fmt.Fprint(w, `<html><body>cookies</body></html>`)
+	}))
+	defer srv.Close()
+
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(30*time.Second),
+			WithHeadless(),
+			WithChromeFlags(chromedp.Flag("ignore-certificate-errors", true)),
+		),
+		func(ctx context.Context) error {
+			var before, after []Cookie
+
+			var echo string
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate(srv.URL),
+				SaveCookies(&before),
+				network.ClearBrowserCookies(),
+				LoadCookies(before),
+				SaveCookies(&after),
+				chromedp.Navigate(srv.URL+"/echo"),
+				chromedp.Text(`body`, &echo, chromedp.ByQuery),
+			); err != nil {
+				return err
+			}
+
+			byName := func(cookies []Cookie) {
+				sort.Slice(cookies, func(i, j int) bool { return cookies[i].Name < cookies[j].Name })
+			}
+
+			byName(before)
+			byName(after)
+
+			if len(before) < 4 || len(after) != len(before) {
+				return fmt.Errorf("got %d cookies, %d after loading", len(before), len(after))
+			}
+
+			for i := range before {
+				if before[i].Name == "session" && !before[i].IsSession() {
+					return fmt.Errorf("session cookie saved with expiry %v", before[i].Expires)
+				}
+
+				// The expiry may be rounded by the browser.
+				before[i].Expires, after[i].Expires = float64(int64(before[i].Expires)), float64(int64(after[i].Expires))
+			}
+
+			if fmt.Sprint(before) != fmt.Sprint(after) {
+				return fmt.Errorf("cookies changed:\n%+v\n%+v", before, after)
+			}
+
+			if !strings.Contains(echo, "__Host-id") || !strings.Contains(echo, "session") {
+				return fmt.Errorf("server got cookies %q", echo)
+			}
+
+			return nil
+		},
+	)
+}
+
+func TestCookieOptions(t *testing.T) {
+	now := time.Now()
+	cookies := []Cookie{
+		{Name: "sso", Domain: ".login.example.com", Expires: float64(now.Add(time.Hour).Unix())},
+		{Name: "_ga", Domain: ".example.com", Expires: float64(now.Add(-time.Hour).Unix())},
+		{Name: "sid", Domain: "notexample.com", Session: true},
+		{Name: "_gid", Domain: "other.org", Expires: -1},
+	}
+
+	names := func(setters ...CookieOptionSetter) []string {
+		var options CookieOptions
+		for _, setter := range setters {
+			setter(&options)
+		}
+
+		var kept []string
+		for _, c := range options.filter(cookies) {
+			kept = append(kept, c.Name)
+		}
+
+		return kept
+	}
+
+	require.Equal(t, []string{"sso", "_ga", "sid", "_gid"}, names())
+	require.Equal(t, []string{"sso", "_ga"}, names(WithCookieDomains("Example.com")))
+	require.Equal(t, []string{"sso", "_gid"}, names(WithCookieDomains("login.example.com", ".other.org")))
+	require.Equal(t, []string{"_ga", "_gid"}, names(WithCookieNamePattern(regexp.MustCompile(`^_g`))))
+	require.Equal(t, []string{"sso", "sid", "_gid"}, names(WithCookiesExpiringAfter(now)))
+	require.Equal(t, []string{"sso"}, names(WithCookiesExpiringAfter(now), WithoutSessionCookies()))
+}
+
+func TestExportCookies(t *testing.T) {
+	expires := float64(time.Now().Add(time.Hour).Unix())
+
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(20*time.Second),
+			WithHeadless(),
+		),
+		func(ctx context.Context) error {
+			var all, filtered, cleared []Cookie
+
+			if err := chromedp.Run(ctx,
+				ImportCookies([]Cookie{
+					{Name: "sso", Value: "1", Domain: ".login.example.com", Path: "/", Secure: true, Expires: expires},
+					{Name: "pref", Value: "2", Domain: "www.example.org", Path: "/", Session: true},
+				}),
+				chromedp.Navigate("about:blank"),
+				ExportCookies(&all),
+				ExportCookies(&filtered, WithCookieDomains("example.com")),
+				ImportCookies([]Cookie{
+					{Name: "new", Value: "3", Domain: ".example.net", Path: "/", Session: true},
+				}, WithClearCookies()),
+				ExportCookies(&cleared),
+			); err != nil {
+				return err
+			}
+
+			if len(all) != 2 || len(filtered) != 1 || filtered[0].Name != "sso" {
+				return fmt.Errorf("exported %+v, filtered %+v", all, filtered)
+			}
+
+			if len(cleared) != 1 || cleared[0].Name != "new" || !cleared[0].IsSession() {
+				return fmt.Errorf("after clearing: %+v", cleared)
+			}
+
+			return nil
+		},
+	)
+}
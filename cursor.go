@@ -0,0 +1,79 @@
+package chromedpundetected
+
+import (
+	"context"
+	"sync"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+)
+
+// cursorState is the last known position of the mouse cursor in a tab.
+type cursorState struct {
+	mu   sync.Mutex
+	x, y float64
+}
+
+func (c *cursorState) get() (float64, float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.x, c.y
+}
+
+func (c *cursorState) set(x, y float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.x, c.y = x, y
+}
+
+// cursors maps a target ID to its cursor state. Unlike page state, it
+// survives navigations.
+var cursors sync.Map //nolint:gochecknoglobals
+
+// cursorFor returns the cursor state of the tab of the context. The state is
+// forgotten when the tab is closed.
+func cursorFor(ctx context.Context) *cursorState {
+	c := chromedp.FromContext(ctx)
+	if c == nil || c.Target == nil {
+		return &cursorState{}
+	}
+
+	id := c.Target.TargetID
+
+	v, loaded := cursors.LoadOrStore(id, &cursorState{})
+	if !loaded && c.Browser != nil {
+		// The context of the action may end long before the tab does.
+		tctx := tabContext(ctx)
+
+		go func() {
+			<-tctx.Done()
+			cursors.Delete(id)
+		}()
+	}
+
+	return v.(*cursorState) //nolint:forcetypeassert
+}
+
+// CursorPosition returns the position of the mouse cursor in the current tab,
+// as left by the last mouse event dispatched through this package, e.g. by
+// MoveMouseToPosition. It is (0, 0) until the first mouse event.
+func CursorPosition(ctx context.Context) (x, y float64) {
+	return cursorFor(ctx).get()
+}
+
+// DispatchMouseEvent dispatches the mouse event, and updates the cursor
+// position of the tab. Use it instead of input.DispatchMouseEvent to keep the
+// cursor position in sync when dispatching mouse events yourself.
+func DispatchMouseEvent(p *input.DispatchMouseEventParams) chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		if err := p.Do(ctx); err != nil {
+			return err
+		}
+
+		cursorFor(ctx).set(p.X, p.Y)
+
+		return nil
+	}
+}
@@ -0,0 +1,78 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorState(t *testing.T) {
+	x, y := CursorPosition(context.Background())
+	require.Zero(t, x)
+	require.Zero(t, y)
+
+	var c cursorState
+
+	c.set(10, 20)
+	x, y = c.get()
+	require.Equal(t, 10.0, x)
+	require.Equal(t, 20.0, y)
+}
+
+func TestCursorAcrossNavigations(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body style="height: 2000px">
+			<script>
+				window.firstMove = null;
+				window.addEventListener('mousemove', (e) => {
+					if (!window.firstMove) window.firstMove = { x: e.clientX, y: e.clientY };
+				});
+			</script>
+		</body></html>`)
+	}))
+	defer srv.Close()
+
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(20*time.Second),
+			WithHeadless(),
+		),
+		func(ctx context.Context) error {
+			var first struct {
+				X float64 `json:"x"`
+				Y float64 `json:"y"`
+			}
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate(srv.URL),
+				MoveMouseToPosition(300, 200, WithSteps(10), WithRandomJitter(0)),
+				chromedp.Navigate(srv.URL+"/next"),
+				MoveMouseToPosition(400, 300, WithSteps(10), WithRandomJitter(0)),
+				chromedp.Evaluate(`window.firstMove`, &first),
+			); err != nil {
+				return err
+			}
+
+			x, y := CursorPosition(ctx)
+			if x != 400 || y != 300 {
+				return fmt.Errorf("unexpected cursor position %v,%v", x, y)
+			}
+
+			// The first movement after the navigation starts where the
+			// previous one ended, instead of jumping from the origin.
+			if math.Hypot(first.X-300, first.Y-200) > 50 {
+				return fmt.Errorf("first move after navigation at %+v", first)
+			}
+
+			return nil
+		},
+	)
+}
@@ -0,0 +1,409 @@
+// Package detection is an offline self-test for the stealth of a browser. It
+// runs a set of bot detection probes, either from local probe pages or in the
+// current page, and reports which of them detect the browser.
+package detection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// Errors.
+var (
+	ErrUnknownProbe = errors.New("unknown probe")
+)
+
+// Probe is a bot detection check.
+type Probe string
+
+// Probes.
+const (
+	// ProbeWebdriver checks navigator.webdriver.
+	ProbeWebdriver Probe = "webdriver"
+
+	// ProbeCDPRuntime checks whether the Runtime domain of the DevTools
+	// protocol is enabled, by logging an error with a stack getter that is
+	// only read when the error is serialized for a DevTools client.
+	ProbeCDPRuntime Probe = "cdp-runtime"
+
+	// ProbeHeadlessUserAgent checks the user agent and client hints for
+	// headless markers.
+	ProbeHeadlessUserAgent Probe = "headless-user-agent"
+
+	// ProbePlugins checks that plugins and mime types are present and
+	// consistent with each other.
+	ProbePlugins Probe = "plugins"
+
+	// ProbePermissions checks that the notification permission matches the
+	// state reported by the permissions API.
+	ProbePermissions Probe = "permissions"
+
+	// ProbeWebGL checks that WebGL is available and not rendered in software.
+	ProbeWebGL Probe = "webgl"
+
+	// ProbeScreen checks that the screen and window dimensions are consistent.
+	ProbeScreen Probe = "screen"
+
+	// ProbeIframe checks that iframes have a window with the same navigator.
+	ProbeIframe Probe = "iframe"
+
+	// ProbeWorker checks that the navigator of a worker matches the page.
+	ProbeWorker Probe = "worker"
+)
+
+// AllProbes are all available probes.
+var AllProbes = []Probe{ //nolint:gochecknoglobals
+	ProbeWebdriver,
+	ProbeCDPRuntime,
+	ProbeHeadlessUserAgent,
+	ProbePlugins,
+	ProbePermissions,
+	ProbeWebGL,
+	ProbeScreen,
+	ProbeIframe,
+	ProbeWorker,
+}
+
+// Result is the result of a probe.
+type Result struct {
+	Probe Probe `json:"probe"`
+
+	// Passed is true if the probe did not detect the browser.
+	Passed bool `json:"passed"`
+
+	// Details describes what the probe found.
+	Details string `json:"details,omitempty"`
+
+	// Error is set if the probe could not run, e.g. because the page blocks
+	// workers.
+	Error string `json:"error,omitempty"`
+}
+
+// Report is the result of a detection run.
+type Report struct {
+	UserAgent string   `json:"userAgent"`
+	Results   []Result `json:"results"`
+}
+
+// Passed returns true if all probes ran and passed.
+func (r Report) Passed() bool {
+	return len(r.Failed()) == 0
+}
+
+// Failed returns the results of the probes that failed or could not run.
+func (r Report) Failed() []Result {
+	var failed []Result
+
+	for _, res := range r.Results {
+		if !res.Passed || res.Error != "" {
+			failed = append(failed, res)
+		}
+	}
+
+	return failed
+}
+
+// String formats the report with one line per probe.
+func (r Report) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "user agent: %s\n", r.UserAgent)
+
+	for _, res := range r.Results {
+		status := "PASS"
+
+		switch {
+		case res.Error != "":
+			status = "ERROR"
+		case !res.Passed:
+			status = "FAIL"
+		}
+
+		fmt.Fprintf(&b, "%-5s %-20s", status, res.Probe)
+
+		if res.Error != "" {
+			fmt.Fprintf(&b, " %s", res.Error)
+		} else if res.Details != "" {
+			fmt.Fprintf(&b, " %s", res.Details)
+		}
+
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// validProbes returns the probes, or all probes if none are given.
+func validProbes(probes []Probe) ([]Probe, error) {
+	if len(probes) == 0 {
+		return AllProbes, nil
+	}
+
+	for _, p := range probes {
+		known := false
+
+		for _, a := range AllProbes {
+			if p == a {
+				known = true
+				break
+			}
+		}
+
+		if !known {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownProbe, p)
+		}
+	}
+
+	return probes, nil
+}
+
+// probeCall returns the JavaScript expression that runs the probes and
+// resolves to their results.
+func probeCall(probes []Probe) (string, error) {
+	names, err := json.Marshal(probes)
+	if err != nil {
+		return "", err
+	}
+
+	return "(" + probesJS + ")(" + string(names) + ")", nil
+}
+
+// CheckDetection runs the probes in the current page, and appends the results
+// to the report. All probes run if none are given.
+//
+// Unlike the probe pages, the probes run after the page has loaded, and some
+// may not be able to run on pages with a strict content security policy.
+func CheckDetection(report *Report, probes ...Probe) chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		probes, err := validProbes(probes)
+		if err != nil {
+			return err
+		}
+
+		call, err := probeCall(probes)
+		if err != nil {
+			return err
+		}
+
+		var results []Result
+
+		if err := chromedp.Run(ctx,
+			chromedp.Evaluate(`navigator.userAgent`, &report.UserAgent),
+			chromedp.Evaluate(call, &results, awaitPromise),
+		); err != nil {
+			return fmt.Errorf("run probes: %w", err)
+		}
+
+		report.Results = append(report.Results, results...)
+
+		return nil
+	}
+}
+
+func awaitPromise(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+	return p.WithAwaitPromise(true)
+}
+
+// probesJS is a function that runs the named probes, and resolves to their
+// results. Each probe resolves to its problems, an empty list if it passed.
+const probesJS = `async (names) => {
+	const sleep = (ms) => new Promise((resolve) => setTimeout(resolve, ms));
+
+	const probes = {
+		'webdriver': async () => {
+			const problems = [];
+			if (navigator.webdriver) {
+				problems.push('navigator.webdriver is true');
+			}
+			if (Object.getOwnPropertyDescriptor(navigator, 'webdriver')) {
+				problems.push('navigator.webdriver is an own property');
+			}
+			return problems;
+		},
+
+		'cdp-runtime': async () => {
+			let serialized = false;
+			const err = new Error('probe');
+			Object.defineProperty(err, 'stack', {
+				configurable: true,
+				get() {
+					serialized = true;
+					return '';
+				},
+			});
+			console.debug(err);
+			await sleep(50);
+			return serialized ? ['error serialized for a DevTools client'] : [];
+		},
+
+		'headless-user-agent': async () => {
+			const problems = [];
+			if (/Headless/.test(navigator.userAgent)) {
+				problems.push('user agent contains Headless');
+			}
+			if (/Headless/.test(navigator.appVersion)) {
+				problems.push('appVersion contains Headless');
+			}
+			const brands = (navigator.userAgentData && navigator.userAgentData.brands) || [];
+			if (brands.some((b) => /Headless/.test(b.brand))) {
+				problems.push('client hints brands contain Headless');
+			}
+			return problems;
+		},
+
+		'plugins': async () => {
+			const problems = [];
+			const plugins = Array.from(navigator.plugins);
+			const mimeTypes = Array.from(navigator.mimeTypes);
+			if (!(navigator.plugins instanceof PluginArray)) {
+				problems.push('navigator.plugins is not a PluginArray');
+			}
+			if (!(navigator.mimeTypes instanceof MimeTypeArray)) {
+				problems.push('navigator.mimeTypes is not a MimeTypeArray');
+			}
+			if (plugins.length === 0) {
+				problems.push('no plugins');
+			}
+			if (mimeTypes.length === 0) {
+				problems.push('no mime types');
+			}
+			for (const m of mimeTypes) {
+				if (!m.enabledPlugin || !plugins.some((p) => p.name === m.enabledPlugin.name)) {
+					problems.push('mime type ' + m.type + ' has no enabled plugin');
+				}
+			}
+			return problems;
+		},
+
+		'permissions': async () => {
+			if (!window.Notification || !navigator.permissions) {
+				return [];
+			}
+			const status = await navigator.permissions.query({ name: 'notifications' });
+			if (Notification.permission === 'denied' && status.state === 'prompt') {
+				return ['notification permission denied while the permissions API prompts'];
+			}
+			return [];
+		},
+
+		'webgl': async () => {
+			const gl = document.createElement('canvas').getContext('webgl');
+			if (!gl) {
+				return ['WebGL unavailable'];
+			}
+			const ext = gl.getExtension('WEBGL_debug_renderer_info');
+			if (!ext) {
+				return ['WEBGL_debug_renderer_info unavailable'];
+			}
+			const vendor = gl.getParameter(ext.UNMASKED_VENDOR_WEBGL);
+			const renderer = gl.getParameter(ext.UNMASKED_RENDERER_WEBGL);
+			if (/SwiftShader|llvmpipe|softpipe|Mesa OffScreen/i.test(renderer)) {
+				return ['software renderer ' + vendor + ' / ' + renderer];
+			}
+			return [];
+		},
+
+		'screen': async () => {
+			const problems = [];
+			if (window.outerWidth === 0 || window.outerHeight === 0) {
+				problems.push('outer window size is zero');
+			}
+			if (window.outerWidth < window.innerWidth || window.outerHeight < window.innerHeight) {
+				problems.push('outer window smaller than inner window');
+			}
+			if (screen.width < window.outerWidth || screen.height < window.outerHeight) {
+				problems.push('window larger than screen');
+			}
+			if (screen.availWidth > screen.width || screen.availHeight > screen.height) {
+				problems.push('available screen larger than screen');
+			}
+			if (screen.width === 800 && screen.height === 600) {
+				problems.push('default headless screen size 800x600');
+			}
+			return problems;
+		},
+
+		'iframe': async () => {
+			const problems = [];
+			const frame = document.createElement('iframe');
+			frame.srcdoc = '<p>probe</p>';
+			const loaded = new Promise((resolve) => {
+				frame.onload = resolve;
+				setTimeout(resolve, 1000);
+			});
+			(document.body || document.documentElement).appendChild(frame);
+			await loaded;
+			try {
+				const w = frame.contentWindow;
+				if (!w) {
+					problems.push('iframe has no contentWindow');
+				} else {
+					if (w === window) {
+						problems.push('iframe contentWindow is the top window');
+					}
+					if (w.navigator.webdriver) {
+						problems.push('navigator.webdriver is true in iframe');
+					}
+					if (w.navigator.userAgent !== navigator.userAgent) {
+						problems.push('iframe user agent differs');
+					}
+					if (!!w.chrome !== !!window.chrome) {
+						problems.push('window.chrome differs in iframe');
+					}
+				}
+			} finally {
+				frame.remove();
+			}
+			return problems;
+		},
+
+		'worker': async () => {
+			const src = 'postMessage({' +
+				'userAgent: navigator.userAgent,' +
+				'languages: navigator.languages.join(","),' +
+				'platform: navigator.platform,' +
+				'hardwareConcurrency: navigator.hardwareConcurrency,' +
+				'webdriver: !!navigator.webdriver,' +
+			'})';
+			const url = URL.createObjectURL(new Blob([src], { type: 'application/javascript' }));
+			const worker = new Worker(url);
+			try {
+				const got = await new Promise((resolve, reject) => {
+					worker.onmessage = (e) => resolve(e.data);
+					worker.onerror = () => reject(new Error('worker failed to start'));
+					setTimeout(() => reject(new Error('worker timed out')), 3000);
+				});
+				const want = {
+					userAgent: navigator.userAgent,
+					languages: navigator.languages.join(','),
+					platform: navigator.platform,
+					hardwareConcurrency: navigator.hardwareConcurrency,
+					webdriver: !!navigator.webdriver,
+				};
+				return Object.keys(want)
+					.filter((k) => got[k] !== want[k])
+					.map((k) => 'worker ' + k + ' ' + JSON.stringify(got[k]) + ' differs from ' + JSON.stringify(want[k]));
+			} finally {
+				worker.terminate();
+				URL.revokeObjectURL(url);
+			}
+		},
+	};
+
+	const results = [];
+	for (const name of names) {
+		try {
+			const problems = await probes[name]();
+			results.push({ probe: name, passed: problems.length === 0, details: problems.join('; ') });
+		} catch (e) {
+			results.push({ probe: name, passed: false, error: String((e && e.message) || e) });
+		}
+	}
+	return results;
+}`
@@ -0,0 +1,109 @@
+package detection
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+
+	cu "github.com/Davincible/chromedp-undetected"
+)
+
+func TestReport(t *testing.T) {
+	report := Report{
+		UserAgent: "Mozilla/5.0",
+		Results: []Result{
+			{Probe: ProbeWebdriver, Passed: true},
+			{Probe: ProbeWebGL, Passed: false, Details: "software renderer"},
+			{Probe: ProbeWorker, Error: "worker timed out"},
+		},
+	}
+
+	require.False(t, report.Passed())
+	require.Equal(t, []Result{report.Results[1], report.Results[2]}, report.Failed())
+
+	s := report.String()
+	require.Contains(t, s, "PASS  webdriver")
+	require.Contains(t, s, "FAIL  webgl                software renderer")
+	require.Contains(t, s, "ERROR worker               worker timed out")
+
+	require.True(t, Report{Results: report.Results[:1]}.Passed())
+}
+
+func TestValidProbes(t *testing.T) {
+	probes, err := validProbes(nil)
+	require.NoError(t, err)
+	require.Equal(t, AllProbes, probes)
+
+	_, err = validProbes([]Probe{ProbeWebdriver, "battery"})
+	require.ErrorIs(t, err, ErrUnknownProbe)
+}
+
+func TestServer(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	get := func(url string) (int, string) {
+		resp, err := http.Get(url) //nolint:gosec,noctx
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return resp.StatusCode, string(b)
+	}
+
+	code, body := get(srv.URL)
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, `(["webdriver","cdp-runtime",`)
+
+	code, body = get(srv.ProbeURL(ProbeIframe))
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, strings.Contains(body, `(["iframe"])`), body)
+
+	code, _ = get(srv.ProbeURL("battery"))
+	require.Equal(t, http.StatusNotFound, code)
+}
+
+func TestRun(t *testing.T) {
+	report, err := Run(cu.NewConfig(
+		cu.WithTimeout(30*time.Second),
+		cu.WithHeadless(),
+	))
+	require.NoError(t, err)
+	require.Len(t, report.Results, len(AllProbes))
+
+	t.Log("\n" + report.String())
+
+	for _, res := range report.Results {
+		if res.Probe == ProbeWebdriver || res.Probe == ProbeHeadlessUserAgent {
+			require.True(t, res.Passed, res.Details)
+		}
+	}
+}
+
+func TestCheckDetection(t *testing.T) {
+	ctx, cancel, err := cu.New(cu.NewConfig(
+		cu.WithTimeout(30*time.Second),
+		cu.WithHeadless(),
+	))
+	require.NoError(t, err)
+	defer cancel()
+
+	var report Report
+
+	require.NoError(t, chromedp.Run(ctx,
+		chromedp.Navigate("about:blank"),
+		CheckDetection(&report, ProbeWebdriver, ProbeIframe),
+	))
+	require.Len(t, report.Results, 2)
+	require.NotEmpty(t, report.UserAgent)
+
+	require.ErrorIs(t, CheckDetection(&report, "battery").Do(context.Background()), ErrUnknownProbe)
+}
@@ -0,0 +1,138 @@
+package detection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+
+	cu "github.com/Davincible/chromedp-undetected"
+)
+
+// Server serves the probe pages on a local address. The page at "/" runs all
+// probes, the page at "/probe/<name>" runs a single probe. The probes run as
+// soon as a page loads, like on a site with bot detection, and the results are
+// written as JSON into the element with id "result".
+type Server struct {
+	*httptest.Server
+}
+
+// NewServer starts a probe page server. Close it when done.
+func NewServer() *Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+
+		serveProbePage(w, "all probes", AllProbes)
+	})
+
+	mux.HandleFunc("/probe/", func(w http.ResponseWriter, r *http.Request) {
+		probes, err := validProbes([]Probe{Probe(strings.TrimPrefix(r.URL.Path, "/probe/"))})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		serveProbePage(w, string(probes[0]), probes)
+	})
+
+	return &Server{Server: httptest.NewServer(mux)}
+}
+
+// ProbeURL returns the URL of the page that runs the probe.
+func (s *Server) ProbeURL(probe Probe) string {
+	return s.URL + "/probe/" + string(probe)
+}
+
+func serveProbePage(w http.ResponseWriter, title string, probes []Probe) {
+	call, err := probeCall(probes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	fmt.Fprintf(w, probePageHTML, title, call)
+}
+
+// RunProbes loads the probe page of each probe from the server, and appends
+// the results to the report. All probes run if none are given.
+func RunProbes(srv *Server, report *Report, probes ...Probe) chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		probes, err := validProbes(probes)
+		if err != nil {
+			return err
+		}
+
+		for _, probe := range probes {
+			var raw string
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate(srv.ProbeURL(probe)),
+				chromedp.WaitReady(`#result[data-done]`, chromedp.ByQuery),
+				chromedp.TextContent(`#result`, &raw, chromedp.ByQuery),
+			); err != nil {
+				return fmt.Errorf("run probe %s: %w", probe, err)
+			}
+
+			var results []Result
+			if err := json.Unmarshal([]byte(raw), &results); err != nil {
+				return fmt.Errorf("parse result of probe %s: %w", probe, err)
+			}
+
+			report.Results = append(report.Results, results...)
+		}
+
+		return chromedp.Evaluate(`navigator.userAgent`, &report.UserAgent).Do(ctx)
+	}
+}
+
+// Run starts a browser with the config, runs the probes against it from local
+// probe pages, and returns the report. All probes run if none are given.
+func Run(config cu.Config, probes ...Probe) (Report, error) {
+	var report Report
+
+	srv := NewServer()
+	defer srv.Close()
+
+	ctx, cancel, err := cu.New(config)
+	if err != nil {
+		return report, fmt.Errorf("create browser: %w", err)
+	}
+	defer cancel()
+
+	if err := chromedp.Run(ctx, RunProbes(srv, &report, probes...)); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// probePageHTML is the probe page, formatted with the title and the probe call.
+const probePageHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Detection probe: %s</title>
+</head>
+<body>
+<pre id="result"></pre>
+<script>
+%s.then((results) => {
+	const el = document.getElementById('result');
+	el.textContent = JSON.stringify(results);
+	el.dataset.done = 'true';
+});
+</script>
+</body>
+</html>
+`
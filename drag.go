@@ -0,0 +1,344 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+)
+
+// DragOptions contains options for dragging.
+type DragOptions struct {
+	hoverMin time.Duration
+	hoverMax time.Duration
+	move     []MoveOptionSetter
+	scroll   []ScrollOptionSetter
+	query    []chromedp.QueryOption
+}
+
+// Default values for dragging.
+var defaultDragOptions = DragOptions{
+	hoverMin: 80 * time.Millisecond,
+	hoverMax: 300 * time.Millisecond,
+}
+
+// DragOptionSetter defines a function type to set drag options.
+type DragOptionSetter func(*DragOptions)
+
+// WithDragHover returns a DragOptionSetter that sets the range of the time the
+// cursor rests on the element before pressing the button, and on the drop
+// point before releasing it.
+func WithDragHover(min, max time.Duration) DragOptionSetter {
+	return func(opt *DragOptions) {
+		opt.hoverMin = min
+		opt.hoverMax = max
+	}
+}
+
+// WithDragMoveOptions returns a DragOptionSetter that sets the options of the
+// mouse movements, e.g. the path generator.
+func WithDragMoveOptions(setters ...MoveOptionSetter) DragOptionSetter {
+	return func(opt *DragOptions) {
+		opt.move = append(opt.move, setters...)
+	}
+}
+
+// WithDragScrollOptions returns a DragOptionSetter that sets the options of
+// scrolling the dragged element into view.
+func WithDragScrollOptions(setters ...ScrollOptionSetter) DragOptionSetter {
+	return func(opt *DragOptions) {
+		opt.scroll = append(opt.scroll, setters...)
+	}
+}
+
+// WithDragQueryOptions returns a DragOptionSetter that sets the options of the
+// element queries, e.g. chromedp.ByQuery.
+func WithDragQueryOptions(opts ...chromedp.QueryOption) DragOptionSetter {
+	return func(opt *DragOptions) {
+		opt.query = append(opt.query, opts...)
+	}
+}
+
+// Drag drags the first element matching the from selector onto the first
+// element matching the to selector like a human would.
+//
+// The dragged element is scrolled into view, the cursor moves onto it and
+// presses the left button, moves to a random point in the drop target along a
+// human like path with the button held, rests there, and releases it. Both
+// elements have to be visible at the same time.
+//
+// Pages using HTML5 drag and drop get the drag events of a real drag, the
+// drag is intercepted when it starts and continued with
+// Input.dispatchDragEvent.
+func Drag(fromSel, toSel any, setters ...DragOptionSetter) chromedp.ActionFunc {
+	options := defaultDragOptions
+
+	for _, setter := range setters {
+		setter(&options)
+	}
+
+	return func(ctx context.Context) error {
+		from, err := queryNode(ctx, fromSel, options.query)
+		if err != nil {
+			return err
+		}
+
+		to, err := queryNode(ctx, toSel, options.query)
+		if err != nil {
+			return err
+		}
+
+		rnd := randFor(ctx)
+
+		box, err := scrollForDrag(ctx, from, options, rnd)
+		if err != nil {
+			return err
+		}
+
+		target, err := visibleBox(ctx, to)
+		if err != nil {
+			return fmt.Errorf("drop target: %w", err)
+		}
+
+		drop := randomPointIn(target, rnd)
+
+		return drag(ctx, box, func(Point) Point { return drop }, math.Min(target.Width, target.Height), options, rnd)
+	}
+}
+
+// DragBy drags the first element matching the selector by the distance in
+// CSS pixels, like Drag does, e.g. to move the handle of a slider.
+func DragBy(sel any, dx, dy float64, setters ...DragOptionSetter) chromedp.ActionFunc {
+	options := defaultDragOptions
+
+	for _, setter := range setters {
+		setter(&options)
+	}
+
+	return func(ctx context.Context) error {
+		node, err := queryNode(ctx, sel, options.query)
+		if err != nil {
+			return err
+		}
+
+		rnd := randFor(ctx)
+
+		box, err := scrollForDrag(ctx, node, options, rnd)
+		if err != nil {
+			return err
+		}
+
+		// The drop point is relative to where the element is grabbed, near
+		// its center.
+		return drag(ctx, box, func(grab Point) Point {
+			return Point{X: grab.X + dx, Y: grab.Y + dy}
+		}, 0, options, rnd)
+	}
+}
+
+// queryNode returns the ID of the first visible node matching the selector.
+func queryNode(ctx context.Context, sel any, opts []chromedp.QueryOption) (cdp.NodeID, error) {
+	var nodes []*cdp.Node
+
+	if err := chromedp.Nodes(sel, &nodes, append([]chromedp.QueryOption{chromedp.NodeVisible}, opts...)...).Do(ctx); err != nil {
+		return 0, err
+	}
+
+	if len(nodes) == 0 {
+		return 0, ErrNoNodes
+	}
+
+	return nodes[0].NodeID, nil
+}
+
+// scrollForDrag scrolls the node into view, and returns its visible box.
+func scrollForDrag(ctx context.Context, nodeID cdp.NodeID, options DragOptions, rnd *rand.Rand) (Box, error) {
+	scroll := defaultScrollOptions
+	scroll.move = options.move
+
+	for _, setter := range options.scroll {
+		setter(&scroll)
+	}
+
+	return scrollNodeIntoView(ctx, nodeID, scroll, rnd)
+}
+
+// drag grabs the element in the box, and drops it at the point dropAt returns
+// for the grab point. The target width is the size of the drop target, zero
+// if unknown.
+func drag(ctx context.Context, box Box, dropAt func(grab Point) Point, targetWidth float64, options DragOptions, rnd *rand.Rand) (err error) {
+	move := defaultMouseMoveOptions
+	move.targetWidth = math.Min(box.Width, box.Height)
+
+	for _, setter := range options.move {
+		setter(&move)
+	}
+
+	grab := randomPointIn(box, rnd)
+	if err := moveMouse(ctx, grab, move); err != nil {
+		return err
+	}
+
+	if err := sleepContext(ctx, randDuration(rnd, options.hoverMin, options.hoverMax)); err != nil {
+		return err
+	}
+
+	// Intercept HTML5 drags, so they can be continued with drag events.
+	// Without interception Chrome would wait for the OS to run the drag.
+	listenCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	intercepted := make(chan *input.DragData, 1)
+
+	chromedp.ListenTarget(listenCtx, func(ev interface{}) {
+		if e, ok := ev.(*input.EventDragIntercepted); ok {
+			select {
+			case intercepted <- e.Data:
+			default:
+			}
+		}
+	})
+
+	if err := input.SetInterceptDrags(true).Do(ctx); err != nil {
+		return fmt.Errorf("intercept drags: %w", err)
+	}
+
+	defer input.SetInterceptDrags(false).Do(ctx) //nolint:errcheck
+
+	d := dragState{intercepted: intercepted}
+
+	press := input.DispatchMouseEvent(input.MousePressed, grab.X, grab.Y).
+		WithButton(input.Left).
+		WithButtons(buttonMask(input.Left)).
+		WithClickCount(1)
+
+	if err := DispatchMouseEvent(press).Do(ctx); err != nil {
+		return err
+	}
+
+	// Don't leave the button held, or the page in a drag, when the drag fails
+	// halfway.
+	defer func() {
+		if err != nil {
+			d.cancel(ctx)
+		}
+	}()
+
+	// Get a grip before moving.
+	if err := sleepContext(ctx, randDuration(rnd, 60*time.Millisecond, 160*time.Millisecond)); err != nil {
+		return err
+	}
+
+	move.targetWidth = targetWidth
+
+	path, err := mousePath(ctx, dropAt(grab), move, rnd)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range path {
+		if err := sleepContext(ctx, p.Delay); err != nil {
+			return err
+		}
+
+		if err := d.moveTo(ctx, p.Point); err != nil {
+			return err
+		}
+	}
+
+	end := path[len(path)-1].Point
+
+	// Rest on the drop point, the page may highlight the target.
+	if err := sleepContext(ctx, randDuration(rnd, options.hoverMin, options.hoverMax)); err != nil {
+		return err
+	}
+
+	if err := d.moveTo(ctx, end); err != nil {
+		return err
+	}
+
+	return d.release(ctx, end)
+}
+
+// dragState tracks whether a drag was intercepted as an HTML5 drag.
+type dragState struct {
+	intercepted chan *input.DragData
+	data        *input.DragData
+	entered     bool
+	dropped     bool
+}
+
+// poll checks whether the drag has been intercepted since the last call.
+func (d *dragState) poll() {
+	if d.data != nil {
+		return
+	}
+
+	select {
+	case data := <-d.intercepted:
+		d.data = data
+	default:
+	}
+}
+
+// moveTo moves the cursor with the left button held, with mouse events or
+// with drag events once the page started an HTML5 drag.
+func (d *dragState) moveTo(ctx context.Context, p Point) error {
+	d.poll()
+
+	if d.data == nil {
+		moved := input.DispatchMouseEvent(input.MouseMoved, p.X, p.Y).
+			WithButton(input.Left).
+			WithButtons(buttonMask(input.Left))
+
+		return DispatchMouseEvent(moved).Do(ctx)
+	}
+
+	typ := input.DragOver
+	if !d.entered {
+		typ = input.DragEnter
+		d.entered = true
+	}
+
+	if err := input.DispatchDragEvent(typ, p.X, p.Y, d.data).Do(ctx); err != nil {
+		return fmt.Errorf("dispatch drag event: %w", err)
+	}
+
+	cursorFor(ctx).set(p.X, p.Y)
+
+	return nil
+}
+
+// release drops an HTML5 drag and releases the button.
+func (d *dragState) release(ctx context.Context, p Point) error {
+	d.poll()
+
+	if d.data != nil {
+		if err := input.DispatchDragEvent(input.Drop, p.X, p.Y, d.data).Do(ctx); err != nil {
+			return fmt.Errorf("dispatch drop event: %w", err)
+		}
+
+		d.dropped = true
+	}
+
+	return releaseLeft(ctx, p)
+}
+
+// cancel ends a failed drag: it cancels an HTML5 drag that was not dropped,
+// and releases the button where the cursor is.
+func (d *dragState) cancel(ctx context.Context) {
+	d.poll()
+
+	x, y := cursorFor(ctx).get()
+
+	if d.data != nil && !d.dropped {
+		input.DispatchDragEvent(input.DragCancel, x, y, d.data).Do(ctx) //nolint:errcheck,gosec
+	}
+
+	releaseLeft(ctx, Point{X: x, Y: y}) //nolint:errcheck,gosec
+}
@@ -0,0 +1,146 @@
+package chromedpundetected
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDragStatePoll(t *testing.T) {
+	d := dragState{intercepted: make(chan *input.DragData, 1)}
+
+	d.poll()
+	require.Nil(t, d.data)
+
+	data := &input.DragData{DragOperationsMask: 1}
+	d.intercepted <- data
+
+	d.poll()
+	require.Same(t, data, d.data)
+
+	// Later drags do not replace the first one.
+	d.intercepted <- &input.DragData{}
+	d.poll()
+	require.Same(t, data, d.data)
+}
+
+// emptyPath is a path generator that returns no points after the first path.
+type emptyPath struct {
+	calls int
+}
+
+func (g *emptyPath) Path(req PathRequest) []PathPoint {
+	g.calls++
+	if g.calls > 1 {
+		return nil
+	}
+
+	return BezierPath{}.Path(req)
+}
+
+func TestDrag(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body style="margin: 0">
+			<input id="slider" type="range" min="0" max="200" value="100" style="position: absolute; left: 50px; top: 30px; width: 300px">
+			<div id="card" style="position: absolute; left: 50px; top: 100px; width: 80px; height: 40px; background: #ccc">card</div>
+			<div id="item" draggable="true" style="position: absolute; left: 50px; top: 200px; width: 80px; height: 40px; background: #aaf">item</div>
+			<div id="zone" style="position: absolute; left: 400px; top: 200px; width: 150px; height: 150px; background: #afa">zone</div>
+			<script>
+				window.held = 0;
+				window.dropped = null;
+				window.down = false;
+				document.addEventListener('mousedown', () => { window.down = true; });
+				document.addEventListener('mouseup', () => { window.down = false; });
+
+				const card = document.getElementById('card');
+				card.addEventListener('mousedown', () => {
+					const move = (e) => { if (e.buttons === 1) window.held++; };
+					document.addEventListener('mousemove', move);
+					document.addEventListener('mouseup', () => document.removeEventListener('mousemove', move), { once: true });
+				});
+
+				document.getElementById('item').addEventListener('dragstart', (e) => {
+					e.dataTransfer.setData('text/plain', 'item');
+				});
+
+				const zone = document.getElementById('zone');
+				zone.addEventListener('dragover', (e) => e.preventDefault());
+				zone.addEventListener('drop', (e) => {
+					e.preventDefault();
+					window.dropped = e.dataTransfer.getData('text/plain');
+				});
+			</script>
+		</body></html>`)
+	}))
+	defer srv.Close()
+
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(60*time.Second),
+			WithHeadless(),
+		),
+		func(ctx context.Context) error {
+			var (
+				value   string
+				held    int
+				dropped string
+			)
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate(srv.URL),
+				DragBy(`#slider`, 75, 0, WithDragQueryOptions(chromedp.ByQuery)),
+				chromedp.Value(`#slider`, &value, chromedp.ByQuery),
+				DragBy(`#card`, 200, 20, WithDragQueryOptions(chromedp.ByQuery)),
+				chromedp.Evaluate(`window.held`, &held),
+				Drag(`#item`, `#zone`, WithDragQueryOptions(chromedp.ByQuery)),
+				chromedp.Evaluate(`window.dropped || ''`, &dropped),
+			); err != nil {
+				return err
+			}
+
+			if v, err := strconv.Atoi(value); err != nil || v <= 100 {
+				return fmt.Errorf("slider value %s after dragging right", value)
+			}
+
+			if held < 5 {
+				return fmt.Errorf("got %d mouse moves with the button held", held)
+			}
+
+			if dropped != "item" {
+				return fmt.Errorf("dropped %q", dropped)
+			}
+
+			// A drag failing with the button pressed releases it.
+			err := chromedp.Run(ctx,
+				DragBy(`#card`, 50, 0,
+					WithDragQueryOptions(chromedp.ByQuery),
+					WithDragMoveOptions(WithPathGenerator(&emptyPath{})),
+				),
+			)
+			if !errors.Is(err, ErrEmptyPath) {
+				return fmt.Errorf("drag along an empty path: %v", err)
+			}
+
+			var down bool
+			if err := chromedp.Run(ctx, chromedp.Evaluate(`window.down`, &down)); err != nil {
+				return err
+			}
+
+			if down {
+				return errors.New("button held after a failed drag")
+			}
+
+			return nil
+		},
+	)
+}
@@ -0,0 +1,450 @@
+package chromedpundetected
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// Evasion is the name of a JavaScript patch that hides a difference between
+// an automated and a regular Chrome.
+type Evasion string
+
+// Evasions.
+const (
+	// EvasionWebdriver makes navigator.webdriver report false.
+	EvasionWebdriver Evasion = "navigator.webdriver"
+
+	// EvasionPlugins fills in the default PDF plugins and mime types of a
+	// regular Chrome when they are empty.
+	EvasionPlugins Evasion = "navigator.plugins"
+
+	// EvasionChromeRuntime adds the window.chrome object, including
+	// chrome.runtime on secure pages, when it is missing.
+	EvasionChromeRuntime Evasion = "chrome.runtime"
+
+	// EvasionPermissions makes the notifications permission query consistent
+	// with Notification.permission.
+	EvasionPermissions Evasion = "navigator.permissions"
+
+	// EvasionIframeContentWindow applies the enabled evasions to the window of
+	// iframes as soon as it is accessed, as scripts injected on new documents
+	// do not run in e.g. srcdoc iframes.
+	EvasionIframeContentWindow Evasion = "iframe.contentWindow"
+
+	// EvasionWebGLVendor reports a common WebGL vendor and renderer instead of
+	// the software renderer used without GPU.
+	EvasionWebGLVendor Evasion = "webgl.vendor"
+
+	// EvasionHardwareConcurrency reports a common number of CPU cores.
+	EvasionHardwareConcurrency Evasion = "navigator.hardwareConcurrency"
+
+	// EvasionOuterDimensions makes the outer window dimensions include the
+	// browser UI, as headless Chrome reports the inner dimensions.
+	EvasionOuterDimensions Evasion = "window.outerdimensions"
+)
+
+// AllEvasions contains every available evasion.
+var AllEvasions = []Evasion{ //nolint:gochecknoglobals
+	EvasionWebdriver,
+	EvasionPlugins,
+	EvasionChromeRuntime,
+	EvasionPermissions,
+	EvasionIframeContentWindow,
+	EvasionWebGLVendor,
+	EvasionHardwareConcurrency,
+	EvasionOuterDimensions,
+}
+
+// Errors.
+var (
+	ErrUnknownEvasion = errors.New("unknown evasion")
+)
+
+// evasionParams are the values reported by the evasions.
+type evasionParams struct {
+	HardwareConcurrency int    `json:"hardwareConcurrency"`
+	WebGLVendor         string `json:"webglVendor"`
+	WebGLRenderer       string `json:"webglRenderer"`
+}
+
+// Default values reported by the evasions.
+var defaultEvasionParams = evasionParams{
+	HardwareConcurrency: 8,
+	WebGLVendor:         "Google Inc. (Intel)",
+	WebGLRenderer:       "ANGLE (Intel, Intel(R) UHD Graphics 620 Direct3D11 vs_5_0 ps_5_0, D3D11)",
+}
+
+// EvasionScript returns the script that applies the evasions to a window, for
+// use with e.g. page.AddScriptToEvaluateOnNewDocument.
+func EvasionScript(evasions ...Evasion) (string, error) {
+	return evasionScript(defaultEvasionParams, evasions...)
+}
+
+func evasionScript(params evasionParams, evasions ...Evasion) (string, error) {
+	evasions = uniqueEvasions(evasions)
+
+	funcs := make([]string, 0, len(evasions))
+
+	for _, evasion := range evasions {
+		js, ok := evasionScripts[evasion]
+		if !ok {
+			return "", fmt.Errorf("%w: %q", ErrUnknownEvasion, evasion)
+		}
+
+		funcs = append(funcs, js)
+	}
+
+	return wrapEvasions(params, funcs...)
+}
+
+// wrapEvasions returns the script that applies the evasion functions with the
+// params, so that they can mask their overrides with the wrapper utils.
+func wrapEvasions(params any, funcs ...string) (string, error) {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(evasionWrapperJS, b, strings.Join(funcs, ",\n")), nil
+}
+
+// uniqueEvasions sorts the evasions and removes duplicates.
+func uniqueEvasions(evasions []Evasion) []Evasion {
+	seen := make(map[Evasion]bool, len(evasions))
+	unique := make([]Evasion, 0, len(evasions))
+
+	for _, evasion := range evasions {
+		if !seen[evasion] {
+			seen[evasion] = true
+
+			unique = append(unique, evasion)
+		}
+	}
+
+	sort.Slice(unique, func(i, j int) bool { return unique[i] < unique[j] })
+
+	return unique
+}
+
+// evasions returns the evasions to apply for the config, which includes the
+// evasions required by the headless mode and the persona.
+func (c Config) evasions() []Evasion {
+	evasions := append([]Evasion(nil), c.Evasions...)
+
+	if c.headlessMode() == HeadlessNew {
+		evasions = append(evasions, EvasionPlugins, EvasionOuterDimensions)
+	}
+
+	if p := c.Persona; p != nil {
+		if p.HardwareConcurrency > 0 {
+			evasions = append(evasions, EvasionHardwareConcurrency)
+		}
+
+		if p.WebGLVendor != "" || p.WebGLRenderer != "" {
+			evasions = append(evasions, EvasionWebGLVendor)
+		}
+	}
+
+	return uniqueEvasions(evasions)
+}
+
+// evasionSetup returns the action that injects the evasions of the config
+// into every document of a tab.
+func evasionSetup(config Config) ([]chromedp.Action, error) {
+	evasions := config.evasions()
+	if len(evasions) == 0 {
+		return nil, nil
+	}
+
+	script, err := evasionScript(config.evasionParams(), evasions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return []chromedp.Action{injectScript(script)}, nil
+}
+
+// injectScript evaluates the script in the main world of every new document
+// of the tab, including the documents of frames, and in the current ones.
+func injectScript(script string) chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		_, err := page.AddScriptToEvaluateOnNewDocument(script).WithRunImmediately(true).Do(ctx)
+
+		return err
+	}
+}
+
+var (
+	// evasionWrapperJS applies a list of evasion functions to the window, and
+	// exposes the apply function to the evasions for use on child windows.
+	evasionWrapperJS = `
+(() => {
+  const params = %s;
+  const evasions = [
+%s
+  ];
+
+  const nativeFuncs = new WeakMap();
+  const toString = Function.prototype.toString;
+  const patched = new WeakSet();
+
+  const utils = {
+    // native makes the function look like a native function when converted
+    // to a string.
+    native: (fn, name) => {
+      nativeFuncs.set(fn, 'function ' + (name || fn.name) + '() { [native code] }');
+      return fn;
+    },
+    // getter replaces a property getter on a prototype.
+    getter: (proto, prop, get) => {
+      Object.defineProperty(proto, prop, {
+        get: utils.native(get, 'get ' + prop),
+        configurable: true,
+        enumerable: true,
+      });
+    },
+    apply: (win) => {
+      if (!win || patched.has(win)) {
+        return;
+      }
+      patched.add(win);
+
+      for (const evasion of evasions) {
+        try {
+          evasion(win, params, utils);
+        } catch (e) {}
+      }
+    },
+  };
+
+  const patchToString = (win) => {
+    const fnToString = function toString() {
+      if (nativeFuncs.has(this)) {
+        return nativeFuncs.get(this);
+      }
+      return toString.call(this);
+    };
+    nativeFuncs.set(fnToString, 'function toString() { [native code] }');
+    win.Function.prototype.toString = fnToString;
+  };
+
+  evasions.unshift(patchToString);
+  utils.apply(window);
+})();
+`
+
+	evasionScripts = map[Evasion]string{
+		EvasionWebdriver: `
+    (win, params, utils) => {
+      if (win.navigator.webdriver !== false) {
+        utils.getter(win.Navigator.prototype, 'webdriver', () => false);
+      }
+    }`,
+
+		EvasionPlugins: `
+    (win, params, utils) => {
+      if (win.navigator.plugins.length > 0) {
+        return;
+      }
+
+      const mimeTypes = [
+        { type: 'application/pdf', suffixes: 'pdf', description: 'Portable Document Format' },
+        { type: 'text/pdf', suffixes: 'pdf', description: 'Portable Document Format' },
+      ];
+      const names = [
+        'PDF Viewer',
+        'Chrome PDF Viewer',
+        'Chromium PDF Viewer',
+        'Microsoft Edge PDF Viewer',
+        'WebKit built-in PDF',
+      ];
+
+      const makeArray = (proto, items, key) => {
+        const arr = Object.create(proto);
+        items.forEach((item, i) => {
+          Object.defineProperty(arr, i, { value: item, enumerable: true });
+          Object.defineProperty(arr, item[key], { value: item });
+        });
+        Object.defineProperty(arr, 'length', { get: () => items.length });
+        arr.item = utils.native((i) => items[i] || null, 'item');
+        arr.namedItem = utils.native((name) => items.find((it) => it[key] === name) || null, 'namedItem');
+        arr[Symbol.iterator] = function* values() { yield* items; };
+        return arr;
+      };
+
+      const mimes = mimeTypes.map((m) => {
+        const mime = Object.create(win.MimeType.prototype);
+        Object.defineProperties(mime, {
+          type: { get: () => m.type },
+          suffixes: { get: () => m.suffixes },
+          description: { get: () => m.description },
+        });
+        return mime;
+      });
+
+      const plugins = names.map((name) => {
+        const plugin = makeArray(win.Plugin.prototype, mimes, 'type');
+        Object.defineProperties(plugin, {
+          name: { get: () => name },
+          filename: { get: () => 'internal-pdf-viewer' },
+          description: { get: () => 'Portable Document Format' },
+        });
+        return plugin;
+      });
+
+      mimes.forEach((mime) => {
+        Object.defineProperty(mime, 'enabledPlugin', { get: () => plugins[0] });
+      });
+
+      const pluginArray = makeArray(win.PluginArray.prototype, plugins, 'name');
+      pluginArray.refresh = utils.native(() => {}, 'refresh');
+      const mimeTypeArray = makeArray(win.MimeTypeArray.prototype, mimes, 'type');
+
+      utils.getter(win.Navigator.prototype, 'plugins', () => pluginArray);
+      utils.getter(win.Navigator.prototype, 'mimeTypes', () => mimeTypeArray);
+      utils.getter(win.Navigator.prototype, 'pdfViewerEnabled', () => true);
+    }`,
+
+		EvasionChromeRuntime: `
+    (win, params, utils) => {
+      if (!win.chrome) {
+        Object.defineProperty(win, 'chrome', { value: {}, writable: true, configurable: true, enumerable: true });
+      }
+
+      const chrome = win.chrome;
+      const start = Date.now() / 1000;
+
+      if (!chrome.app) {
+        chrome.app = {
+          isInstalled: false,
+          InstallState: { DISABLED: 'disabled', INSTALLED: 'installed', NOT_INSTALLED: 'not_installed' },
+          RunningState: { CANNOT_RUN: 'cannot_run', READY_TO_RUN: 'ready_to_run', RUNNING: 'running' },
+          getDetails: utils.native(() => null, 'getDetails'),
+          getIsInstalled: utils.native(() => false, 'getIsInstalled'),
+          runningState: utils.native(() => 'cannot_run', 'runningState'),
+        };
+      }
+
+      if (!chrome.csi) {
+        chrome.csi = utils.native(() => ({
+          onloadT: Math.round(start * 1000),
+          startE: Math.round(start * 1000),
+          pageT: Date.now() - start * 1000,
+          tran: 15,
+        }), 'csi');
+      }
+
+      if (!chrome.loadTimes) {
+        chrome.loadTimes = utils.native(() => ({
+          commitLoadTime: start,
+          connectionInfo: 'h2',
+          finishDocumentLoadTime: start,
+          finishLoadTime: start,
+          firstPaintAfterLoadTime: 0,
+          firstPaintTime: start,
+          navigationType: 'Other',
+          npnNegotiatedProtocol: 'h2',
+          requestTime: start,
+          startLoadTime: start,
+          wasAlternateProtocolAvailable: false,
+          wasFetchedViaSpdy: true,
+          wasNpnNegotiated: true,
+        }), 'loadTimes');
+      }
+
+      if (!chrome.runtime && win.location.protocol === 'https:') {
+        chrome.runtime = {
+          OnInstalledReason: { CHROME_UPDATE: 'chrome_update', INSTALL: 'install', SHARED_MODULE_UPDATE: 'shared_module_update', UPDATE: 'update' },
+          OnRestartRequiredReason: { APP_UPDATE: 'app_update', OS_UPDATE: 'os_update', PERIODIC: 'periodic' },
+          PlatformArch: { ARM: 'arm', ARM64: 'arm64', MIPS: 'mips', MIPS64: 'mips64', X86_32: 'x86-32', X86_64: 'x86-64' },
+          PlatformOs: { ANDROID: 'android', CROS: 'cros', LINUX: 'linux', MAC: 'mac', OPENBSD: 'openbsd', WIN: 'win' },
+          RequestUpdateCheckStatus: { NO_UPDATE: 'no_update', THROTTLED: 'throttled', UPDATE_AVAILABLE: 'update_available' },
+          id: undefined,
+          connect: utils.native(() => { throw new TypeError('Error in invocation of runtime.connect'); }, 'connect'),
+          sendMessage: utils.native(() => { throw new TypeError('Error in invocation of runtime.sendMessage'); }, 'sendMessage'),
+        };
+      }
+    }`,
+
+		EvasionPermissions: `
+    (win, params, utils) => {
+      const permissions = win.navigator.permissions;
+      if (!permissions || !win.Notification) {
+        return;
+      }
+
+      const query = win.Permissions.prototype.query;
+      win.Permissions.prototype.query = utils.native(function query(desc) {
+        if (desc && desc.name === 'notifications') {
+          const state = win.Notification.permission === 'default' ? 'prompt' : win.Notification.permission;
+          return Promise.resolve(Object.setPrototypeOf({ state, name: 'notifications', onchange: null }, win.PermissionStatus.prototype));
+        }
+        return query.call(this, desc);
+      }, 'query');
+    }`,
+
+		EvasionIframeContentWindow: `
+    (win, params, utils) => {
+      const desc = Object.getOwnPropertyDescriptor(win.HTMLIFrameElement.prototype, 'contentWindow');
+      if (!desc || !desc.get) {
+        return;
+      }
+
+      const get = desc.get;
+      utils.getter(win.HTMLIFrameElement.prototype, 'contentWindow', function contentWindow() {
+        const child = get.call(this);
+        try {
+          utils.apply(child);
+        } catch (e) {
+          // Cross origin frames are patched by their own document script.
+        }
+        return child;
+      });
+    }`,
+
+		EvasionWebGLVendor: `
+    (win, params, utils) => {
+      const UNMASKED_VENDOR = 0x9245;
+      const UNMASKED_RENDERER = 0x9246;
+
+      for (const ctx of [win.WebGLRenderingContext, win.WebGL2RenderingContext]) {
+        if (!ctx) {
+          continue;
+        }
+
+        const getParameter = ctx.prototype.getParameter;
+        ctx.prototype.getParameter = utils.native(function getParameter(p) {
+          if (p === UNMASKED_VENDOR && params.webglVendor) {
+            return params.webglVendor;
+          }
+          if (p === UNMASKED_RENDERER && params.webglRenderer) {
+            return params.webglRenderer;
+          }
+          return getParameter.call(this, p);
+        }, 'getParameter');
+      }
+    }`,
+
+		EvasionHardwareConcurrency: `
+    (win, params, utils) => {
+      if (params.hardwareConcurrency > 0) {
+        utils.getter(win.Navigator.prototype, 'hardwareConcurrency', () => params.hardwareConcurrency);
+      }
+    }`,
+
+		EvasionOuterDimensions: `
+    (win, params, utils) => {
+      if (win.outerWidth === win.innerWidth && win.outerHeight === win.innerHeight) {
+        utils.getter(win, 'outerWidth', () => win.innerWidth);
+        utils.getter(win, 'outerHeight', () => win.innerHeight + 85);
+      }
+    }`,
+	}
+)
@@ -0,0 +1,72 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvasionScript(t *testing.T) {
+	script, err := EvasionScript(AllEvasions...)
+	require.NoError(t, err)
+
+	for _, evasion := range AllEvasions {
+		require.Contains(t, script, evasionScripts[evasion])
+	}
+
+	_, err = EvasionScript("navigator.bogus")
+	require.ErrorIs(t, err, ErrUnknownEvasion)
+
+	cfg := NewConfig(WithHeadlessMode(HeadlessNew), WithEvasions(EvasionPlugins, EvasionWebdriver))
+	require.Equal(t, []Evasion{EvasionPlugins, EvasionWebdriver, EvasionOuterDimensions}, cfg.evasions())
+}
+
+func TestEvasions(t *testing.T) {
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(20*time.Second),
+			WithHeadless(),
+			WithEvasions(AllEvasions...),
+		),
+		func(ctx context.Context) error {
+			var res struct {
+				Webdriver bool   `json:"webdriver"`
+				Plugins   int    `json:"plugins"`
+				Chrome    bool   `json:"chrome"`
+				Frame     bool   `json:"frame"`
+				Native    string `json:"native"`
+			}
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate("https://www.example.com/"),
+				chromedp.Evaluate(`(() => {
+					const iframe = document.createElement('iframe');
+					iframe.srcdoc = 'frame';
+					document.body.appendChild(iframe);
+					return {
+						webdriver: navigator.webdriver,
+						plugins: navigator.plugins.length,
+						chrome: !!window.chrome,
+						frame: !!iframe.contentWindow.chrome && iframe.contentWindow.navigator.webdriver === false,
+						native: Object.getOwnPropertyDescriptor(Navigator.prototype, 'webdriver').get.toString(),
+					};
+				})()`, &res),
+			); err != nil {
+				return err
+			}
+
+			if res.Webdriver || res.Plugins == 0 || !res.Chrome || !res.Frame {
+				return fmt.Errorf("evasions not applied: %+v", res)
+			}
+
+			require.Equal(t, "function get webdriver() { [native code] }", res.Native)
+
+			return nil
+		},
+	)
+}
@@ -0,0 +1,631 @@
+package chromedpundetected
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// Errors.
+var (
+	ErrOptionNotFound = errors.New("option not found")
+	ErrNotCheckable   = errors.New("element is not a checkbox")
+	ErrNotRadio       = errors.New("element is not a radio button")
+	ErrNotFileInput   = errors.New("element is not a file input")
+)
+
+// FocusMethod is how the form actions move the focus to a field.
+type FocusMethod string
+
+// Focus methods.
+const (
+	// FocusClick clicks the field.
+	FocusClick FocusMethod = "click"
+
+	// FocusTab presses Tab, or Shift+Tab, until the field has the focus, and
+	// uses the keyboard from there. Fields Tab does not reach are clicked.
+	FocusTab FocusMethod = "tab"
+)
+
+// FormOptions contains options for the form actions.
+type FormOptions struct {
+	focus       FocusMethod
+	maxTabs     int
+	optionSel   string
+	openTimeout time.Duration
+	click       []ClickOptionSetter
+	typing      []TypeOptionSetter
+	query       []chromedp.QueryOption
+}
+
+// Default values for the form actions.
+var defaultFormOptions = FormOptions{
+	focus:       FocusClick,
+	maxTabs:     30,
+	optionSel:   `[role=option]`,
+	openTimeout: 3 * time.Second,
+}
+
+// FormOptionSetter defines a function type to set form options.
+type FormOptionSetter func(*FormOptions)
+
+// WithFocusMethod returns a FormOptionSetter that sets how fields are
+// focused. Defaults to FocusClick.
+func WithFocusMethod(method FocusMethod) FormOptionSetter {
+	return func(opt *FormOptions) {
+		opt.focus = method
+	}
+}
+
+// WithFormOptionSelector returns a FormOptionSetter that sets the CSS selector
+// of the options of custom dropdowns, that are not a select element. Defaults
+// to [role=option].
+func WithFormOptionSelector(sel string) FormOptionSetter {
+	return func(opt *FormOptions) {
+		opt.optionSel = sel
+	}
+}
+
+// WithFormClickOptions returns a FormOptionSetter that sets the options of
+// clicking fields and options.
+func WithFormClickOptions(setters ...ClickOptionSetter) FormOptionSetter {
+	return func(opt *FormOptions) {
+		opt.click = append(opt.click, setters...)
+	}
+}
+
+// WithFormTypeOptions returns a FormOptionSetter that sets the options of the
+// key presses, e.g. the keyboard layout.
+func WithFormTypeOptions(setters ...TypeOptionSetter) FormOptionSetter {
+	return func(opt *FormOptions) {
+		opt.typing = append(opt.typing, setters...)
+	}
+}
+
+// WithFormQueryOptions returns a FormOptionSetter that sets the options of the
+// element queries, e.g. chromedp.ByQuery.
+func WithFormQueryOptions(opts ...chromedp.QueryOption) FormOptionSetter {
+	return func(opt *FormOptions) {
+		opt.query = append(opt.query, opts...)
+	}
+}
+
+// SelectOption selects the option with the value, or else the text, in the
+// first element matching the selector, like a human would.
+//
+// A dropdown select is clicked open, or focused with Tab, and the option is
+// reached with the arrow keys and picked with Enter. The options of a list
+// box select are clicked. Any other element is taken for a custom dropdown:
+// it is clicked open, and the option matching the option selector, see
+// WithFormOptionSelector, is clicked.
+func SelectOption(sel any, option string, setters ...FormOptionSetter) chromedp.ActionFunc {
+	return formAction(sel, setters, func(ctx context.Context, f *formControl, nodeID cdp.NodeID) error {
+		want, err := json.Marshal(option)
+		if err != nil {
+			return err
+		}
+
+		var state struct {
+			Select  bool `json:"select"`
+			Listbox bool `json:"listbox"`
+			Current int  `json:"current"`
+			Target  int  `json:"target"`
+		}
+
+		if err := callIsolatedOn(ctx, nodeID, fmt.Sprintf(selectStateJS, want), &state); err != nil {
+			return err
+		}
+
+		if !state.Select {
+			return f.selectCustom(ctx, nodeID, string(want), option)
+		}
+
+		if state.Target < 0 {
+			return fmt.Errorf("%w: %q", ErrOptionNotFound, option)
+		}
+
+		if state.Target == state.Current {
+			return nil
+		}
+
+		if state.Listbox {
+			optionID, err := isolatedNodeOn(ctx, nodeID, fmt.Sprintf(selectOptionNodeJS, state.Target))
+			if err != nil {
+				return err
+			}
+
+			return clickNode(ctx, optionID, f.click)
+		}
+
+		clicked, err := f.focus(ctx, nodeID)
+		if err != nil {
+			return err
+		}
+
+		// Arrow down from no selection selects the first option.
+		key, steps := "ArrowDown", state.Target-state.Current
+		if steps < 0 {
+			key, steps = "ArrowUp", -steps
+		}
+
+		for i := 0; i < steps; i++ {
+			if err := f.press(ctx, key); err != nil {
+				return err
+			}
+		}
+
+		// Close the popup a click opened.
+		if clicked {
+			return f.press(ctx, "Enter")
+		}
+
+		return nil
+	})
+}
+
+// Check checks the checkbox matching the selector, unless it is checked
+// already, by clicking it or its label, or with Space after focusing it with
+// Tab. Elements with the checkbox or switch role are supported too.
+func Check(sel any, setters ...FormOptionSetter) chromedp.ActionFunc {
+	return formAction(sel, setters, func(ctx context.Context, f *formControl, nodeID cdp.NodeID) error {
+		return f.setChecked(ctx, nodeID, true)
+	})
+}
+
+// Uncheck unchecks the checkbox matching the selector, unless it is
+// unchecked already, like Check does.
+func Uncheck(sel any, setters ...FormOptionSetter) chromedp.ActionFunc {
+	return formAction(sel, setters, func(ctx context.Context, f *formControl, nodeID cdp.NodeID) error {
+		return f.setChecked(ctx, nodeID, false)
+	})
+}
+
+// ChooseRadio checks the radio button matching the selector, unless it is
+// checked already, by clicking it or its label. With FocusTab the focus moves
+// into the group with Tab, and on to the button with the arrow keys.
+func ChooseRadio(sel any, setters ...FormOptionSetter) chromedp.ActionFunc {
+	return formAction(sel, setters, func(ctx context.Context, f *formControl, nodeID cdp.NodeID) error {
+		var state struct {
+			Radio   bool `json:"radio"`
+			Checked bool `json:"checked"`
+		}
+
+		if err := callIsolatedOn(ctx, nodeID, radioStateJS, &state); err != nil {
+			return err
+		}
+
+		if !state.Radio {
+			return ErrNotRadio
+		}
+
+		if state.Checked {
+			return nil
+		}
+
+		if f.options.focus != FocusTab {
+			return f.clickTarget(ctx, nodeID)
+		}
+
+		clicked, err := f.focus(ctx, nodeID)
+		if err != nil || clicked {
+			return err
+		}
+
+		var group struct {
+			Active int `json:"active"`
+			Target int `json:"target"`
+		}
+
+		if err := callIsolatedOn(ctx, nodeID, radioGroupJS, &group); err != nil {
+			return err
+		}
+
+		if group.Active < 0 || group.Active == group.Target {
+			return f.press(ctx, "Space")
+		}
+
+		// The arrow keys check every button on the way.
+		key, steps := "ArrowDown", group.Target-group.Active
+		if steps < 0 {
+			key, steps = "ArrowUp", -steps
+		}
+
+		for i := 0; i < steps; i++ {
+			if err := f.press(ctx, key); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// SetFiles sets the files of the file input matching the selector. The input,
+// or its label if the input is hidden, is clicked, or focused with Tab and
+// opened with Space, and the file chooser it opens is answered with the
+// files. If no chooser opens, the files are set on the input directly.
+func SetFiles(sel any, files []string, setters ...FormOptionSetter) chromedp.ActionFunc {
+	return formAction(sel, setters, func(ctx context.Context, f *formControl, nodeID cdp.NodeID) error {
+		paths := make([]string, 0, len(files))
+
+		for _, file := range files {
+			path, err := filepath.Abs(file)
+			if err != nil {
+				return err
+			}
+
+			paths = append(paths, path)
+		}
+
+		var isFile bool
+		if err := callIsolatedOn(ctx, nodeID, `function() { return this.tagName === 'INPUT' && this.type === 'file'; }`, &isFile); err != nil {
+			return err
+		}
+
+		if !isFile {
+			return ErrNotFileInput
+		}
+
+		listenCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		opened := make(chan cdp.BackendNodeID, 1)
+
+		chromedp.ListenTarget(listenCtx, func(ev interface{}) {
+			if e, ok := ev.(*page.EventFileChooserOpened); ok {
+				select {
+				case opened <- e.BackendNodeID:
+				default:
+				}
+			}
+		})
+
+		if err := page.SetInterceptFileChooserDialog(true).Do(ctx); err != nil {
+			return fmt.Errorf("intercept file chooser: %w", err)
+		}
+
+		defer page.SetInterceptFileChooserDialog(false).Do(ctx) //nolint:errcheck
+
+		clicked, err := f.focus(ctx, nodeID)
+		if err != nil {
+			return err
+		}
+
+		if !clicked {
+			if err := f.press(ctx, "Space"); err != nil {
+				return err
+			}
+		}
+
+		t := time.NewTimer(2 * time.Second)
+		defer t.Stop()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case id := <-opened:
+			// Take a moment to pick the files.
+			if err := sleepContext(ctx, randDuration(f.rnd, 800*time.Millisecond, 2500*time.Millisecond)); err != nil {
+				return err
+			}
+
+			return dom.SetFileInputFiles(paths).WithBackendNodeID(id).Do(ctx)
+		case <-t.C:
+			return dom.SetFileInputFiles(paths).WithNodeID(nodeID).Do(ctx)
+		}
+	})
+}
+
+// selectStateJS returns whether the node is a select element, and the index
+// of the selected option and of the option with the value or text among the
+// enabled options.
+const selectStateJS = `function() {
+	const want = %s;
+	if (this.tagName !== 'SELECT') {
+		return { select: false, listbox: false, current: -1, target: -1 };
+	}
+
+	const options = [...this.options].filter((o) => !o.disabled && !(o.parentElement.tagName === 'OPTGROUP' && o.parentElement.disabled));
+	let target = options.findIndex((o) => o.value === want);
+	if (target < 0) {
+		target = options.findIndex((o) => o.text.trim() === want.trim());
+	}
+
+	return {
+		select: true,
+		listbox: this.multiple || this.size > 1,
+		current: options.findIndex((o) => o.selected),
+		target: target,
+	};
+}`
+
+// selectOptionNodeJS returns the enabled option of the select at the index.
+const selectOptionNodeJS = `function() {
+	const options = [...this.options].filter((o) => !o.disabled && !(o.parentElement.tagName === 'OPTGROUP' && o.parentElement.disabled));
+	return options[%d] || null;
+}`
+
+// customOptionJS returns the shown option with the value or text matching
+// the selector, preferring the list box the node controls.
+const customOptionJS = `function() {
+	const want = %s, sel = %s;
+	const shown = (e) => {
+		const r = e.getBoundingClientRect();
+		return r.width > 0 && r.height > 0 && getComputedStyle(e).visibility !== 'hidden';
+	};
+
+	const id = this.getAttribute('aria-controls') || this.getAttribute('aria-owns');
+	const root = (id && document.getElementById(id)) || document;
+	const options = [...root.querySelectorAll(sel)].filter(shown);
+
+	return options.find((o) => o.getAttribute('data-value') === want || o.getAttribute('value') === want) ||
+		options.find((o) => o.textContent.trim() === want.trim()) ||
+		null;
+}`
+
+// checkedStateJS returns whether the checkbox is checked, or null if it is
+// not a checkbox.
+const checkedStateJS = `function() {
+	if (this.tagName === 'INPUT' && this.type === 'checkbox') {
+		return this.checked;
+	}
+
+	const role = this.getAttribute('role');
+	if (role === 'checkbox' || role === 'switch') {
+		return this.getAttribute('aria-checked') === 'true';
+	}
+
+	return null;
+}`
+
+// radioStateJS returns whether the node is a radio button, and whether it is
+// checked.
+const radioStateJS = `function() {
+	const radio = this.tagName === 'INPUT' && this.type === 'radio';
+	return { radio: radio, checked: radio && this.checked };
+}`
+
+// radioGroupJS returns the index of the focused and of the node among the
+// enabled buttons of its radio group.
+const radioGroupJS = `function() {
+	const group = this.name
+		? [...(this.form || document).querySelectorAll('input[type=radio]')].filter((r) => r.name === this.name && r.form === this.form && !r.disabled)
+		: [this];
+
+	return { active: group.indexOf(document.activeElement), target: group.indexOf(this) };
+}`
+
+// focusStateJS returns whether the node has the focus, or for radio buttons
+// its group, and whether it comes before the focused element.
+const focusStateJS = `function() {
+	const active = document.activeElement;
+	if (active === this) {
+		return { focused: true, before: false };
+	}
+
+	// Tab moves into a radio group at its checked button.
+	if (this.type === 'radio' && this.name && active && active.type === 'radio' && active.name === this.name && active.form === this.form) {
+		return { focused: true, before: false };
+	}
+
+	if (!active || active === document.body) {
+		return { focused: false, before: false };
+	}
+
+	return { focused: false, before: !!(active.compareDocumentPosition(this) & Node.DOCUMENT_POSITION_PRECEDING) };
+}`
+
+// shownLabelJS returns the first shown label of the node if the node itself
+// is not shown, e.g. a checkbox hidden behind a styled label.
+const shownLabelJS = `function() {
+	const shown = (e) => {
+		const r = e.getBoundingClientRect();
+		return r.width > 0 && r.height > 0;
+	};
+
+	if (shown(this)) {
+		return null;
+	}
+
+	return [...(this.labels || [])].find(shown) || null;
+}`
+
+// formControl operates form fields with the options.
+type formControl struct {
+	options FormOptions
+	click   ClickOptions
+	layout  *KeyboardLayout
+	rnd     *rand.Rand
+}
+
+// formAction returns an action that runs fn on the first node matching the
+// selector. Hidden nodes match too, they may be operated through their label.
+func formAction(sel any, setters []FormOptionSetter, fn func(context.Context, *formControl, cdp.NodeID) error) chromedp.ActionFunc {
+	options := defaultFormOptions
+
+	for _, setter := range setters {
+		setter(&options)
+	}
+
+	click := defaultClickOptions
+	for _, setter := range options.click {
+		setter(&click)
+	}
+
+	typing := defaultTypeOptions
+	for _, setter := range options.typing {
+		setter(&typing)
+	}
+
+	if typing.layout == nil {
+		typing.layout = defaultTypeOptions.layout
+	}
+
+	return func(ctx context.Context) error {
+		nodeID, err := queryNode(ctx, sel, append([]chromedp.QueryOption{chromedp.NodeReady}, options.query...))
+		if err != nil {
+			return err
+		}
+
+		f := &formControl{
+			options: options,
+			click:   click,
+			layout:  typing.layout,
+			rnd:     randFor(ctx),
+		}
+
+		return fn(ctx, f, nodeID)
+	}
+}
+
+// press presses the key or shortcut, and pauses before the next one.
+func (f *formControl) press(ctx context.Context, chord string) error {
+	c, err := parseChord(chord, f.layout)
+	if err != nil {
+		return err
+	}
+
+	if err := pressChord(ctx, c, f.layout, f.rnd, nil); err != nil {
+		return err
+	}
+
+	return sleepContext(ctx, randDuration(f.rnd, 80*time.Millisecond, 250*time.Millisecond))
+}
+
+// clickTarget clicks the node, or its label if the node is not shown.
+func (f *formControl) clickTarget(ctx context.Context, nodeID cdp.NodeID) error {
+	labelID, err := isolatedNodeOn(ctx, nodeID, shownLabelJS)
+	if err != nil {
+		return err
+	}
+
+	if labelID != 0 {
+		nodeID = labelID
+	}
+
+	return clickNode(ctx, nodeID, f.click)
+}
+
+// focus moves the focus to the node with the focus method of the options,
+// and reports whether it clicked the node to do so.
+func (f *formControl) focus(ctx context.Context, nodeID cdp.NodeID) (bool, error) {
+	if f.options.focus == FocusTab {
+		focused, err := f.focusByTab(ctx, nodeID)
+		if err != nil || focused {
+			return false, err
+		}
+	}
+
+	if err := f.clickTarget(ctx, nodeID); err != nil {
+		return false, err
+	}
+
+	// Look at what the click opened.
+	return true, sleepContext(ctx, randDuration(f.rnd, 150*time.Millisecond, 400*time.Millisecond))
+}
+
+// focusByTab presses Tab, or Shift+Tab if the node comes before the focused
+// element, until the node has the focus, and reports whether it got it.
+func (f *formControl) focusByTab(ctx context.Context, nodeID cdp.NodeID) (bool, error) {
+	for i := 0; i < f.options.maxTabs; i++ {
+		var state struct {
+			Focused bool `json:"focused"`
+			Before  bool `json:"before"`
+		}
+
+		if err := callIsolatedOn(ctx, nodeID, focusStateJS, &state); err != nil {
+			return false, err
+		}
+
+		if state.Focused {
+			return true, nil
+		}
+
+		chord := "Tab"
+		if state.Before {
+			chord = "Shift+Tab"
+		}
+
+		if err := f.press(ctx, chord); err != nil {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+// setChecked checks or unchecks the checkbox.
+func (f *formControl) setChecked(ctx context.Context, nodeID cdp.NodeID, checked bool) error {
+	var state *bool
+	if err := callIsolatedOn(ctx, nodeID, checkedStateJS, &state); err != nil {
+		return err
+	}
+
+	if state == nil {
+		return ErrNotCheckable
+	}
+
+	if *state == checked {
+		return nil
+	}
+
+	if f.options.focus != FocusTab {
+		return f.clickTarget(ctx, nodeID)
+	}
+
+	clicked, err := f.focus(ctx, nodeID)
+	if err != nil || clicked {
+		return err
+	}
+
+	return f.press(ctx, "Space")
+}
+
+// selectCustom opens a custom dropdown by clicking it, and clicks the option
+// once it shows.
+func (f *formControl) selectCustom(ctx context.Context, nodeID cdp.NodeID, want, option string) error {
+	if err := f.clickTarget(ctx, nodeID); err != nil {
+		return err
+	}
+
+	sel, err := json.Marshal(f.options.optionSel)
+	if err != nil {
+		return err
+	}
+
+	function := fmt.Sprintf(customOptionJS, want, sel)
+	deadline := time.Now().Add(f.options.openTimeout)
+
+	for {
+		optionID, err := isolatedNodeOn(ctx, nodeID, function)
+		if err != nil {
+			return err
+		}
+
+		if optionID != 0 {
+			// Read the options before picking one.
+			if err := sleepContext(ctx, randDuration(f.rnd, 200*time.Millisecond, 600*time.Millisecond)); err != nil {
+				return err
+			}
+
+			return clickNode(ctx, optionID, f.click)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w: %q", ErrOptionNotFound, option)
+		}
+
+		if err := sleepContext(ctx, 100*time.Millisecond); err != nil {
+			return err
+		}
+	}
+}
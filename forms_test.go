@@ -0,0 +1,132 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormScripts(t *testing.T) {
+	// The scripts have no format verbs other than the values they take.
+	for _, script := range []string{
+		fmt.Sprintf(selectStateJS, `"100%"`),
+		fmt.Sprintf(selectOptionNodeJS, 2),
+		fmt.Sprintf(customOptionJS, `"a"`, `"[role=option]"`),
+	} {
+		require.NotContains(t, script, "%!")
+	}
+
+	options := defaultFormOptions
+	for _, setter := range []FormOptionSetter{
+		WithFocusMethod(FocusTab),
+		WithFormOptionSelector(`li.option`),
+		WithFormQueryOptions(chromedp.ByQuery),
+	} {
+		setter(&options)
+	}
+
+	require.Equal(t, FocusTab, options.focus)
+	require.Equal(t, `li.option`, options.optionSel)
+	require.Len(t, options.query, 1)
+	require.Equal(t, FocusClick, defaultFormOptions.focus)
+}
+
+func TestForms(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body>
+			<form>
+				<input id="name">
+				<select id="country">
+					<option value="nl">Netherlands</option>
+					<option value="de" disabled>Germany</option>
+					<option value="fr">France</option>
+					<option value="be">Belgium</option>
+				</select>
+				<select id="colors" size="4">
+					<option>Red</option><option>Green</option><option>Blue</option><option>Yellow</option>
+				</select>
+				<input id="terms" type="checkbox">
+				<label for="news"><span>Newsletter</span></label><input id="news" type="checkbox" checked style="display: none">
+				<input type="radio" name="plan" value="free" id="free" checked>
+				<input type="radio" name="plan" value="pro" id="pro">
+				<input type="radio" name="plan" value="team" id="team">
+				<input id="upload" type="file">
+			</form>
+			<div id="size" role="combobox" aria-controls="sizes" style="width: 120px; height: 30px; background: #ddd">pick a size</div>
+			<ul id="sizes" role="listbox" style="display: none">
+				<li role="option" data-value="s">Small</li>
+				<li role="option" data-value="m">Medium</li>
+			</ul>
+			<script>
+				window.trusted = true;
+				document.addEventListener('change', (e) => window.trusted = window.trusted && e.isTrusted);
+				document.getElementById('size').addEventListener('click', () => document.getElementById('sizes').style.display = 'block');
+				for (const li of document.querySelectorAll('#sizes li')) {
+					li.addEventListener('click', () => window.size = li.dataset.value);
+				}
+			</script>
+		</body></html>`)
+	}))
+	defer srv.Close()
+
+	file := filepath.Join(t.TempDir(), "upload.txt")
+	require.NoError(t, os.WriteFile(file, []byte("hello"), 0o600))
+
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(90*time.Second),
+			WithHeadless(),
+		),
+		func(ctx context.Context) error {
+			byQuery := WithFormQueryOptions(chromedp.ByQuery)
+			tab := WithFocusMethod(FocusTab)
+
+			var (
+				country, colors, plan, size, files string
+				terms, news, trusted               bool
+			)
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate(srv.URL),
+				SelectOption(`#country`, "be", byQuery),
+				SelectOption(`#colors`, "Blue", byQuery),
+				Check(`#terms`, byQuery, tab),
+				Uncheck(`#news`, byQuery),
+				ChooseRadio(`#team`, byQuery, tab),
+				SetFiles(`#upload`, []string{file}, byQuery),
+				SelectOption(`#size`, "Medium", byQuery),
+				chromedp.Value(`#country`, &country, chromedp.ByQuery),
+				chromedp.Value(`#colors`, &colors, chromedp.ByQuery),
+				chromedp.Evaluate(`document.getElementById('terms').checked`, &terms),
+				chromedp.Evaluate(`document.getElementById('news').checked`, &news),
+				chromedp.Evaluate(`document.querySelector('input[name=plan]:checked').value`, &plan),
+				chromedp.Evaluate(`[...document.getElementById('upload').files].map((f) => f.name).join(',')`, &files),
+				chromedp.Evaluate(`window.size || ''`, &size),
+				chromedp.Evaluate(`window.trusted`, &trusted),
+			); err != nil {
+				return err
+			}
+
+			got := strings.Join([]string{country, colors, plan, files, size}, " ")
+			if got != "be Blue team upload.txt m" || !terms || news {
+				return fmt.Errorf("got %q, terms %v, news %v", got, terms, news)
+			}
+
+			if !trusted {
+				return fmt.Errorf("untrusted change events")
+			}
+
+			return nil
+		},
+	)
+}
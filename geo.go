@@ -0,0 +1,250 @@
+package chromedpundetected
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+	"golang.org/x/text/language"
+
+	"github.com/Davincible/chromedp-undetected/util/mmdb"
+)
+
+// Errors.
+var (
+	ErrInvalidTimezone    = errors.New("invalid timezone")
+	ErrInvalidGeolocation = errors.New("invalid geolocation")
+	ErrGeoIPNotFound      = errors.New("IP address not found in GeoIP database")
+)
+
+// Geolocation is a position reported through the geolocation API.
+type Geolocation struct {
+	Latitude  float64 `json:"latitude" yaml:"latitude"`
+	Longitude float64 `json:"longitude" yaml:"longitude"`
+
+	// Accuracy is the accuracy in meters.
+	Accuracy float64 `json:"accuracy" yaml:"accuracy"`
+}
+
+// Validate checks that the coordinates are in range.
+func (g Geolocation) Validate() error {
+	if g.Latitude < -90 || g.Latitude > 90 {
+		return fmt.Errorf("%w: latitude %v out of range", ErrInvalidGeolocation, g.Latitude)
+	}
+
+	if g.Longitude < -180 || g.Longitude > 180 {
+		return fmt.Errorf("%w: longitude %v out of range", ErrInvalidGeolocation, g.Longitude)
+	}
+
+	if g.Accuracy < 0 {
+		return fmt.Errorf("%w: negative accuracy", ErrInvalidGeolocation)
+	}
+
+	return nil
+}
+
+// GeoInfo is the location of an IP address.
+type GeoInfo struct {
+	// Country is the ISO 3166-1 country code, e.g. "NL".
+	Country string `json:"country"`
+
+	// Timezone is the IANA timezone, e.g. "Europe/Amsterdam".
+	Timezone string `json:"timezone"`
+
+	// Languages are the languages likely spoken in the country, e.g.
+	// ["nl-NL", "nl;q=0.9", "en;q=0.8"].
+	Languages []string `json:"languages"`
+
+	// Geolocation are the coordinates, if known.
+	Geolocation *Geolocation `json:"geolocation,omitempty"`
+}
+
+// GeoIP resolves IP addresses to locations with a MaxMind DB, such as the
+// GeoLite2 City database.
+type GeoIP struct {
+	db *mmdb.Reader
+}
+
+// OpenGeoIP opens a MaxMind DB file, e.g. "GeoLite2-City.mmdb".
+func OpenGeoIP(path string) (*GeoIP, error) {
+	db, err := mmdb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open GeoIP database: %w", err)
+	}
+
+	return &GeoIP{db: db}, nil
+}
+
+// Lookup returns the location of the IP address.
+func (g *GeoIP) Lookup(ip string) (GeoInfo, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return GeoInfo{}, fmt.Errorf("invalid IP address %q", ip)
+	}
+
+	v, err := g.db.Lookup(addr)
+	if errors.Is(err, mmdb.ErrNotFound) {
+		return GeoInfo{}, fmt.Errorf("%w: %s", ErrGeoIPNotFound, ip)
+	} else if err != nil {
+		return GeoInfo{}, fmt.Errorf("lookup %s: %w", ip, err)
+	}
+
+	record, _ := v.(map[string]any)                    //nolint:errcheck
+	country, _ := record["country"].(map[string]any)   //nolint:errcheck
+	location, _ := record["location"].(map[string]any) //nolint:errcheck
+
+	var info GeoInfo
+
+	info.Country, _ = country["iso_code"].(string)    //nolint:errcheck
+	info.Timezone, _ = location["time_zone"].(string) //nolint:errcheck
+	info.Languages = countryLanguages(info.Country)
+
+	lat, okLat := location["latitude"].(float64)
+	lon, okLon := location["longitude"].(float64)
+
+	if okLat && okLon {
+		info.Geolocation = &Geolocation{Latitude: lat, Longitude: lon}
+
+		// The accuracy radius is in kilometers.
+		if radius, ok := location["accuracy_radius"].(uint64); ok {
+			info.Geolocation.Accuracy = float64(radius) * 1000
+		}
+	}
+
+	return info, nil
+}
+
+// countryLanguages returns the likely languages of a country, followed by
+// English, or nil if unknown.
+func countryLanguages(country string) []string {
+	region, err := language.ParseRegion(country)
+	if err != nil {
+		return nil
+	}
+
+	tag, err := language.Compose(region)
+	if err != nil {
+		return nil
+	}
+
+	base, confidence := tag.Base()
+	if confidence < language.High {
+		return nil
+	}
+
+	langs := []string{base.String() + "-" + region.String(), base.String() + ";q=0.9"}
+
+	if base.String() != "en" {
+		langs = append(langs, "en;q=0.8")
+	}
+
+	return langs
+}
+
+// resolveGeoIP fills the timezone, geolocation and languages of the config
+// from the location of the exit IP, if a GeoIP database is configured.
+// Explicitly configured values, including those of the persona, are kept.
+func (c *Config) resolveGeoIP() error {
+	if c.GeoIPDatabase == "" || c.ExitIP == "" {
+		return nil
+	}
+
+	geoIP, err := OpenGeoIP(c.GeoIPDatabase)
+	if err != nil {
+		return err
+	}
+
+	info, err := geoIP.Lookup(c.ExitIP)
+	if err != nil {
+		return err
+	}
+
+	if c.timezone() == "" {
+		c.Timezone = info.Timezone
+	}
+
+	if c.Geolocation == nil {
+		c.Geolocation = info.Geolocation
+	}
+
+	if len(c.Languages) == 0 && c.Language == "" && (c.Persona == nil || len(c.Persona.Languages) == 0) {
+		c.Languages = info.Languages
+	}
+
+	return nil
+}
+
+// timezone returns the timezone of the config, taken from the persona if not
+// set.
+func (c Config) timezone() string {
+	if c.Timezone == "" && c.Persona != nil {
+		return c.Persona.Timezone
+	}
+
+	return c.Timezone
+}
+
+// validateGeo checks the timezone and geolocation of the config.
+func (c Config) validateGeo() error {
+	if tz := c.timezone(); tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			return fmt.Errorf("%w %q: %s", ErrInvalidTimezone, tz, err.Error())
+		}
+	}
+
+	if c.Geolocation != nil {
+		return c.Geolocation.Validate()
+	}
+
+	return nil
+}
+
+// geoFlags returns the launch options that set the timezone of the browser
+// process, which is used before the per tab override applies, e.g. by workers.
+func geoFlags(config Config) []chromedp.ExecAllocatorOption {
+	if tz := config.timezone(); tz != "" {
+		return []chromedp.ExecAllocatorOption{chromedp.Env("TZ=" + tz)}
+	}
+
+	return nil
+}
+
+// geoSetup returns the per tab actions that set the timezone and geolocation.
+func geoSetup(config Config) []chromedp.Action {
+	var actions []chromedp.Action
+
+	if tz := config.timezone(); tz != "" {
+		actions = append(actions, emulation.SetTimezoneOverride(tz))
+	}
+
+	if g := config.Geolocation; g != nil {
+		actions = append(actions,
+			grantGeolocation(),
+			emulation.SetGeolocationOverride().
+				WithLatitude(g.Latitude).
+				WithLongitude(g.Longitude).
+				WithAccuracy(g.Accuracy),
+		)
+	}
+
+	return actions
+}
+
+// grantGeolocation grants all origins permission to use the geolocation API,
+// so pages get the position without a prompt.
+func grantGeolocation() chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		// Permissions are granted by the browser, not the tab.
+		if c := chromedp.FromContext(ctx); c != nil && c.Browser != nil {
+			ctx = cdp.WithExecutor(ctx, c.Browser)
+		}
+
+		return browser.GrantPermissions([]browser.PermissionType{browser.PermissionTypeGeolocation}).Do(ctx)
+	}
+}
@@ -0,0 +1,122 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeoIPLookup(t *testing.T) {
+	geoIP, err := OpenGeoIP("testdata/geoip-test.mmdb")
+	require.NoError(t, err)
+
+	info, err := geoIP.Lookup("81.2.69.160")
+	require.NoError(t, err)
+	require.Equal(t, GeoInfo{
+		Country:     "NL",
+		Timezone:    "Europe/Amsterdam",
+		Languages:   []string{"nl-NL", "nl;q=0.9", "en;q=0.8"},
+		Geolocation: &Geolocation{Latitude: 52.3824, Longitude: 4.8995, Accuracy: 20000},
+	}, info)
+
+	info, err = geoIP.Lookup("2001:db8::42")
+	require.NoError(t, err)
+	require.Equal(t, "Asia/Tokyo", info.Timezone)
+	require.Equal(t, []string{"ja-JP", "ja;q=0.9", "en;q=0.8"}, info.Languages)
+
+	_, err = geoIP.Lookup("8.8.8.8")
+	require.ErrorIs(t, err, ErrGeoIPNotFound)
+
+	_, err = geoIP.Lookup("not an ip")
+	require.Error(t, err)
+}
+
+func TestResolveGeoIP(t *testing.T) {
+	c := NewConfig(WithGeoIP("testdata/geoip-test.mmdb", "81.2.69.160"), WithTimezone("Europe/Berlin"))
+	require.NoError(t, c.resolveGeoIP())
+	require.Equal(t, "Europe/Berlin", c.Timezone)
+	require.Equal(t, []string{"nl-NL", "nl;q=0.9", "en;q=0.8"}, c.Languages)
+	require.NotNil(t, c.Geolocation)
+
+	c = NewConfig(WithGeoIP("testdata/geoip-test.mmdb", "81.2.69.160"), WithLanguages("de-DE"))
+	require.NoError(t, c.resolveGeoIP())
+	require.Equal(t, "Europe/Amsterdam", c.Timezone)
+	require.Equal(t, []string{"de-DE"}, c.Languages)
+
+	c = NewConfig(WithGeoIP("testdata/geoip-test.mmdb", "81.2.69.160"),
+		WithPersona(Persona{Timezone: "Europe/Paris", Languages: []string{"fr-FR"}}))
+	require.NoError(t, c.resolveGeoIP())
+	require.Empty(t, c.Timezone)
+	require.Equal(t, "Europe/Paris", c.timezone())
+	require.Empty(t, c.Languages)
+	require.NotNil(t, c.Geolocation)
+
+	require.Equal(t, []string{"en-US", "en;q=0.9"}, countryLanguages("US"))
+	require.Nil(t, countryLanguages("XX"))
+}
+
+func TestValidateGeo(t *testing.T) {
+	require.NoError(t, NewConfig(WithTimezone("Asia/Tokyo"), WithGeolocation(35.6, 139.6, 100)).validateGeo())
+	require.ErrorIs(t, NewConfig(WithTimezone("Mars/Olympus")).validateGeo(), ErrInvalidTimezone)
+	require.ErrorIs(t, NewConfig(WithGeolocation(91, 0, 0)).validateGeo(), ErrInvalidGeolocation)
+	require.ErrorIs(t, NewConfig(WithGeolocation(0, 181, 0)).validateGeo(), ErrInvalidGeolocation)
+
+	c := NewConfig(WithPersona(Persona{Timezone: "Europe/Paris"}))
+	require.Equal(t, "Europe/Paris", c.timezone())
+
+	c.Timezone = "Asia/Tokyo"
+	require.Equal(t, "Asia/Tokyo", c.timezone())
+}
+
+func TestTimezoneGeolocation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><body>geolocation</body></html>")
+	}))
+	defer srv.Close()
+
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(20*time.Second),
+			WithHeadless(),
+			WithTimezone("Asia/Tokyo"),
+			WithGeolocation(35.6897, 139.6895, 100),
+		),
+		func(ctx context.Context) error {
+			var res struct {
+				Timezone  string  `json:"timezone"`
+				Latitude  float64 `json:"latitude"`
+				Longitude float64 `json:"longitude"`
+			}
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate(srv.URL),
+				chromedp.Evaluate(`new Promise((resolve, reject) => {
+					navigator.geolocation.getCurrentPosition((p) => resolve({
+						timezone: Intl.DateTimeFormat().resolvedOptions().timeZone,
+						latitude: p.coords.latitude,
+						longitude: p.coords.longitude,
+					}), (err) => reject(err.message));
+				})`, &res, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+					return p.WithAwaitPromise(true)
+				}),
+			); err != nil {
+				return err
+			}
+
+			if res.Timezone != "Asia/Tokyo" || math.Abs(res.Latitude-35.6897) > 1e-6 || math.Abs(res.Longitude-139.6895) > 1e-6 {
+				return fmt.Errorf("unexpected location: %+v", res)
+			}
+
+			return nil
+		},
+	)
+}
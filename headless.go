@@ -0,0 +1,113 @@
+package chromedpundetected
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/chromedp/chromedp"
+)
+
+// HeadlessMode selects how Chrome runs without a visible window.
+type HeadlessMode string
+
+// Headless modes.
+const (
+	// HeadlessOff runs a regular Chrome with a visible window.
+	HeadlessOff HeadlessMode = "off"
+
+	// HeadlessVirtualDisplay runs a regular Chrome on an Xvfb virtual display.
+	// Requires Xvfb to be installed, only available on Linux.
+	HeadlessVirtualDisplay HeadlessMode = "xvfb"
+
+	// HeadlessNew uses Chrome's new headless mode ('--headless=new'), which
+	// does not need a display server and works on all platforms.
+	//
+	// Every tab is patched to look like a regular Chrome: the user agent no
+	// longer contains "HeadlessChrome", the screen metrics match the window
+	// size, and the EvasionPlugins and EvasionOuterDimensions evasions are
+	// always applied.
+	HeadlessNew HeadlessMode = "new"
+)
+
+// Default window and screen size used in headless modes.
+var (
+	DefaultWindowWidth  = 1920
+	DefaultWindowHeight = 1080
+)
+
+// Errors.
+var (
+	ErrUnknownHeadlessMode = errors.New("unknown headless mode")
+)
+
+// headlessMode returns the effective headless mode of the config.
+func (c Config) headlessMode() HeadlessMode {
+	switch {
+	case c.HeadlessMode != "":
+		return c.HeadlessMode
+	case c.Headless:
+		return HeadlessVirtualDisplay
+	default:
+		return HeadlessOff
+	}
+}
+
+func headlessFlag(config Config) ([]chromedp.ExecAllocatorOption, func() error, error) {
+	var opts []chromedp.ExecAllocatorOption
+
+	cleanup := func() error { return nil }
+
+	window := config.windowSize()
+
+	switch mode := config.headlessMode(); mode {
+	case HeadlessOff:
+	case HeadlessVirtualDisplay:
+		var (
+			optx []chromedp.ExecAllocatorOption
+			err  error
+		)
+
+		screen := config.screen()
+
+		optx, cleanup, err = headlessOpts(fmt.Sprintf("%dx%dx24", screen.Width, screen.Height))
+		if err != nil {
+			return nil, cleanup, err
+		}
+
+		opts = append(opts,
+			chromedp.WindowSize(window.Width, window.Height),
+			chromedp.Flag("start-maximized", true),
+			chromedp.Flag("no-sandbox", true),
+		)
+		opts = append(opts, optx...)
+	case HeadlessNew:
+		opts = append(opts,
+			chromedp.Flag("headless", "new"),
+			chromedp.WindowSize(window.Width, window.Height),
+			chromedp.Flag("hide-scrollbars", true),
+			chromedp.Flag("mute-audio", true),
+		)
+	default:
+		return nil, cleanup, fmt.Errorf("%w: %q", ErrUnknownHeadlessMode, mode)
+	}
+
+	return opts, cleanup, nil
+}
+
+// headlessFixes returns the per tab actions needed to make the configured
+// headless mode look like a regular Chrome.
+func headlessFixes(config Config, langs []weightedLanguage) []chromedp.Action {
+	if config.headlessMode() != HeadlessNew {
+		return nil
+	}
+
+	// A persona reports its own user agent and screen.
+	if config.Persona != nil {
+		return nil
+	}
+
+	return []chromedp.Action{
+		AutoUserAgentOverride(WithUAAcceptLanguage(acceptLanguage(langs))),
+		injectScript(screenMetricsScript(config.screen())),
+	}
+}
@@ -0,0 +1,62 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeadlessMode(t *testing.T) {
+	require.Equal(t, HeadlessOff, NewConfig().headlessMode())
+	require.Equal(t, HeadlessVirtualDisplay, NewConfig(WithHeadless()).headlessMode())
+	require.Equal(t, HeadlessNew, NewConfig(WithHeadless(), WithHeadlessMode(HeadlessNew)).headlessMode())
+
+	_, _, err := headlessFlag(NewConfig(WithHeadlessMode("bogus")))
+	require.ErrorIs(t, err, ErrUnknownHeadlessMode)
+
+	ua := "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/116.0.0.0 Safari/537.36"
+	require.NotContains(t, stripHeadless(ua), "Headless")
+}
+
+func TestHeadlessNew(t *testing.T) {
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(20*time.Second),
+			WithHeadlessMode(HeadlessNew),
+		),
+		func(ctx context.Context) error {
+			var res struct {
+				UserAgent   string `json:"userAgent"`
+				ScreenWidth int    `json:"screenWidth"`
+				Plugins     int    `json:"plugins"`
+			}
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate("https://www.example.com/"),
+				chromedp.Evaluate(`({
+					userAgent: navigator.userAgent,
+					screenWidth: screen.width,
+					plugins: navigator.plugins.length,
+				})`, &res),
+			); err != nil {
+				return err
+			}
+
+			if strings.Contains(res.UserAgent, "Headless") {
+				return fmt.Errorf("user agent contains headless marker: %s", res.UserAgent)
+			}
+
+			if res.ScreenWidth != DefaultWindowWidth || res.Plugins == 0 {
+				return fmt.Errorf("unexpected metrics: %+v", res)
+			}
+
+			return nil
+		},
+	)
+}
@@ -0,0 +1,590 @@
+package chromedpundetected
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// Errors.
+var (
+	ErrInvalidRecording = errors.New("invalid input recording")
+)
+
+// InputEventType is the type of a recorded input event.
+type InputEventType string
+
+// Input event types, as written in recording files.
+const (
+	InputMouseMove InputEventType = "m"
+	InputMouseDown InputEventType = "d"
+	InputMouseUp   InputEventType = "u"
+	InputWheel     InputEventType = "w"
+	InputKeyDown   InputEventType = "k"
+	InputKeyUp     InputEventType = "K"
+)
+
+// InputEvent is a recorded input event. Positions are in CSS pixels, relative
+// to the viewport.
+type InputEvent struct {
+	// Time is the time since the first event of the recording.
+	Time time.Duration
+	Type InputEventType
+
+	// X and Y are the cursor position of mouse and wheel events.
+	X float64
+	Y float64
+
+	// Button is the button pressed or released by mouse down and up events.
+	Button input.MouseButton
+
+	// Buttons is the bitmask of the buttons held during a mouse move.
+	Buttons int64
+
+	// DeltaX and DeltaY are the scroll distance of wheel events in pixels.
+	DeltaX float64
+	DeltaY float64
+
+	// Code is the physical key of key events, e.g. KeyA. The key values are
+	// not recorded, so recordings do not contain the typed text.
+	Code string
+}
+
+// Recording is a sequence of recorded input events.
+type Recording struct {
+	Events []InputEvent
+}
+
+// recordingHeader is the first line of a recording file.
+const recordingHeader = "# chromedp-undetected input v1"
+
+// Write writes the recording in a compact text format, one event per line:
+// the milliseconds since the previous event, the event type and its values,
+// e.g. "16 m 412 300" for a mouse move.
+func (r *Recording) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, recordingHeader)
+
+	var last int64
+
+	for _, e := range r.Events {
+		// Deltas between rounded times, so rounding errors do not add up.
+		t := e.Time.Round(time.Millisecond).Milliseconds()
+		fields := []string{strconv.FormatInt(t-last, 10), string(e.Type)}
+		last = t
+
+		switch e.Type {
+		case InputMouseMove:
+			fields = append(fields, formatCoord(e.X), formatCoord(e.Y))
+			if e.Buttons != 0 {
+				fields = append(fields, strconv.FormatInt(e.Buttons, 10))
+			}
+		case InputMouseDown, InputMouseUp:
+			fields = append(fields, formatCoord(e.X), formatCoord(e.Y), string(e.Button))
+		case InputWheel:
+			fields = append(fields, formatCoord(e.X), formatCoord(e.Y), formatCoord(e.DeltaX), formatCoord(e.DeltaY))
+		case InputKeyDown, InputKeyUp:
+			fields = append(fields, e.Code)
+		default:
+			return fmt.Errorf("%w: event type %q", ErrInvalidRecording, e.Type)
+		}
+
+		fmt.Fprintln(bw, strings.Join(fields, " "))
+	}
+
+	return bw.Flush()
+}
+
+// WriteFile writes the recording to the file, see Write.
+func (r *Recording) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := r.Write(f); err != nil {
+		f.Close() //nolint:errcheck,gosec
+		return err
+	}
+
+	return f.Close()
+}
+
+// formatCoord formats a position or distance to a tenth of a pixel.
+func formatCoord(v float64) string {
+	return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64)
+}
+
+// ReadRecording reads a recording written by Recording.Write.
+func ReadRecording(r io.Reader) (*Recording, error) {
+	scanner := bufio.NewScanner(r)
+
+	if !scanner.Scan() || scanner.Text() != recordingHeader {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidRecording)
+	}
+
+	rec := &Recording{}
+	line := 1
+
+	var t time.Duration
+
+	for scanner.Scan() {
+		line++
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		e, err := parseInputEvent(fields)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidRecording, line, err) //nolint:errorlint
+		}
+
+		t += e.Time
+		e.Time = t
+
+		rec.Events = append(rec.Events, e)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// ReadRecordingFile reads a recording from the file, see ReadRecording.
+func ReadRecordingFile(path string) (*Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadRecording(f)
+}
+
+// parseInputEvent parses the fields of a recording line. The time of the
+// event is the delta to the previous one.
+func parseInputEvent(fields []string) (InputEvent, error) {
+	if len(fields) < 2 {
+		return InputEvent{}, errors.New("missing fields")
+	}
+
+	dt, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil || dt < 0 {
+		return InputEvent{}, fmt.Errorf("time %q", fields[0])
+	}
+
+	e := InputEvent{Time: time.Duration(dt) * time.Millisecond, Type: InputEventType(fields[1])}
+	values := fields[2:]
+
+	// floats parses the values starting at the index into the targets.
+	floats := func(from int, targets ...*float64) error {
+		for i, target := range targets {
+			v, err := strconv.ParseFloat(values[from+i], 64)
+			if err != nil {
+				return fmt.Errorf("value %q", values[from+i])
+			}
+
+			*target = v
+		}
+
+		return nil
+	}
+
+	switch e.Type {
+	case InputMouseMove:
+		if len(values) != 2 && len(values) != 3 {
+			return e, errors.New("mouse move needs a position")
+		}
+
+		if len(values) == 3 {
+			if e.Buttons, err = strconv.ParseInt(values[2], 10, 64); err != nil {
+				return e, fmt.Errorf("buttons %q", values[2])
+			}
+		}
+
+		return e, floats(0, &e.X, &e.Y)
+	case InputMouseDown, InputMouseUp:
+		if len(values) != 3 {
+			return e, errors.New("mouse button event needs a position and a button")
+		}
+
+		e.Button = input.MouseButton(values[2])
+
+		return e, floats(0, &e.X, &e.Y)
+	case InputWheel:
+		if len(values) != 4 {
+			return e, errors.New("wheel event needs a position and a distance")
+		}
+
+		return e, floats(0, &e.X, &e.Y, &e.DeltaX, &e.DeltaY)
+	case InputKeyDown, InputKeyUp:
+		if len(values) != 1 {
+			return e, errors.New("key event needs a code")
+		}
+
+		e.Code = values[0]
+
+		return e, nil
+	default:
+		return e, fmt.Errorf("event type %q", e.Type)
+	}
+}
+
+// Defaults used to split recordings into trajectories.
+const (
+	// trajectoryPause is the pause between mouse moves that ends a movement.
+	trajectoryPause = 250 * time.Millisecond
+
+	// minTrajectoryPoints and minTrajectoryDistance are the minimum number
+	// of points and the minimum distance between the ends of a movement.
+	minTrajectoryPoints   = 5
+	minTrajectoryDistance = 20
+)
+
+// Trajectories splits the mouse moves of the recording into single
+// movements. A movement ends on a pause, or on any other event, e.g. the
+// click it led to. The first point of a trajectory is where it starts, with
+// no delay, the delays of the others are the time since the previous point.
+func (r *Recording) Trajectories() [][]PathPoint {
+	var (
+		trajectories [][]PathPoint
+		current      []PathPoint
+		last         time.Duration
+	)
+
+	end := func() {
+		if len(current) >= minTrajectoryPoints {
+			a, b := current[0].Point, current[len(current)-1].Point
+			if math.Hypot(b.X-a.X, b.Y-a.Y) >= minTrajectoryDistance {
+				trajectories = append(trajectories, current)
+			}
+		}
+
+		current = nil
+	}
+
+	for _, e := range r.Events {
+		if e.Type != InputMouseMove {
+			end()
+			continue
+		}
+
+		p := PathPoint{Point: Point{X: e.X, Y: e.Y}}
+
+		switch {
+		case len(current) == 0:
+		case e.Time-last > trajectoryPause:
+			end()
+		default:
+			p.Delay = e.Time - last
+		}
+
+		current = append(current, p)
+		last = e.Time
+	}
+
+	end()
+
+	return trajectories
+}
+
+// KeyTimings returns the recorded key hold times, from pressing to releasing
+// a key, and the flight times between pressing consecutive keys. Flight times
+// longer than a second are left out as pauses.
+func (r *Recording) KeyTimings() (holds, flights []time.Duration) {
+	down := map[string]time.Duration{}
+
+	var (
+		last    time.Duration
+		pressed bool
+	)
+
+	for _, e := range r.Events {
+		switch e.Type {
+		case InputKeyDown:
+			if pressed && e.Time-last <= time.Second {
+				flights = append(flights, e.Time-last)
+			}
+
+			down[e.Code] = e.Time
+			last, pressed = e.Time, true
+		case InputKeyUp:
+			if t, ok := down[e.Code]; ok {
+				holds = append(holds, e.Time-t)
+				delete(down, e.Code)
+			}
+		default:
+		}
+	}
+
+	return holds, flights
+}
+
+// inputRecordBinding is the name of the binding the recorder script reports
+// events through. It is only exposed to the isolated world.
+const inputRecordBinding = "cdpuInputRecord"
+
+// inputRecorderJS listens to trusted input events in the top frame and reports
+// them to the binding in batches. The listeners run in the isolated world, so
+// the page can not see them.
+const inputRecorderJS = `(() => {
+	if (window.top !== window || window.__cdpuInputRecorder) {
+		return;
+	}
+
+	const queue = [];
+	let timer = null;
+
+	const flush = () => {
+		timer = null;
+		const send = globalThis.` + inputRecordBinding + `;
+		if (queue.length === 0 || typeof send !== 'function') {
+			return;
+		}
+		send(JSON.stringify(queue.splice(0)));
+	};
+
+	const push = (e, ev) => {
+		if (!e.isTrusted) {
+			return;
+		}
+		ev.t = performance.timeOrigin + e.timeStamp;
+		queue.push(ev);
+		if (timer === null) {
+			timer = setTimeout(flush, 100);
+		}
+	};
+
+	const scale = (e) => e.deltaMode === 1 ? 40 : e.deltaMode === 2 ? window.innerHeight : 1;
+	const options = { capture: true, passive: true };
+
+	window.addEventListener('mousemove', (e) => push(e, { k: 'm', x: e.clientX, y: e.clientY, b: e.buttons }), options);
+	window.addEventListener('mousedown', (e) => push(e, { k: 'd', x: e.clientX, y: e.clientY, b: e.button }), options);
+	window.addEventListener('mouseup', (e) => push(e, { k: 'u', x: e.clientX, y: e.clientY, b: e.button }), options);
+	window.addEventListener('wheel', (e) => push(e, { k: 'w', x: e.clientX, y: e.clientY, dx: e.deltaX * scale(e), dy: e.deltaY * scale(e) }), options);
+	window.addEventListener('keydown', (e) => e.repeat || push(e, { k: 'k', c: e.code }), options);
+	window.addEventListener('keyup', (e) => push(e, { k: 'K', c: e.code }), options);
+	window.addEventListener('pagehide', flush, options);
+
+	window.__cdpuInputRecorder = { flush };
+})();`
+
+// flushInputRecorderJS reports the queued events right away.
+const flushInputRecorderJS = `window.__cdpuInputRecorder && window.__cdpuInputRecorder.flush()`
+
+// recordedInput is an event as reported by the recorder script.
+type recordedInput struct {
+	T  float64 `json:"t"`
+	K  string  `json:"k"`
+	X  float64 `json:"x"`
+	Y  float64 `json:"y"`
+	B  int64   `json:"b"`
+	DX float64 `json:"dx"`
+	DY float64 `json:"dy"`
+	C  string  `json:"c"`
+}
+
+// recordedButtons are the mouse buttons by their MouseEvent.button number.
+var recordedButtons = []input.MouseButton{input.Left, input.Middle, input.Right, input.Back, input.Forward} //nolint:gochecknoglobals
+
+// event converts the reported event, with the time in milliseconds since the
+// first event.
+func (e recordedInput) event(first float64) InputEvent {
+	ev := InputEvent{
+		Time: time.Duration((e.T - first) * float64(time.Millisecond)),
+		Type: InputEventType(e.K),
+		X:    e.X,
+		Y:    e.Y,
+		Code: e.C,
+	}
+
+	switch ev.Type {
+	case InputMouseMove:
+		ev.Buttons = e.B
+	case InputMouseDown, InputMouseUp:
+		ev.Button = input.None
+		if e.B >= 0 && int(e.B) < len(recordedButtons) {
+			ev.Button = recordedButtons[e.B]
+		}
+	case InputWheel:
+		ev.DeltaX, ev.DeltaY = e.DX, e.DY
+	default:
+	}
+
+	return ev
+}
+
+// InputRecorder records the mouse moves, clicks, scrolls and key presses of a
+// person using a headful browser, e.g. to move the mouse along real human
+// trajectories with RecordedPath.
+//
+// The events are captured by listeners in the isolated world of the top
+// frame, which the page can not detect, and keep being captured across
+// navigations until the recorder is stopped:
+//
+//	rec := NewInputRecorder()
+//	err := chromedp.Run(ctx, chromedp.Navigate(url), rec.Start(), chromedp.Sleep(time.Minute), rec.Stop())
+//	err = rec.Recording().WriteFile("input.rec")
+type InputRecorder struct {
+	mu       sync.Mutex
+	events   []recordedInput
+	stop     func(context.Context) error
+	starting bool
+}
+
+// NewInputRecorder creates a new input recorder.
+func NewInputRecorder() *InputRecorder {
+	return &InputRecorder{}
+}
+
+// Start starts recording the input of the current tab.
+func (r *InputRecorder) Start() chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		r.mu.Lock()
+		if r.stop != nil || r.starting {
+			r.mu.Unlock()
+			return ErrRecorderRunning
+		}
+		r.starting = true
+		r.mu.Unlock()
+
+		// Listeners of an earlier recording in the same document may report
+		// events while starting, and addEvents locks the mutex on the event
+		// loop, so it must not be held here.
+		stop, err := startInputRecording(ctx, r.addEvents)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.starting = false
+		if err != nil {
+			return err
+		}
+
+		r.stop = stop
+
+		return nil
+	}
+}
+
+// startInputRecording injects the input recorder, and calls onEvents with the
+// batches of events it reports until the returned function stops it.
+func startInputRecording(ctx context.Context, onEvents func(payload string)) (func(context.Context) error, error) {
+	listenCtx, cancel := context.WithCancel(ctx)
+
+	chromedp.ListenTarget(listenCtx, func(ev interface{}) {
+		if e, ok := ev.(*runtime.EventBindingCalled); ok && e.Name == inputRecordBinding {
+			onEvents(e.Payload)
+		}
+	})
+
+	if err := runtime.AddBinding(inputRecordBinding).WithExecutionContextName(IsolatedWorldName).Do(ctx); err != nil {
+		cancel()
+		return nil, fmt.Errorf("add binding: %w", err)
+	}
+
+	id, err := page.AddScriptToEvaluateOnNewDocument(inputRecorderJS).
+		WithWorldName(IsolatedWorldName).
+		WithRunImmediately(true).
+		Do(ctx)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("inject input recorder: %w", err)
+	}
+
+	stop := func(ctx context.Context) error {
+		defer cancel()
+
+		// Collect what the script has not reported yet.
+		if err := EvaluateIsolated(flushInputRecorderJS, nil).Do(ctx); err != nil {
+			return fmt.Errorf("flush input recorder: %w", err)
+		}
+
+		if err := page.RemoveScriptToEvaluateOnNewDocument(id).Do(ctx); err != nil {
+			return err
+		}
+
+		return runtime.RemoveBinding(inputRecordBinding).Do(ctx)
+	}
+
+	return stop, nil
+}
+
+// Stop stops recording. The recorded events are kept, a subsequent Start
+// appends to them.
+func (r *InputRecorder) Stop() chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		r.mu.Lock()
+		stop := r.stop
+		r.stop = nil
+		r.mu.Unlock()
+
+		if stop == nil {
+			return ErrRecorderNotRunning
+		}
+
+		return stop(ctx)
+	}
+}
+
+func (r *InputRecorder) addEvents(payload string) {
+	var batch []recordedInput
+	if err := json.Unmarshal([]byte(payload), &batch); err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, batch...)
+}
+
+// Recording returns the events recorded so far.
+func (r *InputRecorder) Recording() *Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec := &Recording{}
+	if len(r.events) == 0 {
+		return rec
+	}
+
+	first := r.events[0].T
+
+	for _, e := range r.events {
+		rec.Events = append(rec.Events, e.event(first))
+	}
+
+	return rec
+}
+
+// Reset drops all recorded events.
+func (r *InputRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = nil
+}
@@ -0,0 +1,166 @@
+package chromedpundetected
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+func testRecording() *Recording {
+	ms := time.Millisecond
+
+	return &Recording{Events: []InputEvent{
+		{Time: 0, Type: InputMouseMove, X: 10, Y: 10},
+		{Time: 16 * ms, Type: InputMouseMove, X: 30.25, Y: 12},
+		{Time: 33 * ms, Type: InputMouseMove, X: 60, Y: 15},
+		{Time: 49 * ms, Type: InputMouseMove, X: 90, Y: 17},
+		{Time: 66 * ms, Type: InputMouseMove, X: 100, Y: 18},
+		{Time: 150 * ms, Type: InputMouseDown, X: 100, Y: 18, Button: input.Left},
+		{Time: 166 * ms, Type: InputMouseMove, X: 120, Y: 18, Buttons: 1},
+		{Time: 240 * ms, Type: InputMouseUp, X: 120, Y: 18, Button: input.Left},
+		{Time: 400 * ms, Type: InputWheel, X: 120, Y: 18, DeltaY: 100},
+		{Time: 500 * ms, Type: InputKeyDown, Code: "KeyA"},
+		{Time: 580 * ms, Type: InputKeyUp, Code: "KeyA"},
+		{Time: 620 * ms, Type: InputKeyDown, Code: "KeyB"},
+		{Time: 700 * ms, Type: InputKeyUp, Code: "KeyB"},
+	}}
+}
+
+func TestRecordingFormat(t *testing.T) {
+	rec := testRecording()
+
+	var buf bytes.Buffer
+	require.NoError(t, rec.Write(&buf))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Equal(t, recordingHeader, lines[0])
+	require.Equal(t, "16 m 30.3 12", lines[2])
+	require.Equal(t, "84 d 100 18 left", lines[6])
+	require.Equal(t, "16 m 120 18 1", lines[7])
+	require.Equal(t, "100 k KeyA", lines[10])
+
+	read, err := ReadRecording(&buf)
+	require.NoError(t, err)
+	require.Len(t, read.Events, len(rec.Events))
+
+	for i, e := range rec.Events {
+		require.InDelta(t, e.X, read.Events[i].X, 0.06)
+
+		read.Events[i].X = e.X
+		require.Equal(t, e, read.Events[i])
+	}
+
+	path := filepath.Join(t.TempDir(), "input.rec")
+	require.NoError(t, rec.WriteFile(path))
+
+	read, err = ReadRecordingFile(path)
+	require.NoError(t, err)
+	require.Len(t, read.Events, len(rec.Events))
+
+	for _, data := range []string{
+		"",
+		"16 m 1 2\n",
+		recordingHeader + "\n16 x 1 2\n",
+		recordingHeader + "\n-1 m 1 2\n",
+		recordingHeader + "\n16 d 1 2\n",
+		recordingHeader + "\n16 w 1 2 a 4\n",
+	} {
+		_, err := ReadRecording(strings.NewReader(data))
+		require.True(t, errors.Is(err, ErrInvalidRecording), "%q: %v", data, err)
+	}
+}
+
+func TestTrajectories(t *testing.T) {
+	trajectories := testRecording().Trajectories()
+
+	// The moves before the click form a movement, the move while holding
+	// the button is too short.
+	require.Len(t, trajectories, 1)
+	require.Len(t, trajectories[0], 5)
+	require.Equal(t, Point{X: 10, Y: 10}, trajectories[0][0].Point)
+	require.Zero(t, trajectories[0][0].Delay)
+	require.Equal(t, 17*time.Millisecond, trajectories[0][2].Delay)
+
+	// A pause splits movements.
+	var rec Recording
+	for i := 0; i < 10; i++ {
+		d := time.Duration(i) * 20 * time.Millisecond
+		if i >= 5 {
+			d += time.Second
+		}
+
+		rec.Events = append(rec.Events, InputEvent{Time: d, Type: InputMouseMove, X: float64(i * 10), Y: 0})
+	}
+
+	require.Len(t, rec.Trajectories(), 2)
+}
+
+func TestKeyTimings(t *testing.T) {
+	holds, flights := testRecording().KeyTimings()
+	require.Equal(t, []time.Duration{80 * time.Millisecond, 80 * time.Millisecond}, holds)
+	require.Equal(t, []time.Duration{120 * time.Millisecond}, flights)
+}
+
+func TestInputRecorder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body style="height: 3000px"><input id="field"></body></html>`)
+	}))
+	defer srv.Close()
+
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(60*time.Second),
+			WithHeadless(),
+		),
+		func(ctx context.Context) error {
+			rec := NewInputRecorder()
+
+			var hooked bool
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate(srv.URL),
+				rec.Start(),
+				MoveMouseToPosition(300, 200),
+				Click(`#field`, WithClickQueryOptions(chromedp.ByQuery)),
+				Type("hi"),
+				Scroll(0, 200),
+				rec.Stop(),
+				chromedp.Evaluate(`'__cdpuInputRecorder' in window || 'cdpuInputRecord' in window`, &hooked),
+			); err != nil {
+				return err
+			}
+
+			if hooked {
+				return errors.New("the recorder is visible to the page")
+			}
+
+			counts := map[InputEventType]int{}
+			for _, e := range rec.Recording().Events {
+				counts[e.Type]++
+			}
+
+			if counts[InputMouseMove] < 10 || counts[InputMouseDown] != 1 || counts[InputMouseUp] != 1 ||
+				counts[InputKeyDown] != 2 || counts[InputKeyUp] != 2 || counts[InputWheel] == 0 {
+				return fmt.Errorf("recorded %v", counts)
+			}
+
+			if len(rec.Recording().Trajectories()) == 0 {
+				return errors.New("no trajectories recorded")
+			}
+
+			return nil
+		},
+	)
+}
@@ -0,0 +1,178 @@
+package chromedpundetected
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// IsolatedWorldName is the name of the isolated world the library scripts run
+// in. An isolated world shares the DOM with the page, but has its own
+// JavaScript globals, so page scripts can not see the variables, functions
+// and event listeners defined in it.
+const IsolatedWorldName = "chromedp-undetected"
+
+// EvaluateIsolated evaluates the expression in the isolated world of the main
+// frame, and unmarshals the result into res, like chromedp.Evaluate does for
+// the main world. Use it for scripts the page should not be able to detect.
+//
+// The world is created on first use in a document and re-created after a
+// navigation, so state defined in it lasts until the page navigates.
+func EvaluateIsolated(expression string, res any, opts ...chromedp.EvaluateOption) chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		tree, err := page.GetFrameTree().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("get frame tree: %w", err)
+		}
+
+		return EvaluateIsolatedInFrame(tree.Frame.ID, expression, res, opts...).Do(ctx)
+	}
+}
+
+// EvaluateIsolatedInFrame evaluates the expression in the isolated world of
+// the frame, e.g. of an iframe node's FrameID. See EvaluateIsolated.
+func EvaluateIsolatedInFrame(frameID cdp.FrameID, expression string, res any, opts ...chromedp.EvaluateOption) chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		// Chrome returns the existing world of the frame if it has one.
+		id, err := page.CreateIsolatedWorld(frameID).WithWorldName(IsolatedWorldName).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("create isolated world: %w", err)
+		}
+
+		params := runtime.Evaluate(expression).WithContextID(id)
+		if _, ok := res.(**runtime.RemoteObject); !ok {
+			params = params.WithReturnByValue(true)
+		}
+
+		for _, opt := range opts {
+			params = opt(params)
+		}
+
+		v, exp, err := params.Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		if exp != nil {
+			return exp
+		}
+
+		return parseRemoteObject(v, res)
+	}
+}
+
+// callIsolatedOn calls the function declaration with the node as this, in the
+// isolated world of the main frame, and unmarshals the result into res. A
+// **runtime.RemoteObject res gets the result by reference, it has to be
+// released by the caller.
+func callIsolatedOn(ctx context.Context, nodeID cdp.NodeID, function string, res any) error {
+	tree, err := page.GetFrameTree().Do(ctx)
+	if err != nil {
+		return fmt.Errorf("get frame tree: %w", err)
+	}
+
+	id, err := page.CreateIsolatedWorld(tree.Frame.ID).WithWorldName(IsolatedWorldName).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("create isolated world: %w", err)
+	}
+
+	obj, err := dom.ResolveNode().WithNodeID(nodeID).WithExecutionContextID(id).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("resolve node: %w", err)
+	}
+
+	defer runtime.ReleaseObject(obj.ObjectID).Do(ctx) //nolint:errcheck
+
+	params := runtime.CallFunctionOn(function).WithObjectID(obj.ObjectID)
+	if _, ok := res.(**runtime.RemoteObject); !ok {
+		params = params.WithReturnByValue(true)
+	}
+
+	v, exp, err := params.Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	if exp != nil {
+		return exp
+	}
+
+	return parseRemoteObject(v, res)
+}
+
+// isolatedNodeOn calls the function declaration with the node as this, like
+// callIsolatedOn, and returns the ID of the node it returns, or zero if it
+// returns null.
+func isolatedNodeOn(ctx context.Context, nodeID cdp.NodeID, function string) (cdp.NodeID, error) {
+	var obj *runtime.RemoteObject
+	if err := callIsolatedOn(ctx, nodeID, function, &obj); err != nil {
+		return 0, err
+	}
+
+	if obj.ObjectID == "" {
+		return 0, nil
+	}
+
+	defer runtime.ReleaseObject(obj.ObjectID).Do(ctx) //nolint:errcheck
+
+	id, err := dom.RequestNode(obj.ObjectID).Do(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("request node: %w", err)
+	}
+
+	return id, nil
+}
+
+// parseRemoteObject stores the evaluation result in res, the same way
+// chromedp.Evaluate does.
+func parseRemoteObject(v *runtime.RemoteObject, res any) error {
+	switch r := res.(type) {
+	case nil:
+		return nil
+	case **runtime.RemoteObject:
+		*r = v
+		return nil
+	case *[]byte:
+		*r = v.Value
+		return nil
+	}
+
+	value := v.Value
+	if value == nil {
+		if rv := reflect.ValueOf(res); rv.Kind() == reflect.Ptr {
+			switch rv.Elem().Kind() {
+			case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func, reflect.Interface:
+			default:
+				if v.Type == runtime.TypeUndefined {
+					return chromedp.ErrJSUndefined
+				}
+
+				return chromedp.ErrJSNull
+			}
+		}
+
+		value = []byte("null")
+	}
+
+	return json.Unmarshal(value, res)
+}
+
+// InjectIsolated evaluates the script in the isolated world of every frame of
+// the current tab, both in the current documents and every new document.
+func InjectIsolated(script string) chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		_, err := page.AddScriptToEvaluateOnNewDocument(script).
+			WithWorldName(IsolatedWorldName).
+			WithRunImmediately(true).
+			Do(ctx)
+
+		return err
+	}
+}
@@ -0,0 +1,94 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRemoteObject(t *testing.T) {
+	var n int
+	require.NoError(t, parseRemoteObject(&runtime.RemoteObject{Type: runtime.TypeNumber, Value: []byte("42")}, &n))
+	require.Equal(t, 42, n)
+
+	require.ErrorIs(t, parseRemoteObject(&runtime.RemoteObject{Type: runtime.TypeUndefined}, &n), chromedp.ErrJSUndefined)
+
+	var m map[string]any
+	require.NoError(t, parseRemoteObject(&runtime.RemoteObject{Type: runtime.TypeUndefined}, &m))
+	require.Nil(t, m)
+
+	var raw []byte
+	require.NoError(t, parseRemoteObject(&runtime.RemoteObject{Type: runtime.TypeString, Value: []byte(`"a"`)}, &raw))
+	require.Equal(t, []byte(`"a"`), raw)
+}
+
+func TestIsolatedWorld(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/frame" {
+			fmt.Fprint(w, "<html><body>frame</body></html>")
+			return
+		}
+
+		fmt.Fprint(w, `<html><body>main<iframe src="/frame"></iframe></body></html>`)
+	}))
+	defer srv.Close()
+
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(20*time.Second),
+			WithHeadless(),
+		),
+		func(ctx context.Context) error {
+			var (
+				isolated, main, frame string
+				reloaded              string
+			)
+
+			if err := chromedp.Run(ctx,
+				InjectIsolated(`window.injected = 'yes'`),
+				chromedp.Navigate(srv.URL),
+				EvaluateIsolated(`window.secret = 'hidden'`, nil),
+				EvaluateIsolated(`window.secret + ',' + window.injected`, &isolated),
+				chromedp.Evaluate(`typeof window.secret + ',' + typeof window.injected`, &main),
+				chromedp.ActionFunc(func(ctx context.Context) error {
+					tree, err := page.GetFrameTree().Do(ctx)
+					if err != nil {
+						return err
+					}
+
+					if len(tree.ChildFrames) != 1 {
+						return fmt.Errorf("expected 1 child frame, got %d", len(tree.ChildFrames))
+					}
+
+					return EvaluateIsolatedInFrame(tree.ChildFrames[0].Frame.ID, `document.body.textContent + ',' + window.injected`, &frame).Do(ctx)
+				}),
+				chromedp.Reload(),
+				EvaluateIsolated(`typeof window.secret + ',' + window.injected`, &reloaded),
+			); err != nil {
+				return err
+			}
+
+			for _, c := range [][2]string{
+				{isolated, "hidden,yes"},
+				{main, "undefined,undefined"},
+				{frame, "frame,yes"},
+				{reloaded, "undefined,yes"},
+			} {
+				if c[0] != c[1] {
+					return fmt.Errorf("got %q, want %q", c[0], c[1])
+				}
+			}
+
+			return nil
+		},
+	)
+}
@@ -0,0 +1,788 @@
+package chromedpundetected
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+)
+
+// Errors.
+var (
+	ErrUnknownKey = errors.New("unknown key")
+)
+
+// keyLevel is the modifier state a character is typed in.
+type keyLevel int
+
+const (
+	levelBase keyLevel = iota
+	levelShift
+	levelAltGr
+)
+
+// layoutKey is a physical key, pressed in a level, that produces a character.
+type layoutKey struct {
+	code    string
+	keyCode int64
+	level   keyLevel
+
+	// base is the character of the key without modifiers.
+	base rune
+}
+
+// KeyboardLayout maps characters to the physical keys, and the modifiers, a
+// real keyboard with the layout types them with. Characters with accents the
+// layout has no key for are typed with dead keys.
+type KeyboardLayout struct {
+	name    string
+	keys    map[rune]layoutKey
+	dead    map[rune]layoutKey
+	compose map[rune][2]layoutKey
+}
+
+// Keyboard layouts.
+var (
+	LayoutUS = newLayout("us", false, [4]string{ //nolint:gochecknoglobals
+		"`~ 1! 2@ 3# 4$ 5% 6^ 7& 8* 9( 0) -_ =+",
+		"qQ wW eE rR tT yY uU iI oO pP [{ ]} \\|",
+		"aA sS dD fF gG hH jJ kK lL ;: '\"",
+		"zZ xX cC vV bB nN mM ,< .> /?",
+	}, "")
+
+	LayoutUK = newLayout("uk", true, [4]string{ //nolint:gochecknoglobals
+		"`¬¦ 1! 2\" 3£ 4$€ 5% 6^ 7& 8* 9( 0) -_ =+",
+		"qQ wW eEé rR tT yY uUú iIí oOó pP [{ ]}",
+		"aAá sS dD fF gG hH jJ kK lL ;: '@ #~",
+		"\\| zZ xX cC vV bB nN mM ,< .> /?",
+	}, "")
+
+	LayoutDE = newLayout("de", true, [4]string{ //nolint:gochecknoglobals
+		"^° 1! 2\"² 3§³ 4$ 5% 6& 7/{ 8([ 9)] 0=} ß?\\ ´`",
+		"qQ@ wW eE€ rR tT zZ uU iI oO pP üÜ +*~",
+		"aA sS dD fF gG hH jJ kK lL öÖ äÄ #'",
+		"<>| yY xX cC vV bB nN mMµ ,; .: -_",
+	}, "^´`")
+
+	LayoutFR = newLayout("fr", true, [4]string{ //nolint:gochecknoglobals
+		"² &1 é2~ \"3# '4{ (5[ -6| è7` _8\\ ç9^ à0@ )°] =+}",
+		"aA zZ eE€ rR tT yY uU iI oO pP ^¨ $£¤",
+		"qQ sS dD fF gG hH jJ kK lL mM ù% *µ",
+		"<> wW xX cC vV bB nN ,? ;. :/ !§",
+	}, "^¨~`")
+)
+
+// Name returns the name of the layout, e.g. "us".
+func (l *KeyboardLayout) Name() string {
+	return l.name
+}
+
+// Physical key codes per row, the ISO layouts have an extra key left of Z and
+// the Backslash key on the home row.
+var (
+	ansiRows = [4][]string{ //nolint:gochecknoglobals
+		{"Backquote", "Digit1", "Digit2", "Digit3", "Digit4", "Digit5", "Digit6", "Digit7", "Digit8", "Digit9", "Digit0", "Minus", "Equal"},
+		{"KeyQ", "KeyW", "KeyE", "KeyR", "KeyT", "KeyY", "KeyU", "KeyI", "KeyO", "KeyP", "BracketLeft", "BracketRight", "Backslash"},
+		{"KeyA", "KeyS", "KeyD", "KeyF", "KeyG", "KeyH", "KeyJ", "KeyK", "KeyL", "Semicolon", "Quote"},
+		{"KeyZ", "KeyX", "KeyC", "KeyV", "KeyB", "KeyN", "KeyM", "Comma", "Period", "Slash"},
+	}
+
+	isoRows = [4][]string{ //nolint:gochecknoglobals
+		ansiRows[0],
+		ansiRows[1][:12],
+		append(append([]string{}, ansiRows[2]...), "Backslash"),
+		append([]string{"IntlBackslash"}, ansiRows[3]...),
+	}
+
+	// rowOffsets are the horizontal offsets of the first letter key of each
+	// row, in key widths from the Backquote key.
+	rowOffsets = [4]float64{0, 1.5, 1.75, 2.25} //nolint:gochecknoglobals
+)
+
+// oemKeyCodes are the Windows virtual key codes of the punctuation keys.
+var oemKeyCodes = map[string]int64{ //nolint:gochecknoglobals
+	"Backquote":     192,
+	"Minus":         189,
+	"Equal":         187,
+	"BracketLeft":   219,
+	"BracketRight":  221,
+	"Backslash":     220,
+	"Semicolon":     186,
+	"Quote":         222,
+	"Comma":         188,
+	"Period":        190,
+	"Slash":         191,
+	"IntlBackslash": 226,
+	"Space":         32,
+}
+
+// punctuationKeyCodes are the Windows virtual key codes that belong to the
+// key typing the character, wherever the layout puts it, e.g. the comma on
+// the M key of AZERTY keyboards.
+var punctuationKeyCodes = map[rune]int64{ //nolint:gochecknoglobals
+	',': 188,
+	'.': 190,
+	'+': 187,
+	'-': 189,
+}
+
+// layoutKeyCode returns the Windows virtual key code a key with the characters
+// reports on a real keyboard with the layout.
+func layoutKeyCode(code string, chars []rune) int64 {
+	// Letter keys report the letter of the layout, e.g. A for the Q key on
+	// AZERTY keyboards.
+	if base := unicode.ToUpper(chars[0]); base >= 'A' && base <= 'Z' {
+		return int64(base)
+	}
+
+	// The digit row reports the digits, even where they need Shift.
+	if strings.HasPrefix(code, "Digit") {
+		return int64(code[len(code)-1])
+	}
+
+	// Keys typing one of the punctuation characters at the base or shift
+	// level report its code.
+	if len(chars) > 2 {
+		chars = chars[:2]
+	}
+
+	for _, r := range chars {
+		if keyCode, ok := punctuationKeyCodes[r]; ok {
+			return keyCode
+		}
+	}
+
+	if strings.HasPrefix(code, "Key") {
+		return int64(code[len(code)-1])
+	}
+
+	return oemKeyCodes[code]
+}
+
+// keyPosition is the position of a physical key, in key widths and rows.
+type keyPosition struct{ x, y float64 }
+
+// keyPositions are the positions of the physical keys.
+var keyPositions = func() map[string]keyPosition { //nolint:gochecknoglobals
+	positions := map[string]keyPosition{"IntlBackslash": {x: 1.25, y: 3}}
+
+	for y, row := range ansiRows {
+		for x, code := range row {
+			positions[code] = keyPosition{x: rowOffsets[y] + float64(x), y: float64(y)}
+		}
+	}
+
+	return positions
+}()
+
+// accents are the characters dead keys compose, by dead key.
+var accents = map[rune]string{ //nolint:gochecknoglobals
+	'´': "aáeéiíoóuúyýAÁEÉIÍOÓUÚYÝ",
+	'`': "aàeèiìoòuùAÀEÈIÌOÒUÙ",
+	'^': "aâeêiîoôuûAÂEÊIÎOÔUÛ",
+	'¨': "aäeëiïoöuüyÿAÄEËIÏOÖUÜ",
+	'~': "aãnñoõAÃNÑOÕ",
+}
+
+// newLayout builds a layout from rows of keys separated by spaces, each with
+// the characters of its base, shift and, optionally, AltGr level. The first
+// key producing one of the dead characters is a dead key.
+func newLayout(name string, iso bool, rows [4]string, dead string) *KeyboardLayout {
+	layout := &KeyboardLayout{
+		name:    name,
+		keys:    map[rune]layoutKey{' ': {code: "Space", keyCode: 32, base: ' '}},
+		dead:    map[rune]layoutKey{},
+		compose: map[rune][2]layoutKey{},
+	}
+
+	codes := ansiRows
+	if iso {
+		codes = isoRows
+	}
+
+	// Add the levels in order, so characters are typed the simplest way.
+	for level := levelBase; level <= levelAltGr; level++ {
+		for y, row := range rows {
+			for x, chars := range strings.Fields(row) {
+				runes := []rune(chars)
+				if int(level) >= len(runes) {
+					continue
+				}
+
+				r := runes[level]
+				key := layoutKey{code: codes[y][x], level: level, base: runes[0]}
+
+				key.keyCode = layoutKeyCode(key.code, runes)
+
+				if _, ok := layout.dead[r]; !ok && strings.ContainsRune(dead, r) {
+					layout.dead[r] = key
+					continue
+				}
+
+				if _, ok := layout.keys[r]; !ok {
+					layout.keys[r] = key
+				}
+			}
+		}
+	}
+
+	for accent, deadKey := range layout.dead {
+		pairs := []rune(accents[accent])
+
+		for i := 0; i+1 < len(pairs); i += 2 {
+			base, composed := pairs[i], pairs[i+1]
+
+			if _, ok := layout.keys[composed]; ok {
+				continue
+			}
+
+			if key, ok := layout.keys[base]; ok {
+				layout.compose[composed] = [2]layoutKey{deadKey, key}
+			}
+		}
+	}
+
+	return layout
+}
+
+// strokes returns the keys to press in order to type the character, the last
+// one producing it. Dead keys are followed by the key they modify, or by the
+// space bar to type the accent itself.
+func (l *KeyboardLayout) strokes(r rune) ([]layoutKey, bool) {
+	if key, ok := l.keys[r]; ok {
+		return []layoutKey{key}, true
+	}
+
+	if key, ok := l.dead[r]; ok {
+		return []layoutKey{key, l.keys[' ']}, true
+	}
+
+	if keys, ok := l.compose[r]; ok {
+		return keys[:], true
+	}
+
+	return nil, false
+}
+
+// position returns the position of the key of the character.
+func (l *KeyboardLayout) position(r rune) (keyPosition, bool) {
+	keys, ok := l.strokes(r)
+	if !ok {
+		return keyPosition{}, false
+	}
+
+	p, ok := keyPositions[keys[len(keys)-1].code]
+
+	return p, ok
+}
+
+// leftHand reports whether the character is typed with the left hand.
+func (l *KeyboardLayout) leftHand(r rune) bool {
+	p, ok := l.position(r)
+
+	// The keyboard rows are staggered, so the split between the hands leans.
+	return ok && p.x-0.3*p.y < 6
+}
+
+// adjacent returns the letters on the keys around the key of the letter.
+func (l *KeyboardLayout) adjacent(r rune) []rune {
+	at, ok := l.position(r)
+	if !ok || r == ' ' {
+		return nil
+	}
+
+	var neighbors []rune
+
+	for k, key := range l.keys {
+		p, ok := keyPositions[key.code]
+		if !ok || key.level != levelBase || !unicode.IsLetter(k) || k == r {
+			continue
+		}
+
+		if math.Hypot(p.x-at.x, p.y-at.y) < 1.3 {
+			neighbors = append(neighbors, k)
+		}
+	}
+
+	// Keep the order stable for seeded randomness.
+	sort.Slice(neighbors, func(i, j int) bool { return neighbors[i] < neighbors[j] })
+
+	return neighbors
+}
+
+// keyboard is the state of the keyboard while typing: the modifier level held
+// down and the text of an open IME composition.
+type keyboard struct {
+	layout      *KeyboardLayout
+	rnd         *rand.Rand
+	level       keyLevel
+	composition []rune
+}
+
+// altGraphKey is the AltGr key, Chrome has no modifier flag for it.
+var altGraphKey = input.DispatchKeyEventParams{ //nolint:gochecknoglobals
+	Key:                   "AltGraph",
+	Code:                  "AltRight",
+	WindowsVirtualKeyCode: 225,
+	NativeVirtualKeyCode:  225,
+	Location:              2,
+}
+
+// setLevel presses and releases Shift and AltGr to switch to the level.
+func (k *keyboard) setLevel(ctx context.Context, level keyLevel) error {
+	if k.level == level {
+		return nil
+	}
+
+	switch k.level {
+	case levelShift:
+		if err := releaseModifiers(ctx, []input.Modifier{input.ModifierShift}); err != nil {
+			return err
+		}
+	case levelAltGr:
+		up := altGraphKey
+		up.Type = input.KeyUp
+
+		if err := up.Do(ctx); err != nil {
+			return err
+		}
+	case levelBase:
+	}
+
+	k.level = levelBase
+
+	switch level {
+	case levelShift:
+		if _, err := pressModifiers(ctx, []input.Modifier{input.ModifierShift}); err != nil {
+			return err
+		}
+	case levelAltGr:
+		down := altGraphKey
+		down.Type = input.KeyRawDown
+
+		if err := down.Do(ctx); err != nil {
+			return err
+		}
+	case levelBase:
+	}
+
+	k.level = level
+
+	return nil
+}
+
+// modifiers returns the modifier flags of the held level.
+func (k *keyboard) modifiers() input.Modifier {
+	if k.level == levelShift {
+		return input.ModifierShift
+	}
+
+	return 0
+}
+
+// typeRune types the character of the keystroke, with the keys of the layout
+// if it has them, in an IME composition for CJK scripts, and by inserting it
+// otherwise, like an emoji picker does.
+func (k *keyboard) typeRune(ctx context.Context, stroke keystroke) error {
+	r := stroke.r
+	if r == '\n' {
+		r = '\r'
+	}
+
+	if isIMERune(r) {
+		return k.composeRune(ctx, r, stroke)
+	}
+
+	if err := k.commit(ctx); err != nil {
+		return err
+	}
+
+	if keys, ok := k.layout.strokes(r); ok {
+		for i, key := range keys {
+			if err := k.setLevel(ctx, key.level); err != nil {
+				return err
+			}
+
+			// Dead keys produce no text themselves.
+			value, text := "Dead", ""
+			if i == len(keys)-1 {
+				value, text = string(r), string(r)
+			}
+
+			hold := stroke.hold
+			if i < len(keys)-1 {
+				hold /= 2
+			}
+
+			if err := k.press(ctx, key, value, text, hold); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := k.setLevel(ctx, levelBase); err != nil {
+		return err
+	}
+
+	if key, ok := kb.Keys[r]; ok && key.Key != string(r) {
+		events := kb.Encode(r)
+
+		for _, ev := range events[:len(events)-1] {
+			if err := ev.Do(ctx); err != nil {
+				return err
+			}
+		}
+
+		if err := sleepContext(ctx, stroke.hold); err != nil {
+			return err
+		}
+
+		return events[len(events)-1].Do(ctx)
+	}
+
+	return input.InsertText(string(r)).Do(ctx)
+}
+
+// press presses and releases the physical key, typing the text if not empty.
+func (k *keyboard) press(ctx context.Context, key layoutKey, value, text string, hold time.Duration) error {
+	down := &input.DispatchKeyEventParams{
+		Type:                  input.KeyRawDown,
+		Key:                   value,
+		Code:                  key.code,
+		WindowsVirtualKeyCode: key.keyCode,
+		NativeVirtualKeyCode:  key.keyCode,
+		Modifiers:             k.modifiers(),
+	}
+
+	if text != "" {
+		down.Type = input.KeyDown
+		down.Text = text
+		down.UnmodifiedText = string(key.base)
+	}
+
+	if err := down.Do(ctx); err != nil {
+		return err
+	}
+
+	if err := sleepContext(ctx, hold); err != nil {
+		return err
+	}
+
+	up := *down
+	up.Type = input.KeyUp
+	up.Text = ""
+	up.UnmodifiedText = ""
+
+	return up.Do(ctx)
+}
+
+// processKey is the key IMEs report for the keys they handle.
+var processKey = input.DispatchKeyEventParams{ //nolint:gochecknoglobals
+	Key:                   "Process",
+	Code:                  "",
+	WindowsVirtualKeyCode: 229,
+	NativeVirtualKeyCode:  229,
+}
+
+// isIMERune reports whether the character is typed with an IME.
+func isIMERune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// composeRune adds the character to the IME composition, with a key press
+// handled by the IME.
+func (k *keyboard) composeRune(ctx context.Context, r rune, stroke keystroke) error {
+	if err := k.setLevel(ctx, levelBase); err != nil {
+		return err
+	}
+
+	k.composition = append(k.composition, r)
+
+	return k.process(ctx, stroke.hold, func(ctx context.Context) error {
+		n := int64(len(k.composition))
+		return input.ImeSetComposition(string(k.composition), n, n).Do(ctx)
+	})
+}
+
+// commit ends an open IME composition, as confirming the candidate does.
+func (k *keyboard) commit(ctx context.Context) error {
+	if len(k.composition) == 0 {
+		return nil
+	}
+
+	text := string(k.composition)
+	k.composition = nil
+
+	return k.process(ctx, keyHold(k.rnd), func(ctx context.Context) error {
+		return input.InsertText(text).Do(ctx)
+	})
+}
+
+// process presses a key handled by the IME, running the action while it is
+// down.
+func (k *keyboard) process(ctx context.Context, hold time.Duration, action func(context.Context) error) error {
+	down := processKey
+	down.Type = input.KeyRawDown
+
+	if err := down.Do(ctx); err != nil {
+		return err
+	}
+
+	if err := action(ctx); err != nil {
+		return err
+	}
+
+	if err := sleepContext(ctx, hold); err != nil {
+		return err
+	}
+
+	up := processKey
+	up.Type = input.KeyUp
+
+	return up.Do(ctx)
+}
+
+// finish commits an open composition and releases the modifiers.
+func (k *keyboard) finish(ctx context.Context) error {
+	if err := k.commit(ctx); err != nil {
+		return err
+	}
+
+	return k.setLevel(ctx, levelBase)
+}
+
+// Press presses a key or a key chord like a human would, e.g. "Enter",
+// "Control+A" or "Control+Shift+ArrowLeft". The modifiers are pressed one by
+// one, then the key, and all are released again in reverse order.
+//
+// Keys are the key names of the kb package, like "Tab" or "PageDown", or a
+// character of the keyboard layout; letters name their key, so "Shift+A" and
+// "Shift+a" are the same. Modifiers are Alt, Control or Ctrl, Meta or Command,
+// and Shift. Use "Control++" for the plus key.
+func Press(chord string, setters ...TypeOptionSetter) chromedp.ActionFunc {
+	options := defaultTypeOptions
+
+	for _, setter := range setters {
+		setter(&options)
+	}
+
+	if options.layout == nil {
+		options.layout = defaultTypeOptions.layout
+	}
+
+	return func(ctx context.Context) error {
+		c, err := parseChord(chord, options.layout)
+		if err != nil {
+			return err
+		}
+
+		return pressChord(ctx, c, options.layout, randFor(ctx), nil)
+	}
+}
+
+// chord is a parsed key chord.
+type chord struct {
+	modifiers []input.Modifier
+
+	// key is the key pressed last, with its level on the layout if it is a
+	// character.
+	key   input.DispatchKeyEventParams
+	level keyLevel
+}
+
+// chordModifiers are the modifier names of chords.
+var chordModifiers = map[string]input.Modifier{ //nolint:gochecknoglobals
+	"alt":     input.ModifierAlt,
+	"control": input.ModifierCtrl,
+	"ctrl":    input.ModifierCtrl,
+	"meta":    input.ModifierMeta,
+	"command": input.ModifierMeta,
+	"cmd":     input.ModifierMeta,
+	"shift":   input.ModifierShift,
+}
+
+// parseChord parses a chord of modifiers and a key joined by plus signs.
+func parseChord(s string, layout *KeyboardLayout) (chord, error) {
+	var c chord
+
+	parts := strings.Split(s, "+")
+	if strings.HasSuffix(s, "++") {
+		parts = append(parts[:len(parts)-2], "+")
+	}
+
+	name := parts[len(parts)-1]
+
+	for _, part := range parts[:len(parts)-1] {
+		m, ok := chordModifiers[strings.ToLower(part)]
+		if !ok {
+			return chord{}, fmt.Errorf("%w: modifier %q in %q", ErrUnknownKey, part, s)
+		}
+
+		c.modifiers = append(c.modifiers, m)
+	}
+
+	// A modifier on its own.
+	if m, ok := chordModifiers[strings.ToLower(name)]; ok {
+		key, _ := findModifierKey(m)
+		c.key = input.DispatchKeyEventParams{
+			Key:                   key.key,
+			Code:                  key.code,
+			WindowsVirtualKeyCode: key.keyCode,
+			NativeVirtualKeyCode:  key.keyCode,
+		}
+
+		return c, nil
+	}
+
+	if name == "Space" {
+		name = " "
+	}
+
+	if runes := []rune(name); len(runes) == 1 {
+		r := runes[0]
+
+		shifted := false
+		for _, m := range c.modifiers {
+			shifted = shifted || m == input.ModifierShift
+		}
+
+		if unicode.IsLetter(r) {
+			r = unicode.ToLower(r)
+			if shifted {
+				r = unicode.ToUpper(r)
+			}
+		}
+
+		if keys, ok := layout.strokes(r); ok && len(keys) == 1 {
+			key := keys[0]
+			c.level = key.level
+
+			if key.level == levelShift && !shifted {
+				c.modifiers = append(c.modifiers, input.ModifierShift)
+			}
+
+			if key.level == levelShift && unicode.IsLetter(r) {
+				// The chord names the key, the level comes from the modifiers.
+				c.level = levelBase
+			}
+
+			c.key = input.DispatchKeyEventParams{
+				Key:                   string(r),
+				Code:                  key.code,
+				WindowsVirtualKeyCode: key.keyCode,
+				NativeVirtualKeyCode:  key.keyCode,
+				Text:                  string(r),
+				UnmodifiedText:        string(key.base),
+			}
+
+			return c, nil
+		}
+	}
+
+	for r, key := range kb.Keys {
+		if key.Key == name && key.Key != string(r) {
+			c.key = input.DispatchKeyEventParams{
+				Key:                   key.Key,
+				Code:                  key.Code,
+				WindowsVirtualKeyCode: key.Windows,
+				NativeVirtualKeyCode:  key.Native,
+				Text:                  key.Text,
+				UnmodifiedText:        key.Unmodified,
+			}
+
+			return c, nil
+		}
+	}
+
+	return chord{}, fmt.Errorf("%w: %q in %q", ErrUnknownKey, name, s)
+}
+
+// pressChord presses the modifiers and the key of the chord, runs the action
+// while they are held down, and releases them again.
+func pressChord(ctx context.Context, c chord, layout *KeyboardLayout, rnd *rand.Rand, action func(context.Context) error) error {
+	k := keyboard{layout: layout, rnd: rnd}
+
+	var held input.Modifier
+
+	for _, m := range c.modifiers {
+		key, _ := findModifierKey(m)
+		held |= m
+
+		if err := modifierKeyEvent(input.KeyRawDown, key, held).Do(ctx); err != nil {
+			return err
+		}
+
+		if err := sleepContext(ctx, randDuration(rnd, 30*time.Millisecond, 90*time.Millisecond)); err != nil {
+			return err
+		}
+	}
+
+	if err := k.setLevel(ctx, c.level); err != nil {
+		return err
+	}
+
+	down := c.key
+	down.Modifiers = held | k.modifiers()
+	down.Type = input.KeyDown
+
+	// Shortcuts with other modifiers than Shift do not type text.
+	if down.Text == "" || held&^input.ModifierShift != 0 {
+		down.Type = input.KeyRawDown
+		down.Text = ""
+		down.UnmodifiedText = ""
+	}
+
+	if err := down.Do(ctx); err != nil {
+		return err
+	}
+
+	if action != nil {
+		if err := action(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := sleepContext(ctx, keyHold(rnd)); err != nil {
+		return err
+	}
+
+	up := down
+	up.Type = input.KeyUp
+	up.Text = ""
+	up.UnmodifiedText = ""
+	up.Commands = nil
+
+	if err := up.Do(ctx); err != nil {
+		return err
+	}
+
+	if err := k.setLevel(ctx, levelBase); err != nil {
+		return err
+	}
+
+	for i := len(c.modifiers) - 1; i >= 0; i-- {
+		if err := sleepContext(ctx, randDuration(rnd, 20*time.Millisecond, 60*time.Millisecond)); err != nil {
+			return err
+		}
+
+		key, _ := findModifierKey(c.modifiers[i])
+		held &^= c.modifiers[i]
+
+		if err := modifierKeyEvent(input.KeyUp, key, held).Do(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
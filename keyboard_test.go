@@ -0,0 +1,203 @@
+package chromedpundetected
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayouts(t *testing.T) {
+	type stroke struct {
+		code  string
+		level keyLevel
+	}
+
+	strokes := func(layout *KeyboardLayout, r rune) []stroke {
+		keys, ok := layout.strokes(r)
+		require.True(t, ok, "%s %q", layout.Name(), r)
+
+		var s []stroke
+		for _, k := range keys {
+			s = append(s, stroke{k.code, k.level})
+		}
+
+		return s
+	}
+
+	tests := []struct {
+		layout *KeyboardLayout
+		r      rune
+		want   []stroke
+	}{
+		{LayoutUS, 'a', []stroke{{"KeyA", levelBase}}},
+		{LayoutUS, 'A', []stroke{{"KeyA", levelShift}}},
+		{LayoutUS, '@', []stroke{{"Digit2", levelShift}}},
+		{LayoutUS, ' ', []stroke{{"Space", levelBase}}},
+		{LayoutUK, '@', []stroke{{"Quote", levelShift}}},
+		{LayoutUK, '£', []stroke{{"Digit3", levelShift}}},
+		{LayoutUK, '#', []stroke{{"Backslash", levelBase}}},
+		{LayoutUK, '\\', []stroke{{"IntlBackslash", levelBase}}},
+		{LayoutDE, 'z', []stroke{{"KeyY", levelBase}}},
+		{LayoutDE, '@', []stroke{{"KeyQ", levelAltGr}}},
+		{LayoutDE, 'ß', []stroke{{"Minus", levelBase}}},
+		{LayoutDE, 'é', []stroke{{"Equal", levelBase}, {"KeyE", levelBase}}},
+		{LayoutDE, 'Ê', []stroke{{"Backquote", levelBase}, {"KeyE", levelShift}}},
+		{LayoutDE, '^', []stroke{{"Backquote", levelBase}, {"Space", levelBase}}},
+		{LayoutFR, 'a', []stroke{{"KeyQ", levelBase}}},
+		{LayoutFR, '1', []stroke{{"Digit1", levelShift}}},
+		{LayoutFR, 'é', []stroke{{"Digit2", levelBase}}},
+		{LayoutFR, 'ê', []stroke{{"BracketLeft", levelBase}, {"KeyE", levelBase}}},
+		{LayoutFR, 'ï', []stroke{{"BracketLeft", levelShift}, {"KeyI", levelBase}}},
+		{LayoutFR, '^', []stroke{{"Digit9", levelAltGr}}},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.want, strokes(test.layout, test.r), "%s %q", test.layout.Name(), test.r)
+	}
+
+	_, ok := LayoutUS.strokes('é')
+	require.False(t, ok)
+
+	keys, _ := LayoutFR.strokes('a')
+	require.Equal(t, int64('A'), keys[0].keyCode)
+
+	keys, _ = LayoutUS.strokes(';')
+	require.Equal(t, int64(186), keys[0].keyCode)
+
+	// The comma key of AZERTY keyboards is where US keyboards have M.
+	keys, _ = LayoutFR.strokes(',')
+	require.Equal(t, "KeyM", keys[0].code)
+	require.Equal(t, int64(188), keys[0].keyCode)
+
+	keys, _ = LayoutFR.strokes('m')
+	require.Equal(t, int64('M'), keys[0].keyCode)
+
+	keys, _ = LayoutFR.strokes('-')
+	require.Equal(t, int64('6'), keys[0].keyCode)
+
+	require.True(t, isIMERune('日'))
+	require.True(t, isIMERune('한'))
+	require.False(t, isIMERune('é'))
+}
+
+func TestParseChord(t *testing.T) {
+	c, err := parseChord("Control+A", LayoutUS)
+	require.NoError(t, err)
+	require.Equal(t, []input.Modifier{input.ModifierCtrl}, c.modifiers)
+	require.Equal(t, "KeyA", c.key.Code)
+	require.Equal(t, "a", c.key.Key)
+
+	c, err = parseChord("ctrl+shift+a", LayoutUS)
+	require.NoError(t, err)
+	require.Equal(t, []input.Modifier{input.ModifierCtrl, input.ModifierShift}, c.modifiers)
+	require.Equal(t, "A", c.key.Key)
+	require.Equal(t, levelBase, c.level)
+
+	c, err = parseChord("Control+Shift+ArrowLeft", LayoutUS)
+	require.NoError(t, err)
+	require.Equal(t, "ArrowLeft", c.key.Key)
+	require.Equal(t, int64(37), c.key.WindowsVirtualKeyCode)
+
+	c, err = parseChord("Control++", LayoutUS)
+	require.NoError(t, err)
+	require.Equal(t, "Equal", c.key.Code)
+	require.Equal(t, []input.Modifier{input.ModifierCtrl, input.ModifierShift}, c.modifiers)
+
+	c, err = parseChord("Enter", LayoutUS)
+	require.NoError(t, err)
+	require.Equal(t, "Enter", c.key.Key)
+	require.Empty(t, c.modifiers)
+
+	c, err = parseChord("Shift", LayoutUS)
+	require.NoError(t, err)
+	require.Equal(t, "ShiftLeft", c.key.Code)
+
+	c, err = parseChord("Control+Z", LayoutDE)
+	require.NoError(t, err)
+	require.Equal(t, "KeyY", c.key.Code)
+
+	_, err = parseChord("Hyper+A", LayoutUS)
+	require.True(t, errors.Is(err, ErrUnknownKey))
+
+	_, err = parseChord("Control+Banana", LayoutUS)
+	require.True(t, errors.Is(err, ErrUnknownKey))
+}
+
+func TestKeyboard(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body>
+			<input id="field">
+			<script>
+				window.codes = [];
+				window.compositions = 0;
+				const field = document.getElementById('field');
+				field.addEventListener('keydown', (e) => window.codes.push(e.code + ':' + e.key));
+				field.addEventListener('compositionupdate', () => window.compositions++);
+			</script>
+		</body></html>`)
+	}))
+	defer srv.Close()
+
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(60*time.Second),
+			WithHeadless(),
+		),
+		func(ctx context.Context) error {
+			var (
+				german, replaced, cjk string
+				codes                 []string
+				compositions          int
+			)
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate(srv.URL),
+				chromedp.Focus(`#field`, chromedp.ByQuery),
+				Type("Grüße, café zu @", WithKeyboardLayout(LayoutDE), WithWPM(150)),
+				chromedp.Value(`#field`, &german, chromedp.ByQuery),
+				chromedp.Evaluate(`window.codes.splice(0)`, &codes),
+				Press("Control+A"),
+				Type("new", WithWPM(150)),
+				chromedp.Value(`#field`, &replaced, chromedp.ByQuery),
+				Press("Control+A"),
+				Press("Backspace"),
+				Type("日本語 ok", WithWPM(150)),
+				chromedp.Value(`#field`, &cjk, chromedp.ByQuery),
+				chromedp.Evaluate(`window.compositions`, &compositions),
+			); err != nil {
+				return err
+			}
+
+			if german != "Grüße, café zu @" {
+				return fmt.Errorf("typed %q with the German layout", german)
+			}
+
+			// On a German keyboard z is on the Y key, é uses a dead key and
+			// @ is typed with AltGr.
+			for _, want := range []string{"KeyY:z", "Equal:Dead", "KeyE:é", "AltRight:AltGraph", "KeyQ:@"} {
+				if !contains(codes, want) {
+					return fmt.Errorf("missing key %s in %v", want, codes)
+				}
+			}
+
+			if replaced != "new" {
+				return fmt.Errorf("typed %q after selecting all", replaced)
+			}
+
+			if cjk != "日本語 ok" || compositions < 3 {
+				return fmt.Errorf("typed %q with %d composition updates", cjk, compositions)
+			}
+
+			return nil
+		},
+	)
+}
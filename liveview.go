@@ -0,0 +1,617 @@
+package chromedpundetected
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+)
+
+// Errors.
+var (
+	ErrLiveViewRunning    = errors.New("live view already running")
+	ErrLiveViewNotRunning = errors.New("live view not running")
+	ErrEmptyToken         = errors.New("live view token must not be empty")
+	ErrInputOutOfSequence = errors.New("live view input out of sequence")
+)
+
+// LiveView streams a tab to a small web page and forwards the mouse and
+// keyboard input of the viewer back to the tab, so a person can watch or take
+// over a session, e.g. to complete a manual login.
+//
+// LiveView implements http.Handler; every request must carry the token as
+// 'token' query parameter or as bearer token. Input is only forwarded while
+// the viewer has taken control.
+//
+//	lv, err := NewLiveView(token)
+//	err = chromedp.Run(ctx, lv.Start())
+//	go lv.ListenAndServe("127.0.0.1:8080")
+//	// Ask for help and block until the viewer hands control back.
+//	err = chromedp.Run(ctx, lv.WaitHandBack("please log in"))
+type LiveView struct {
+	token   string
+	options screencastOptions
+
+	mu          sync.Mutex
+	ctx         context.Context //nolint:containedctx
+	stop        func(context.Context) error
+	starting    bool
+	frame       Frame
+	subscribers map[chan Frame]struct{}
+	controlled  bool
+	requested   string
+	handBack    chan struct{}
+	server      *http.Server
+
+	// inputMu serializes the dispatch of viewer input, in sequence order.
+	inputMu sync.Mutex
+	inputs  inputSequence
+}
+
+// NewLiveView creates a new live view protected by the token. The screencast
+// options control the frame rate and resolution of the stream.
+func NewLiveView(token string, setters ...ScreencastOption) (*LiveView, error) {
+	if token == "" {
+		return nil, ErrEmptyToken
+	}
+
+	options := defaultScreencastOptions
+
+	for _, setter := range setters {
+		setter(&options)
+	}
+
+	return &LiveView{
+		token:       token,
+		options:     options,
+		subscribers: make(map[chan Frame]struct{}),
+		handBack:    make(chan struct{}),
+	}, nil
+}
+
+// Start starts streaming the current tab. Input of the viewer is dispatched
+// with the context of this action, so it must stay valid while streaming.
+func (l *LiveView) Start() chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		l.mu.Lock()
+		if l.stop != nil || l.starting {
+			l.mu.Unlock()
+			return ErrLiveViewRunning
+		}
+		l.starting = true
+		l.mu.Unlock()
+
+		// Frames may arrive before the screencast has started, and publish
+		// locks the mutex on the event loop, so it must not be held here.
+		stop, err := startScreencast(ctx, l.options, l.publish)
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		l.starting = false
+		if err != nil {
+			return err
+		}
+
+		l.ctx = ctx
+		l.stop = stop
+
+		return nil
+	}
+}
+
+// Stop stops streaming the tab. Connected viewers keep the last frame.
+func (l *LiveView) Stop() chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		l.mu.Lock()
+		stop := l.stop
+		l.stop = nil
+		l.ctx = nil
+		l.mu.Unlock()
+
+		if stop == nil {
+			return ErrLiveViewNotRunning
+		}
+
+		return stop(ctx)
+	}
+}
+
+// WaitHandBack asks the viewer for help with the given message and blocks until
+// the viewer has taken control and handed it back, or the context is done.
+func (l *LiveView) WaitHandBack(message string) chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		l.mu.Lock()
+		l.requested = message
+		ch := l.handBack
+		l.mu.Unlock()
+
+		defer func() {
+			l.mu.Lock()
+			l.requested = ""
+			l.mu.Unlock()
+		}()
+
+		select {
+		case <-ch:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Controlled reports whether the viewer currently has control over the tab.
+func (l *LiveView) Controlled() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.controlled
+}
+
+// ListenAndServe serves the live view on the address until Close is called.
+func (l *LiveView) ListenAndServe(addr string) error {
+	l.mu.Lock()
+	l.server = &http.Server{Addr: addr, Handler: l, ReadHeaderTimeout: 10 * time.Second}
+	server := l.server
+	l.mu.Unlock()
+
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Close shuts down the server started by ListenAndServe.
+func (l *LiveView) Close() error {
+	l.mu.Lock()
+	server := l.server
+	l.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	return server.Close()
+}
+
+// ServeHTTP implements http.Handler.
+func (l *LiveView) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !l.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/", "/index.html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(liveViewHTML)) //nolint:errcheck
+	case "/stream":
+		l.serveStream(w, r)
+	case "/state":
+		l.serveState(w, r)
+	case "/control":
+		l.serveControl(w, r)
+	case "/input":
+		l.serveInput(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (l *LiveView) authorized(r *http.Request) bool {
+	token := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(l.token)) == 1
+}
+
+func (l *LiveView) publish(frame Frame) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.frame = frame
+
+	for ch := range l.subscribers {
+		// Drop the frame for slow viewers rather than blocking the stream.
+		select {
+		case ch <- frame:
+		default:
+		}
+	}
+}
+
+func (l *LiveView) subscribe() (chan Frame, func()) {
+	ch := make(chan Frame, 1)
+
+	l.mu.Lock()
+	l.subscribers[ch] = struct{}{}
+
+	if l.frame.Data != nil {
+		ch <- l.frame
+	}
+	l.mu.Unlock()
+
+	return ch, func() {
+		l.mu.Lock()
+		delete(l.subscribers, ch)
+		l.mu.Unlock()
+	}
+}
+
+// serveStream streams the frames as multipart JPEG, which browsers render
+// natively in an image element.
+func (l *LiveView) serveStream(w http.ResponseWriter, r *http.Request) {
+	const boundary = "liveviewframe"
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ch, unsubscribe := l.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+boundary)
+	w.Header().Set("Cache-Control", "no-store")
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case frame := <-ch:
+			if _, err := fmt.Fprintf(w, "--%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n",
+				boundary, len(frame.Data)); err != nil {
+				return
+			}
+
+			if _, err := w.Write(frame.Data); err != nil {
+				return
+			}
+
+			if _, err := w.Write([]byte("\r\n")); err != nil {
+				return
+			}
+
+			flusher.Flush()
+		}
+	}
+}
+
+// liveViewState is the state of the live view as reported to the viewer.
+type liveViewState struct {
+	Running    bool    `json:"running"`
+	Controlled bool    `json:"controlled"`
+	Requested  string  `json:"requested"`
+	Width      float64 `json:"width"`
+	Height     float64 `json:"height"`
+}
+
+func (l *LiveView) serveState(w http.ResponseWriter, _ *http.Request) {
+	l.mu.Lock()
+	state := liveViewState{
+		Running:    l.stop != nil,
+		Controlled: l.controlled,
+		Requested:  l.requested,
+		Width:      l.frame.Width,
+		Height:     l.frame.Height,
+	}
+	l.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(state) //nolint:errcheck,errchkjson
+}
+
+// serveControl lets the viewer take control, or hand it back to the Go code.
+func (l *LiveView) serveControl(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Take bool `json:"take"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l.mu.Lock()
+	if l.controlled && !req.Take {
+		close(l.handBack)
+		l.handBack = make(chan struct{})
+	}
+	l.controlled = req.Take
+	l.mu.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// liveViewInput is an input event sent by the viewer. Mouse coordinates are
+// relative to the stream image, between 0 and 1.
+type liveViewInput struct {
+	Kind       string  `json:"kind"`
+	Type       string  `json:"type"`
+	X          float64 `json:"x"`
+	Y          float64 `json:"y"`
+	Button     string  `json:"button"`
+	ClickCount int64   `json:"clickCount"`
+	DeltaX     float64 `json:"deltaX"`
+	DeltaY     float64 `json:"deltaY"`
+	Modifiers  int64   `json:"modifiers"`
+	Key        string  `json:"key"`
+	Code       string  `json:"code"`
+	Text       string  `json:"text"`
+	KeyCode    int64   `json:"keyCode"`
+
+	// Viewer identifies the viewer page, and Seq numbers its events from 1.
+	Viewer string `json:"viewer"`
+	Seq    int64  `json:"seq"`
+}
+
+// maxPendingInputs is the number of events held back waiting for an earlier
+// event of the sequence.
+const maxPendingInputs = 64
+
+// inputSequence puts the input events of a viewer back in the order they were
+// sent in, as HTTP requests may arrive out of order.
+type inputSequence struct {
+	viewer  string
+	next    int64
+	pending map[int64]liveViewInput
+}
+
+// add adds the event, and returns the events that are next in the sequence,
+// in order. A new viewer starts a new sequence. Events already seen, or too
+// far ahead, are rejected.
+func (s *inputSequence) add(ev liveViewInput) ([]liveViewInput, error) {
+	if ev.Viewer != s.viewer || s.pending == nil {
+		s.viewer = ev.Viewer
+		s.next = 1
+		s.pending = make(map[int64]liveViewInput)
+	}
+
+	switch {
+	case ev.Seq < s.next:
+		return nil, fmt.Errorf("%w: got %d, want %d", ErrInputOutOfSequence, ev.Seq, s.next)
+	case ev.Seq > s.next:
+		if _, ok := s.pending[ev.Seq]; ok || len(s.pending) >= maxPendingInputs {
+			return nil, fmt.Errorf("%w: got %d, want %d", ErrInputOutOfSequence, ev.Seq, s.next)
+		}
+
+		s.pending[ev.Seq] = ev
+
+		return nil, nil
+	}
+
+	ready := []liveViewInput{ev}
+	s.next++
+
+	for {
+		pending, ok := s.pending[s.next]
+		if !ok {
+			break
+		}
+
+		delete(s.pending, s.next)
+		ready = append(ready, pending)
+		s.next++
+	}
+
+	return ready, nil
+}
+
+func (l *LiveView) serveInput(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var ev liveViewInput
+	if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l.inputMu.Lock()
+	defer l.inputMu.Unlock()
+
+	// The sequence advances for rejected events too, or the events after
+	// them would wait for them forever.
+	ready, err := l.inputs.add(ev)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	l.mu.Lock()
+	ctx, controlled, frame := l.ctx, l.controlled, l.frame
+	l.mu.Unlock()
+
+	switch {
+	case !controlled:
+		http.Error(w, "take control first", http.StatusConflict)
+		return
+	case ctx == nil:
+		http.Error(w, "live view not running", http.StatusConflict)
+		return
+	case frame.Width == 0 || frame.Height == 0:
+		// Coordinates are relative to the frame, so can't be mapped yet.
+		http.Error(w, "no frame yet", http.StatusConflict)
+		return
+	}
+
+	for _, ev := range ready {
+		if err := dispatchLiveViewInput(ctx, ev, frame); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+
+	if len(ready) == 0 {
+		// Held back until the events before it arrive.
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func dispatchLiveViewInput(ctx context.Context, ev liveViewInput, frame Frame) error {
+	switch ev.Kind {
+	case "mouse":
+		x, y := ev.X*frame.Width, ev.Y*frame.Height
+
+		p := input.DispatchMouseEvent(input.MouseType(ev.Type), x, y).
+			WithModifiers(input.Modifier(ev.Modifiers)).
+			WithClickCount(ev.ClickCount)
+
+		if ev.Button != "" {
+			p = p.WithButton(input.MouseButton(ev.Button))
+		}
+
+		if input.MouseType(ev.Type) == input.MouseWheel {
+			p = p.WithDeltaX(ev.DeltaX).WithDeltaY(ev.DeltaY)
+		}
+
+		return DispatchMouseEvent(p).Do(ctx)
+	case "key":
+		p := input.DispatchKeyEvent(input.KeyType(ev.Type)).
+			WithModifiers(input.Modifier(ev.Modifiers)).
+			WithKey(ev.Key).
+			WithCode(ev.Code).
+			WithWindowsVirtualKeyCode(ev.KeyCode).
+			WithNativeVirtualKeyCode(ev.KeyCode)
+
+		if ev.Text != "" {
+			p = p.WithText(ev.Text).WithUnmodifiedText(ev.Text)
+		}
+
+		return p.Do(ctx)
+	default:
+		return fmt.Errorf("unknown input kind %q", ev.Kind)
+	}
+}
+
+// liveViewHTML is the viewer page.
+var liveViewHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Live view</title>
+<style>
+  body { margin: 0; font-family: sans-serif; background: #222; color: #eee; }
+  header { display: flex; gap: 1em; align-items: center; padding: .5em 1em; }
+  #request { color: #fc3; }
+  #screen { display: block; max-width: 100%; margin: 0 auto; outline: none; }
+  #screen.controlled { box-shadow: 0 0 0 3px #e33; cursor: crosshair; }
+</style>
+</head>
+<body>
+<header>
+  <button id="control">Take control</button>
+  <span id="status"></span>
+  <span id="request"></span>
+</header>
+<img id="screen" tabindex="0" draggable="false">
+<script>
+(() => {
+  const token = new URLSearchParams(location.search).get('token') || '';
+  const q = '?token=' + encodeURIComponent(token);
+  const screen = document.getElementById('screen');
+  const button = document.getElementById('control');
+  const status = document.getElementById('status');
+  const request = document.getElementById('request');
+  let controlled = false;
+
+  screen.src = 'stream' + q;
+
+  const post = (path, body) => fetch(path + q, { method: 'POST', body: JSON.stringify(body) });
+
+  // Input is sent one request at a time, so the tab receives it in order, and
+  // numbered, so the server can restore the order regardless.
+  const viewer = Math.random().toString(36).slice(2);
+  let seq = 0;
+  let queue = Promise.resolve();
+  const send = (ev) => {
+    ev.viewer = viewer;
+    ev.seq = ++seq;
+    queue = queue.then(() => post('input', ev)).catch(() => {});
+  };
+
+  const modifiers = (e) => (e.altKey ? 1 : 0) | (e.ctrlKey ? 2 : 0) | (e.metaKey ? 4 : 0) | (e.shiftKey ? 8 : 0);
+  const buttons = ['left', 'middle', 'right'];
+
+  const mouse = (type, e, extra) => {
+    if (!controlled) return;
+    e.preventDefault();
+    const r = screen.getBoundingClientRect();
+    send(Object.assign({
+      kind: 'mouse', type,
+      x: (e.clientX - r.left) / r.width,
+      y: (e.clientY - r.top) / r.height,
+      modifiers: modifiers(e),
+    }, extra || {}));
+  };
+
+  let lastMove = 0;
+  screen.addEventListener('mousemove', (e) => {
+    const now = Date.now();
+    if (now - lastMove < 30) return;
+    lastMove = now;
+    mouse('mouseMoved', e, { button: 'none' });
+  });
+  screen.addEventListener('mousedown', (e) => { screen.focus(); mouse('mousePressed', e, { button: buttons[e.button], clickCount: e.detail || 1 }); });
+  screen.addEventListener('mouseup', (e) => mouse('mouseReleased', e, { button: buttons[e.button], clickCount: e.detail || 1 }));
+  screen.addEventListener('contextmenu', (e) => e.preventDefault());
+  screen.addEventListener('wheel', (e) => mouse('mouseWheel', e, { deltaX: e.deltaX, deltaY: e.deltaY }), { passive: false });
+
+  const key = (type, e) => {
+    if (!controlled) return;
+    e.preventDefault();
+    const text = type === 'keyDown' && e.key.length === 1 ? e.key : '';
+    send({
+      kind: 'key', type: text ? 'keyDown' : (type === 'keyDown' ? 'rawKeyDown' : type),
+      key: e.key, code: e.code, text, keyCode: e.keyCode, modifiers: modifiers(e),
+    });
+  };
+  screen.addEventListener('keydown', (e) => key('keyDown', e));
+  screen.addEventListener('keyup', (e) => key('keyUp', e));
+
+  button.addEventListener('click', async () => {
+    await post('control', { take: !controlled });
+    refresh();
+  });
+
+  const refresh = async () => {
+    const res = await fetch('state' + q);
+    if (!res.ok) return;
+    const state = await res.json();
+    controlled = state.controlled;
+    button.textContent = controlled ? 'Hand back control' : 'Take control';
+    screen.classList.toggle('controlled', controlled);
+    status.textContent = state.running ? (controlled ? 'You are in control' : 'Watching') : 'Not streaming';
+    request.textContent = state.requested ? 'Help requested: ' + state.requested : '';
+  };
+
+  refresh();
+  setInterval(refresh, 1000);
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,104 @@
+package chromedpundetected
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveViewControl(t *testing.T) {
+	_, err := NewLiveView("")
+	require.ErrorIs(t, err, ErrEmptyToken)
+
+	lv, err := NewLiveView("secret")
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(lv)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/state?token=wrong")
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	post := func(path, body string) int {
+		res, err := http.Post(srv.URL+path+"?token=secret", "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		return res.StatusCode
+	}
+
+	// Input is rejected until the viewer takes control.
+	require.Equal(t, http.StatusConflict, post("/input", `{"kind":"mouse","type":"mouseMoved","viewer":"a","seq":1}`))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- lv.WaitHandBack("log in")(context.Background())
+	}()
+
+	require.Equal(t, http.StatusNoContent, post("/control", `{"take":true}`))
+	require.True(t, lv.Controlled())
+
+	// The rejected event still counts in the sequence, so the next one is
+	// not held back waiting for it.
+	require.Equal(t, http.StatusConflict, post("/input", `{"kind":"mouse","type":"mouseMoved","viewer":"a","seq":2}`))
+	lv.inputMu.Lock()
+	require.Equal(t, int64(3), lv.inputs.next)
+	lv.inputMu.Unlock()
+	require.Equal(t, http.StatusNoContent, post("/control", `{"take":false}`))
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("WaitHandBack did not return after hand back")
+	}
+}
+
+func TestInputSequence(t *testing.T) {
+	var s inputSequence
+
+	seqs := func(events []liveViewInput) []int64 {
+		var got []int64
+		for _, ev := range events {
+			got = append(got, ev.Seq)
+		}
+
+		return got
+	}
+
+	ready, err := s.add(liveViewInput{Viewer: "a", Seq: 1})
+	require.NoError(t, err)
+	require.Equal(t, []int64{1}, seqs(ready))
+
+	// Events arriving early are held back until the gap is filled.
+	ready, err = s.add(liveViewInput{Viewer: "a", Seq: 3})
+	require.NoError(t, err)
+	require.Empty(t, ready)
+
+	ready, err = s.add(liveViewInput{Viewer: "a", Seq: 2})
+	require.NoError(t, err)
+	require.Equal(t, []int64{2, 3}, seqs(ready))
+
+	_, err = s.add(liveViewInput{Viewer: "a", Seq: 2})
+	require.ErrorIs(t, err, ErrInputOutOfSequence)
+
+	// A reloaded viewer page starts over.
+	ready, err = s.add(liveViewInput{Viewer: "b", Seq: 1})
+	require.NoError(t, err)
+	require.Equal(t, []int64{1}, seqs(ready))
+
+	for i := int64(0); i < maxPendingInputs; i++ {
+		_, err = s.add(liveViewInput{Viewer: "b", Seq: 3 + i})
+		require.NoError(t, err)
+	}
+
+	_, err = s.add(liveViewInput{Viewer: "b", Seq: 3 + maxPendingInputs})
+	require.ErrorIs(t, err, ErrInputOutOfSequence)
+}
@@ -0,0 +1,226 @@
+package chromedpundetected
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/Xuanwo/go-locale"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+	"golang.org/x/text/language"
+)
+
+// DefaultLanguage is used when no language is configured and the host
+// language can not be detected.
+var DefaultLanguage = "en-US"
+
+// Errors.
+var (
+	ErrInvalidLanguage = errors.New("invalid language")
+)
+
+// weightedLanguage is a language tag with its Accept-Language quality.
+type weightedLanguage struct {
+	tag     language.Tag
+	quality float32
+}
+
+// parseLanguages parses language tags, each optionally weighted as in the
+// Accept-Language header, e.g. ["nl-NL", "nl;q=0.9", "en;q=0.8"]. The order
+// is kept, tags without weight get decreasing weights like Chrome uses.
+func parseLanguages(langs []string) ([]weightedLanguage, error) {
+	parsed := make([]weightedLanguage, 0, len(langs))
+	seen := make(map[language.Tag]bool, len(langs))
+
+	for i, lang := range langs {
+		tag, weight, _ := strings.Cut(lang, ";")
+
+		t, err := language.Parse(strings.TrimSpace(tag))
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %s", ErrInvalidLanguage, lang, err.Error())
+		}
+
+		q := float32(1) - float32(i)*0.1
+		if q < 0.1 {
+			q = 0.1
+		}
+
+		if weight != "" {
+			weight = strings.TrimSpace(weight)
+			f, err := strconv.ParseFloat(strings.TrimPrefix(weight, "q="), 32)
+
+			if !strings.HasPrefix(weight, "q=") || err != nil || f < 0 || f > 1 {
+				return nil, fmt.Errorf("%w %q: invalid weight", ErrInvalidLanguage, lang)
+			}
+
+			q = float32(f)
+		}
+
+		if !seen[t] {
+			seen[t] = true
+
+			parsed = append(parsed, weightedLanguage{tag: t, quality: q})
+		}
+	}
+
+	return parsed, nil
+}
+
+// detectLanguages returns the languages of the host, from the environment on
+// Linux and the system settings on Windows and macOS. Falls back to
+// DefaultLanguage if none can be detected.
+func detectLanguages() []string {
+	var langs []string
+
+	tags, err := locale.DetectAll()
+	if err == nil {
+		seen := make(map[string]bool, len(tags))
+
+		for _, tag := range tags {
+			// The C and POSIX locales are parsed as undetermined.
+			if s := tag.String(); tag != language.Und && !seen[s] {
+				seen[s] = true
+
+				langs = append(langs, s)
+			}
+		}
+	}
+
+	if len(langs) == 0 {
+		return []string{DefaultLanguage}
+	}
+
+	return langs
+}
+
+// languages returns the languages of the config. Languages takes precedence
+// over Language, then the persona languages, and finally the host languages.
+func (c Config) languages() ([]weightedLanguage, error) {
+	switch {
+	case len(c.Languages) > 0:
+		return parseLanguages(c.Languages)
+	case c.Language != "":
+		return parseLanguages([]string{c.Language})
+	case c.Persona != nil && len(c.Persona.Languages) > 0:
+		return parseLanguages(c.Persona.Languages)
+	default:
+		return parseLanguages(detectLanguages())
+	}
+}
+
+// acceptLanguage formats the languages as Accept-Language header value, e.g.
+// "nl-NL,nl;q=0.9,en;q=0.8".
+func acceptLanguage(langs []weightedLanguage) string {
+	parts := make([]string, 0, len(langs))
+
+	for i, lang := range langs {
+		if i == 0 || lang.quality >= 1 {
+			parts = append(parts, lang.tag.String())
+			continue
+		}
+
+		parts = append(parts, lang.tag.String()+";q="+strconv.FormatFloat(float64(lang.quality), 'f', 1, 32))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// languageList formats the languages as comma separated list without weights,
+// as used by the Chrome preferences, e.g. "nl-NL,nl,en".
+func languageList(langs []weightedLanguage) string {
+	parts := make([]string, 0, len(langs))
+
+	for _, lang := range langs {
+		parts = append(parts, lang.tag.String())
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// localeFlags returns the launch options that set the browser languages.
+//
+// On Linux Chrome ignores the --lang flag and takes its UI language from the
+// environment, so the environment is set as well.
+func localeFlags(langs []weightedLanguage) []chromedp.ExecAllocatorOption {
+	opts := []chromedp.ExecAllocatorOption{
+		chromedp.Flag("lang", langs[0].tag.String()),
+		chromedp.Flag("accept-lang", languageList(langs)),
+	}
+
+	if runtime.GOOS == "linux" {
+		posix := make([]string, 0, len(langs))
+		for _, lang := range langs {
+			posix = append(posix, strings.ReplaceAll(lang.tag.String(), "-", "_"))
+		}
+
+		opts = append(opts, chromedp.Env(
+			"LANGUAGE="+strings.Join(posix, ":"),
+			"LANG="+posix[0]+".UTF-8",
+		))
+	}
+
+	return opts
+}
+
+// writeLanguagePrefs sets the accepted languages in the preferences of the
+// default profile in the user data dir, from which Chrome derives the
+// Accept-Language header and navigator.languages. Only the intl preferences
+// are decoded, other preferences are written back as they were read.
+func writeLanguagePrefs(userDataDir string, langs []weightedLanguage) error {
+	path := filepath.Join(userDataDir, "Default", "Preferences")
+
+	prefs := make(map[string]json.RawMessage)
+
+	if b, err := os.ReadFile(path); err == nil { //nolint:gosec
+		if err := json.Unmarshal(b, &prefs); err != nil {
+			return fmt.Errorf("unmarshal preferences: %w", err)
+		}
+	}
+
+	intl := make(map[string]any)
+
+	if raw, ok := prefs["intl"]; ok {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+
+		if err := dec.Decode(&intl); err != nil {
+			return fmt.Errorf("unmarshal intl preferences: %w", err)
+		}
+	}
+
+	intl["accept_languages"] = languageList(langs)
+	intl["selected_languages"] = languageList(langs)
+
+	raw, err := json.Marshal(intl)
+	if err != nil {
+		return err
+	}
+
+	prefs["intl"] = raw
+
+	b, err := json.Marshal(prefs)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b, 0o600)
+}
+
+// localeSetup returns the per tab actions that set the default locale of Intl
+// to the preferred language.
+func localeSetup(langs []weightedLanguage) []chromedp.Action {
+	return []chromedp.Action{
+		emulation.SetLocaleOverride().WithLocale(langs[0].tag.String()),
+	}
+}
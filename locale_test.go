@@ -0,0 +1,110 @@
+package chromedpundetected
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLanguages(t *testing.T) {
+	langs, err := parseLanguages([]string{"nl-NL", "nl", "en;q=0.5", "nl"})
+	require.NoError(t, err)
+	require.Len(t, langs, 3)
+	require.Equal(t, "nl-NL,nl;q=0.9,en;q=0.5", acceptLanguage(langs))
+	require.Equal(t, "nl-NL,nl,en", languageList(langs))
+
+	_, err = parseLanguages([]string{"en;q=2"})
+	require.ErrorIs(t, err, ErrInvalidLanguage)
+
+	_, err = parseLanguages([]string{"not a language"})
+	require.ErrorIs(t, err, ErrInvalidLanguage)
+
+	langs, err = NewConfig(WithLanguages("de-DE")).languages()
+	require.NoError(t, err)
+	require.Equal(t, "de-DE", acceptLanguage(langs))
+
+	require.NotEmpty(t, detectLanguages())
+}
+
+func TestWriteLanguagePrefs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Default", "Preferences")
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+	require.NoError(t, os.WriteFile(path, []byte(`{"profile":{"name":"test"},"intl":{"app_locale":"fr"},"sync":{"id":9007199254740993}}`), 0o600))
+
+	langs, err := parseLanguages([]string{"fr-FR", "fr"})
+	require.NoError(t, err)
+	require.NoError(t, writeLanguagePrefs(dir, langs))
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var prefs struct {
+		Profile map[string]string          `json:"profile"`
+		Intl    map[string]string          `json:"intl"`
+		Sync    map[string]json.RawMessage `json:"sync"`
+	}
+	require.NoError(t, json.Unmarshal(b, &prefs))
+	require.Equal(t, "test", prefs.Profile["name"])
+	require.Equal(t, "fr", prefs.Intl["app_locale"])
+	require.Equal(t, "fr-FR,fr", prefs.Intl["accept_languages"])
+	require.Equal(t, "9007199254740993", string(prefs.Sync["id"]))
+}
+
+func TestLanguages(t *testing.T) {
+	header := make(chan string, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case header <- r.Header.Get("Accept-Language"):
+		default:
+		}
+		fmt.Fprint(w, "<html><body>languages</body></html>")
+	}))
+	defer srv.Close()
+
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(20*time.Second),
+			WithHeadless(),
+			WithLanguages("nl-NL", "nl", "en"),
+		),
+		func(ctx context.Context) error {
+			var res struct {
+				Languages []string `json:"languages"`
+				Intl      string   `json:"intl"`
+			}
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate(srv.URL),
+				chromedp.Evaluate(`({
+					languages: navigator.languages,
+					intl: Intl.DateTimeFormat().resolvedOptions().locale,
+				})`, &res),
+			); err != nil {
+				return err
+			}
+
+			if h := <-header; h != "nl-NL,nl;q=0.9,en;q=0.8" {
+				return fmt.Errorf("unexpected Accept-Language %q", h)
+			}
+
+			if len(res.Languages) != 3 || res.Languages[0] != "nl-NL" || res.Intl != "nl-NL" {
+				return fmt.Errorf("unexpected languages: %+v", res)
+			}
+
+			return nil
+		},
+	)
+}
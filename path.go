@@ -0,0 +1,385 @@
+package chromedpundetected
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Errors.
+var (
+	ErrEmptyPath = errors.New("path generator returned no points")
+)
+
+// Point is a position in CSS pixels, relative to the viewport.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// PathPoint is a point of a mouse path, with the time to wait before moving
+// the cursor to it.
+type PathPoint struct {
+	Point
+	Delay time.Duration
+}
+
+// PathRequest describes the mouse movement to generate a path for.
+type PathRequest struct {
+	From Point
+	To   Point
+
+	// TargetWidth is the size of the target in pixels, smaller targets take
+	// longer to hit. Zero if unknown.
+	TargetWidth float64
+
+	// Steps is the number of points requested, zero lets the generator decide.
+	Steps int
+
+	// Rand is the source of randomness to use.
+	Rand *rand.Rand
+}
+
+// PathGenerator generates the points a mouse movement passes through. The last
+// point must be the destination. The points are clamped to the viewport.
+type PathGenerator interface {
+	Path(req PathRequest) []PathPoint
+}
+
+// Defaults used by the path generators.
+const (
+	// defaultTargetWidth is the target size assumed if unknown.
+	defaultTargetWidth = 20
+
+	// pathInterval is the interval between points of time based paths, about
+	// the rate at which a real mouse reports its position.
+	pathInterval = 16 * time.Millisecond
+)
+
+// fittsDuration returns the time a movement over the distance to a target of
+// the width takes, following Fitts's law: a + b * log2(distance/width + 1).
+func fittsDuration(a, b time.Duration, distance, width float64) time.Duration {
+	if width <= 0 {
+		width = defaultTargetWidth
+	}
+
+	return a + time.Duration(float64(b)*math.Log2(distance/width+1))
+}
+
+// minimumJerk is the position along a movement at time t between 0 and 1, for
+// the smooth bell shaped velocity profile of human reaching movements.
+func minimumJerk(t float64) float64 {
+	return t * t * t * (10 - 15*t + 6*t*t)
+}
+
+// pathSteps returns the number of points for a movement taking the duration,
+// unless the request sets it.
+func pathSteps(req PathRequest, duration time.Duration) int {
+	if req.Steps > 0 {
+		return req.Steps
+	}
+
+	if steps := int(duration / pathInterval); steps > 1 {
+		return steps
+	}
+
+	return 1
+}
+
+// BezierPath moves along a cubic Bezier curve with randomly placed control
+// points, with the speed following a minimum jerk profile. The duration
+// follows Fitts's law.
+type BezierPath struct {
+	// Spread is the maximum distance of the control points from the straight
+	// line, relative to the distance. Defaults to 0.3.
+	Spread float64
+}
+
+// Path implements PathGenerator.
+func (b BezierPath) Path(req PathRequest) []PathPoint {
+	spread := b.Spread
+	if spread == 0 {
+		spread = 0.3
+	}
+
+	distance := math.Hypot(req.To.X-req.From.X, req.To.Y-req.From.Y)
+	duration := fittsDuration(100*time.Millisecond, 150*time.Millisecond, distance, req.TargetWidth)
+	steps := pathSteps(req, duration)
+
+	c1, c2 := bezierControls(req.From, req.To, spread, req.Rand)
+
+	points := make([]PathPoint, 0, steps)
+
+	for i := 1; i <= steps; i++ {
+		t := minimumJerk(float64(i) / float64(steps))
+		points = append(points, PathPoint{
+			Point: bezierCubic(req.From, c1, c2, req.To, t),
+			Delay: duration / time.Duration(steps),
+		})
+	}
+
+	points[len(points)-1].Point = req.To
+
+	return points
+}
+
+// bezierControls returns two control points at about one and two thirds of
+// the line from a to b, offset perpendicular to it by up to spread times the
+// distance, both to the same side for a natural arc.
+func bezierControls(a, b Point, spread float64, rnd *rand.Rand) (Point, Point) {
+	dx, dy := b.X-a.X, b.Y-a.Y
+
+	// Normal of the line, as long as the line itself.
+	nx, ny := -dy, dx
+
+	side := 1.0
+	if rnd.Intn(2) == 0 {
+		side = -1
+	}
+
+	control := func(along float64) Point {
+		along += (rnd.Float64() - 0.5) * 0.2
+		offset := side * spread * (0.3 + 0.7*rnd.Float64())
+
+		return Point{
+			X: a.X + dx*along + nx*offset,
+			Y: a.Y + dy*along + ny*offset,
+		}
+	}
+
+	return control(1.0 / 3), control(2.0 / 3)
+}
+
+// bezierCubic returns a point along a cubic Bézier curve.
+// t is the "progress" along the curve, should be between 0 and 1.
+func bezierCubic(p0, p1, p2, p3 Point, t float64) Point {
+	mt := 1 - t
+	mt2 := mt * mt
+	t2 := t * t
+
+	return Point{
+		X: mt2*mt*p0.X + 3*mt2*t*p1.X + 3*mt*t2*p2.X + t2*t*p3.X,
+		Y: mt2*mt*p0.Y + 3*mt2*t*p1.Y + 3*mt*t2*p2.Y + t2*t*p3.Y,
+	}
+}
+
+// WindMousePath moves like the WindMouse algorithm: the cursor is pulled to
+// the destination by gravity and pushed around by random wind, slowing down
+// near the destination. The zero value uses the defaults of the original
+// algorithm.
+//
+// See https://ben.land/post/2021/04/25/windmouse-human-mouse-movement/.
+type WindMousePath struct {
+	// Gravity is the pull towards the destination. Defaults to 9.
+	Gravity float64
+
+	// Wind is the strength of the random fluctuations. Defaults to 3.
+	Wind float64
+
+	// MaxStep is the maximum distance of a step in pixels. Defaults to 15.
+	MaxStep float64
+
+	// TargetArea is the distance from the destination at which the wind dies
+	// down and the steps shrink. Defaults to 12.
+	TargetArea float64
+}
+
+// Path implements PathGenerator.
+func (w WindMousePath) Path(req PathRequest) []PathPoint { //nolint:varnamelen
+	gravity, wind, maxStep, area := w.Gravity, w.Wind, w.MaxStep, w.TargetArea
+	if gravity == 0 {
+		gravity = 9
+	}
+
+	if wind == 0 {
+		wind = 3
+	}
+
+	if maxStep == 0 {
+		maxStep = 15
+	}
+
+	if area == 0 {
+		area = 12
+	}
+
+	var (
+		sqrt3 = math.Sqrt(3)
+		sqrt5 = math.Sqrt(5)
+
+		rnd    = req.Rand
+		x, y   = req.From.X, req.From.Y
+		vx, vy float64
+		wx, wy float64
+		points []Point
+	)
+
+	// Guard against paths that never converge with odd parameters.
+	for i := 0; i < 10000; i++ {
+		dist := math.Hypot(req.To.X-x, req.To.Y-y)
+		if dist < 1 {
+			break
+		}
+
+		gust := math.Min(wind, dist)
+
+		if dist >= area {
+			wx = wx/sqrt3 + (2*rnd.Float64()-1)*gust/sqrt5
+			wy = wy/sqrt3 + (2*rnd.Float64()-1)*gust/sqrt5
+		} else {
+			wx /= sqrt3
+			wy /= sqrt3
+
+			if maxStep < 3 {
+				maxStep = rnd.Float64()*3 + 3
+			} else {
+				maxStep /= sqrt5
+			}
+		}
+
+		vx += wx + gravity*(req.To.X-x)/dist
+		vy += wy + gravity*(req.To.Y-y)/dist
+
+		if v := math.Hypot(vx, vy); v > maxStep {
+			clip := maxStep/2 + rnd.Float64()*maxStep/2
+			vx = vx / v * clip
+			vy = vy / v * clip
+		}
+
+		x += vx
+		y += vy
+
+		points = append(points, Point{X: x, Y: y})
+	}
+
+	points = append(points, req.To)
+	points = resample(points, req.Steps)
+
+	distance := math.Hypot(req.To.X-req.From.X, req.To.Y-req.From.Y)
+	duration := fittsDuration(100*time.Millisecond, 150*time.Millisecond, distance, req.TargetWidth)
+
+	path := make([]PathPoint, 0, len(points))
+	for _, p := range points {
+		path = append(path, PathPoint{Point: p, Delay: duration / time.Duration(len(points))})
+	}
+
+	return path
+}
+
+// resample picks n points evenly from the points, keeping the last one. The
+// points are returned as is if n is zero or not smaller.
+func resample(points []Point, n int) []Point {
+	if n <= 0 || n >= len(points) {
+		return points
+	}
+
+	out := make([]Point, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, points[i*len(points)/n-1])
+	}
+
+	return out
+}
+
+// FittsPath moves in a primary movement that overshoots the destination,
+// followed by a short correction back to it, both with a minimum jerk speed
+// profile. The total duration follows Fitts's law, scaling with the distance
+// and the target size.
+type FittsPath struct {
+	// A is the fixed part of the movement time. Defaults to 100ms.
+	A time.Duration
+
+	// B is the part of the movement time per bit of difficulty. Defaults
+	// to 150ms.
+	B time.Duration
+
+	// Overshoot is the maximum overshoot relative to the distance. Defaults
+	// to 0.08.
+	Overshoot float64
+
+	// MinOvershootDistance is the distance below which movements do not
+	// overshoot. Defaults to 100 pixels.
+	MinOvershootDistance float64
+}
+
+// Path implements PathGenerator.
+func (f FittsPath) Path(req PathRequest) []PathPoint {
+	a, b, overshoot, minDistance := f.A, f.B, f.Overshoot, f.MinOvershootDistance
+	if a == 0 {
+		a = 100 * time.Millisecond
+	}
+
+	if b == 0 {
+		b = 150 * time.Millisecond
+	}
+
+	if overshoot == 0 {
+		overshoot = 0.08
+	}
+
+	if minDistance == 0 {
+		minDistance = 100
+	}
+
+	rnd := req.Rand
+	dx, dy := req.To.X-req.From.X, req.To.Y-req.From.Y
+	distance := math.Hypot(dx, dy)
+	duration := fittsDuration(a, b, distance, req.TargetWidth)
+
+	if distance < minDistance {
+		return timedArc(req.From, req.To, duration, pathSteps(req, duration), rnd)
+	}
+
+	// Overshoot along the movement, slightly off to the side.
+	ratio := overshoot * (0.3 + 0.7*rnd.Float64())
+	lateral := (rnd.Float64() - 0.5) * ratio
+	over := Point{
+		X: req.To.X + dx*ratio - dy*lateral,
+		Y: req.To.Y + dy*ratio + dx*lateral,
+	}
+
+	// The primary movement covers most of the time, the correction is a
+	// small movement that follows after a short pause.
+	primary := duration * 8 / 10
+	correction := duration - primary
+
+	primarySteps, correctionSteps := pathSteps(req, primary), pathSteps(req, correction)
+	if req.Steps > 0 {
+		primarySteps = int(math.Max(1, float64(req.Steps)*0.8))
+		correctionSteps = int(math.Max(1, float64(req.Steps-primarySteps)))
+	}
+
+	path := timedArc(req.From, over, primary, primarySteps, rnd)
+	back := timedArc(over, req.To, correction, correctionSteps, rnd)
+	back[0].Delay += time.Duration(50+rnd.Intn(100)) * time.Millisecond
+
+	return append(path, back...)
+}
+
+// timedArc moves from a to b along a slight arc in the number of steps, with a
+// minimum jerk speed profile over the duration.
+func timedArc(a, b Point, duration time.Duration, steps int, rnd *rand.Rand) []PathPoint {
+	c1, c2 := bezierControls(a, b, 0.1, rnd)
+
+	points := make([]PathPoint, 0, steps)
+
+	for i := 1; i <= steps; i++ {
+		points = append(points, PathPoint{
+			Point: bezierCubic(a, c1, c2, b, minimumJerk(float64(i)/float64(steps))),
+			Delay: duration / time.Duration(steps),
+		})
+	}
+
+	points[len(points)-1].Point = b
+
+	return points
+}
+
+// clampPoint keeps the point within a viewport of the size.
+func clampPoint(p Point, width, height float64) Point {
+	return Point{
+		X: math.Max(0, math.Min(p.X, width-1)),
+		Y: math.Max(0, math.Min(p.Y, height-1)),
+	}
+}
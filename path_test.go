@@ -0,0 +1,127 @@
+package chromedpundetected
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func pathDuration(path []PathPoint) time.Duration {
+	var d time.Duration
+	for _, p := range path {
+		d += p.Delay
+	}
+
+	return d
+}
+
+// maxDeviation returns the largest distance of a path point from the line
+// through a and b.
+func maxDeviation(path []PathPoint, a, b Point) float64 {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	length := math.Hypot(dx, dy)
+
+	var deviation float64
+	for _, p := range path {
+		d := math.Abs(dy*(p.X-a.X)-dx*(p.Y-a.Y)) / length
+		deviation = math.Max(deviation, d)
+	}
+
+	return deviation
+}
+
+func TestPathGenerators(t *testing.T) {
+	generators := map[string]PathGenerator{
+		"bezier":    BezierPath{},
+		"windmouse": WindMousePath{},
+		"fitts":     FittsPath{},
+	}
+
+	// All directions, including those where the old control points collapsed.
+	targets := []Point{{800, 600}, {100, 600}, {800, 50}, {100, 50}, {500, 320}, {450, 300}, {450, 320}}
+	from := Point{X: 450, Y: 320}
+
+	for name, g := range generators {
+		for seed := int64(0); seed < 20; seed++ {
+			for _, to := range targets {
+				req := PathRequest{From: from, To: to, Rand: rand.New(rand.NewSource(seed))} //nolint:gosec
+
+				path := g.Path(req)
+				require.NotEmpty(t, path, name)
+				require.Equal(t, to, path[len(path)-1].Point, name)
+
+				distance := math.Hypot(to.X-from.X, to.Y-from.Y)
+				if distance > 0 {
+					require.LessOrEqual(t, maxDeviation(path, from, to), 0.5*distance+10, "%s to %v", name, to)
+				}
+
+				for _, p := range path {
+					require.False(t, math.IsNaN(p.X) || math.IsNaN(p.Y), name)
+					require.GreaterOrEqual(t, p.Delay, time.Duration(0), name)
+				}
+			}
+		}
+	}
+}
+
+func TestPathDuration(t *testing.T) {
+	for _, g := range []PathGenerator{BezierPath{}, WindMousePath{}, FittsPath{}} {
+		path := func(to Point, width float64) []PathPoint {
+			return g.Path(PathRequest{To: to, TargetWidth: width, Rand: rand.New(rand.NewSource(1))}) //nolint:gosec
+		}
+
+		near := pathDuration(path(Point{X: 50}, 20))
+		far := pathDuration(path(Point{X: 1000}, 20))
+		small := pathDuration(path(Point{X: 1000}, 5))
+
+		require.Greater(t, far, near)
+		require.Greater(t, small, far)
+	}
+}
+
+func TestPathSteps(t *testing.T) {
+	req := PathRequest{To: Point{X: 300, Y: 300}, Steps: 7, Rand: rand.New(rand.NewSource(1))} //nolint:gosec
+
+	require.Len(t, BezierPath{}.Path(req), 7)
+	require.Len(t, WindMousePath{}.Path(req), 7)
+
+	auto := BezierPath{}.Path(PathRequest{To: Point{X: 300, Y: 300}, Rand: req.Rand})
+	require.Greater(t, len(auto), 10)
+}
+
+func TestFittsOvershoot(t *testing.T) {
+	from, to := Point{X: 100, Y: 100}, Point{X: 900, Y: 100}
+	overshot := 0
+
+	for seed := int64(0); seed < 10; seed++ {
+		path := FittsPath{}.Path(PathRequest{From: from, To: to, Rand: rand.New(rand.NewSource(seed))}) //nolint:gosec
+
+		for _, p := range path {
+			if p.X > to.X+1 {
+				overshot++
+				break
+			}
+		}
+	}
+
+	require.Equal(t, 10, overshot)
+
+	// Short movements do not overshoot.
+	path := FittsPath{}.Path(PathRequest{From: from, To: Point{X: 150, Y: 100}, Rand: rand.New(rand.NewSource(1))}) //nolint:gosec
+	for _, p := range path {
+		require.LessOrEqual(t, p.X, 151.0)
+	}
+}
+
+func TestResampleClamp(t *testing.T) {
+	points := []Point{{X: 1}, {X: 2}, {X: 3}, {X: 4}, {X: 5}, {X: 6}}
+	require.Equal(t, []Point{{X: 3}, {X: 6}}, resample(points, 2))
+	require.Equal(t, points, resample(points, 0))
+	require.Equal(t, points, resample(points, 10))
+
+	require.Equal(t, Point{X: 0, Y: 599}, clampPoint(Point{X: -10, Y: 700}, 800, 600))
+	require.Equal(t, Point{X: 10, Y: 20}, clampPoint(Point{X: 10, Y: 20}, 800, 600))
+}
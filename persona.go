@@ -0,0 +1,489 @@
+package chromedpundetected
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+	"github.com/hashicorp/go-multierror"
+)
+
+// Errors.
+var (
+	ErrInvalidPersona = errors.New("invalid persona")
+)
+
+// Persona describes a single believable device. When set in the config, it is
+// applied through the launch flags, the user agent override, emulation
+// commands and injected scripts, so every surface reports the same device.
+type Persona struct {
+	// UserAgent is the user agent string.
+	UserAgent string `json:"userAgent" yaml:"userAgent"`
+
+	// ClientHints is the User-Agent Client Hints metadata, reported through
+	// navigator.userAgentData and the Sec-CH-UA headers.
+	ClientHints *emulation.UserAgentMetadata `json:"clientHints,omitempty" yaml:"clientHints,omitempty"`
+
+	// Platform is the value of navigator.platform, e.g. "Win32".
+	Platform string `json:"platform" yaml:"platform"`
+
+	// Languages are the preferred languages, most preferred first, optionally
+	// weighted, e.g. ["en-US", "en;q=0.9"]. Used unless the config sets
+	// languages itself.
+	Languages []string `json:"languages" yaml:"languages"`
+
+	// Timezone is the IANA timezone, e.g. "Europe/Amsterdam".
+	Timezone string `json:"timezone" yaml:"timezone"`
+
+	// Screen is the screen geometry.
+	Screen Screen `json:"screen" yaml:"screen"`
+
+	// Window is the size of the outer browser window.
+	Window WindowSize `json:"window" yaml:"window"`
+
+	// Viewport is the size of the layout viewport in CSS pixels, e.g. of the
+	// browser of a phone. By default the window size decides.
+	Viewport *WindowSize `json:"viewport,omitempty" yaml:"viewport,omitempty"`
+
+	// MaxTouchPoints is the number of touches the touch screen supports, as
+	// reported by navigator.maxTouchPoints. Touch events are emulated if
+	// positive, see Tap and Swipe.
+	MaxTouchPoints int `json:"maxTouchPoints,omitempty" yaml:"maxTouchPoints,omitempty"`
+
+	// DeviceMemory is the value of navigator.deviceMemory in GiB, one of
+	// 0.25, 0.5, 1, 2, 4 or 8.
+	DeviceMemory float64 `json:"deviceMemory" yaml:"deviceMemory"`
+
+	// HardwareConcurrency is the number of logical CPU cores.
+	HardwareConcurrency int `json:"hardwareConcurrency" yaml:"hardwareConcurrency"`
+
+	// WebGLVendor and WebGLRenderer are the unmasked WebGL vendor and renderer.
+	WebGLVendor   string `json:"webglVendor" yaml:"webglVendor"`
+	WebGLRenderer string `json:"webglRenderer" yaml:"webglRenderer"`
+
+	// Fonts are the font families reported as available by
+	// document.fonts.check. If empty, fonts are not patched.
+	Fonts []string `json:"fonts,omitempty" yaml:"fonts,omitempty"`
+}
+
+// Screen is the geometry of a screen.
+type Screen struct {
+	Width            int     `json:"width" yaml:"width"`
+	Height           int     `json:"height" yaml:"height"`
+	AvailWidth       int     `json:"availWidth" yaml:"availWidth"`
+	AvailHeight      int     `json:"availHeight" yaml:"availHeight"`
+	ColorDepth       int     `json:"colorDepth" yaml:"colorDepth"`
+	DevicePixelRatio float64 `json:"devicePixelRatio" yaml:"devicePixelRatio"`
+}
+
+// WindowSize is the size of a window.
+type WindowSize struct {
+	Width  int `json:"width" yaml:"width"`
+	Height int `json:"height" yaml:"height"`
+}
+
+// LoadPersona reads a persona from a JSON file and validates it.
+func LoadPersona(path string) (Persona, error) {
+	var p Persona
+
+	b, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return p, fmt.Errorf("failed to open file '%s': %w", path, err)
+	}
+
+	if err := json.Unmarshal(b, &p); err != nil {
+		return p, fmt.Errorf("unmarshal persona from json: %w", err)
+	}
+
+	if err := p.Validate(); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
+
+// MobilePersona returns the persona of a Pixel 7 phone running Chrome for
+// Android of the full version, e.g. "116.0.5845.188", which should be the
+// version of the browser it is used with. Touch is emulated, and the viewport,
+// device pixel ratio and client hints are those of the phone.
+func MobilePersona(chromeVersion string) Persona {
+	major := chromeVersion
+	if i := strings.Index(chromeVersion, "."); i >= 0 {
+		major = chromeVersion[:i]
+	}
+
+	userAgent := "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/" +
+		major + ".0.0.0 Mobile Safari/537.36"
+
+	hints := userAgentMetadata(userAgent, chromeVersion)
+	hints.PlatformVersion = "14.0.0"
+	hints.Model = "Pixel 7"
+
+	return Persona{
+		UserAgent:   userAgent,
+		ClientHints: hints,
+		Platform:    "Linux armv8l",
+		Languages:   []string{"en-US", "en;q=0.9"},
+		Screen: Screen{
+			Width:            412,
+			Height:           915,
+			AvailWidth:       412,
+			AvailHeight:      915,
+			ColorDepth:       24,
+			DevicePixelRatio: 2.625,
+		},
+		Window:              WindowSize{Width: 412, Height: 915},
+		Viewport:            &WindowSize{Width: 412, Height: 839},
+		MaxTouchPoints:      5,
+		DeviceMemory:        8,
+		HardwareConcurrency: 8,
+		WebGLVendor:         "Qualcomm",
+		WebGLRenderer:       "Adreno (TM) 730",
+	}
+}
+
+var chromeVersionRegex = regexp.MustCompile(`Chrome/(\d+)\.`)
+
+// personaOS is an operating system, with the values it reports.
+type personaOS struct {
+	name           string
+	uaMarker       string
+	platformPrefix string
+	hintsPlatform  []string
+}
+
+// personaOSes are the supported operating systems, in order of detection from
+// the user agent.
+var personaOSes = []personaOS{
+	{name: "Android", uaMarker: "Android", platformPrefix: "Linux", hintsPlatform: []string{"Android"}},
+	{name: "ChromeOS", uaMarker: "CrOS", platformPrefix: "Linux", hintsPlatform: []string{"Chrome OS", "ChromeOS"}},
+	{name: "Windows", uaMarker: "Windows NT", platformPrefix: "Win", hintsPlatform: []string{"Windows"}},
+	{name: "macOS", uaMarker: "Macintosh", platformPrefix: "Mac", hintsPlatform: []string{"macOS"}},
+	{name: "Linux", uaMarker: "Linux", platformPrefix: "Linux", hintsPlatform: []string{"Linux"}},
+}
+
+// Validate checks that the persona is complete and internally consistent.
+func (p Persona) Validate() error { //nolint:gocognit,cyclop,funlen
+	var merr *multierror.Error
+
+	fail := func(format string, args ...any) {
+		merr = multierror.Append(merr, fmt.Errorf(format, args...))
+	}
+
+	if p.UserAgent == "" {
+		fail("user agent is empty")
+	}
+
+	var ros *personaOS
+
+	for i := range personaOSes {
+		if strings.Contains(p.UserAgent, personaOSes[i].uaMarker) {
+			ros = &personaOSes[i]
+			break
+		}
+	}
+
+	if ros == nil {
+		fail("unable to detect the operating system of user agent %q", p.UserAgent)
+	} else if !strings.HasPrefix(p.Platform, ros.platformPrefix) {
+		fail("platform %q does not match %s user agent", p.Platform, ros.name)
+	}
+
+	if strings.Contains(p.UserAgent, "Headless") {
+		fail("user agent contains a headless marker")
+	}
+
+	mobile := strings.Contains(p.UserAgent, " Mobile")
+
+	if hints := p.ClientHints; hints != nil {
+		if ros != nil && !contains(ros.hintsPlatform, hints.Platform) {
+			fail("client hints platform %q does not match %s user agent", hints.Platform, ros.name)
+		}
+
+		if hints.Mobile != mobile {
+			fail("client hints mobile is %t, user agent mobile is %t", hints.Mobile, mobile)
+		}
+
+		if m := chromeVersionRegex.FindStringSubmatch(p.UserAgent); m != nil {
+			if !hasBrandVersion(hints.Brands, m[1], false) {
+				fail("client hints brands do not contain Chrome version %s", m[1])
+			}
+
+			if len(hints.FullVersionList) > 0 && !hasBrandVersion(hints.FullVersionList, m[1], true) {
+				fail("client hints full version list does not contain Chrome version %s", m[1])
+			}
+		}
+	}
+
+	if _, err := parseLanguages(p.Languages); err != nil {
+		fail("%w", err)
+	}
+
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			fail("invalid timezone %q: %w", p.Timezone, err)
+		}
+	}
+
+	s := p.Screen
+	switch {
+	case s.Width <= 0 || s.Height <= 0:
+		fail("screen size must be positive")
+	case s.AvailWidth > s.Width || s.AvailHeight > s.Height:
+		fail("available screen size %dx%d exceeds screen size %dx%d", s.AvailWidth, s.AvailHeight, s.Width, s.Height)
+	case p.Window.Width > s.Width || p.Window.Height > s.Height:
+		fail("window size %dx%d exceeds screen size %dx%d", p.Window.Width, p.Window.Height, s.Width, s.Height)
+	}
+
+	if p.Window.Width <= 0 || p.Window.Height <= 0 {
+		fail("window size must be positive")
+	}
+
+	if v := p.Viewport; v != nil {
+		if v.Width <= 0 || v.Height <= 0 {
+			fail("viewport size must be positive")
+		} else if v.Width > s.Width || v.Height > s.Height {
+			fail("viewport size %dx%d exceeds screen size %dx%d", v.Width, v.Height, s.Width, s.Height)
+		}
+	}
+
+	if p.MaxTouchPoints < 0 {
+		fail("max touch points must not be negative")
+	} else if mobile && p.MaxTouchPoints == 0 {
+		fail("mobile user agent without touch points")
+	}
+
+	if s.DevicePixelRatio < 0 {
+		fail("device pixel ratio must not be negative")
+	}
+
+	switch p.DeviceMemory {
+	case 0, 0.25, 0.5, 1, 2, 4, 8:
+	default:
+		fail("device memory %v is not reported by browsers, use 0.25, 0.5, 1, 2, 4 or 8", p.DeviceMemory)
+	}
+
+	if p.HardwareConcurrency < 0 {
+		fail("hardware concurrency must not be negative")
+	}
+
+	if ros != nil && p.WebGLRenderer != "" {
+		renderer := p.WebGLRenderer
+		if (strings.Contains(renderer, "Direct3D") || strings.Contains(renderer, "D3D11")) && ros.name != "Windows" {
+			fail("Direct3D WebGL renderer on %s", ros.name)
+		}
+
+		if (strings.Contains(renderer, "Apple") || strings.Contains(renderer, "Metal")) && ros.name != "macOS" {
+			fail("Apple WebGL renderer on %s", ros.name)
+		}
+	}
+
+	if err := merr.ErrorOrNil(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidPersona, err.Error())
+	}
+
+	return nil
+}
+
+func hasBrandVersion(brands []*emulation.UserAgentBrandVersion, major string, full bool) bool {
+	for _, b := range brands {
+		if b == nil || !strings.Contains(b.Brand, "Chrom") {
+			continue
+		}
+
+		if b.Version == major || (full && strings.HasPrefix(b.Version, major+".")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// personaFlags returns the launch flags for the persona of the config.
+func personaFlags(config Config) []chromedp.ExecAllocatorOption {
+	p := config.Persona
+	if p == nil {
+		return nil
+	}
+
+	return []chromedp.ExecAllocatorOption{
+		chromedp.UserAgent(p.UserAgent),
+		chromedp.WindowSize(p.Window.Width, p.Window.Height),
+	}
+}
+
+// personaSetup returns the per tab actions that apply the persona of the
+// config.
+func personaSetup(config Config, langs []weightedLanguage) []chromedp.Action {
+	p := config.Persona
+	if p == nil {
+		return nil
+	}
+
+	actions := []chromedp.Action{
+		UserAgentOverride(p.UserAgent,
+			WithUAPlatform(p.Platform),
+			WithUAAcceptLanguage(acceptLanguage(langs)),
+			WithUAMetadata(p.ClientHints),
+		),
+		injectScript(screenMetricsScript(p.Screen)),
+		injectScript(personaScript(*p)),
+	}
+
+	if p.HardwareConcurrency > 0 {
+		actions = append(actions, emulation.SetHardwareConcurrencyOverride(int64(p.HardwareConcurrency)))
+	}
+
+	if p.MaxTouchPoints > 0 {
+		actions = append(actions, emulation.SetTouchEmulationEnabled(true).WithMaxTouchPoints(int64(p.MaxTouchPoints)))
+	}
+
+	if p.Screen.DevicePixelRatio > 0 || p.Viewport != nil {
+		var width, height int64
+		if v := p.Viewport; v != nil {
+			width, height = int64(v.Width), int64(v.Height)
+		}
+
+		mobile := p.ClientHints != nil && p.ClientHints.Mobile
+		actions = append(actions, emulation.SetDeviceMetricsOverride(width, height, p.Screen.DevicePixelRatio, mobile).
+			WithScreenWidth(int64(p.Screen.Width)).
+			WithScreenHeight(int64(p.Screen.Height)))
+	}
+
+	return actions
+}
+
+// evasionParams returns the values reported by the evasions, taken from the
+// persona if set.
+func (c Config) evasionParams() evasionParams {
+	params := defaultEvasionParams
+
+	if p := c.Persona; p != nil {
+		if p.HardwareConcurrency > 0 {
+			params.HardwareConcurrency = p.HardwareConcurrency
+		}
+
+		if p.WebGLVendor != "" {
+			params.WebGLVendor = p.WebGLVendor
+		}
+
+		if p.WebGLRenderer != "" {
+			params.WebGLRenderer = p.WebGLRenderer
+		}
+	}
+
+	return params
+}
+
+// windowSize returns the size of the browser window.
+func (c Config) windowSize() WindowSize {
+	if c.Persona != nil {
+		return c.Persona.Window
+	}
+
+	return WindowSize{Width: DefaultWindowWidth, Height: DefaultWindowHeight}
+}
+
+// screen returns the screen geometry.
+func (c Config) screen() Screen {
+	if c.Persona != nil {
+		return c.Persona.Screen
+	}
+
+	return Screen{
+		Width:       DefaultWindowWidth,
+		Height:      DefaultWindowHeight,
+		AvailWidth:  DefaultWindowWidth,
+		AvailHeight: DefaultWindowHeight - 40,
+		ColorDepth:  24,
+	}
+}
+
+// personaScript returns the script that reports the navigator values and
+// fonts of the persona. The languages are set by the locale setup.
+func personaScript(p Persona) string {
+	script, _ := wrapEvasions(map[string]any{ //nolint:errcheck
+		"platform":     p.Platform,
+		"deviceMemory": p.DeviceMemory,
+		"fonts":        p.Fonts,
+	}, personaJS)
+
+	return script
+}
+
+// screenMetricsScript returns the script that reports the screen geometry.
+func screenMetricsScript(s Screen) string {
+	availWidth, availHeight := s.AvailWidth, s.AvailHeight
+	if availWidth == 0 || availHeight == 0 {
+		availWidth, availHeight = s.Width, s.Height
+	}
+
+	colorDepth := s.ColorDepth
+	if colorDepth == 0 {
+		colorDepth = 24
+	}
+
+	script, _ := wrapEvasions(map[string]int{ //nolint:errcheck
+		"width":       s.Width,
+		"height":      s.Height,
+		"availWidth":  availWidth,
+		"availHeight": availHeight,
+		"colorDepth":  colorDepth,
+		"pixelDepth":  colorDepth,
+	}, screenMetricsJS)
+
+	return script
+}
+
+var (
+	// screenMetricsJS reports the screen geometry.
+	screenMetricsJS = `
+    (win, screen, utils) => {
+      for (const [prop, value] of Object.entries(screen)) {
+        utils.getter(win.Screen.prototype, prop, () => value);
+      }
+    }`
+
+	// personaJS reports the navigator values and fonts of a persona.
+	personaJS = `
+    (win, persona, utils) => {
+      if (persona.platform) {
+        utils.getter(win.Navigator.prototype, 'platform', () => persona.platform);
+      }
+      if (persona.deviceMemory) {
+        utils.getter(win.Navigator.prototype, 'deviceMemory', () => persona.deviceMemory);
+      }
+
+      if (persona.fonts && persona.fonts.length && win.FontFaceSet) {
+        const generic = ['serif', 'sans-serif', 'monospace', 'cursive', 'fantasy', 'system-ui'];
+        const allowed = new Set(persona.fonts.concat(generic).map((f) => f.toLowerCase()));
+        const origCheck = win.FontFaceSet.prototype.check;
+
+        win.FontFaceSet.prototype.check = utils.native(function check(font, text) {
+          const families = String(font).replace(/^.*?\d+(\.\d+)?(px|pt|em|rem|%)\s*(\/\s*\S+\s*)?/, '').split(',');
+          for (const family of families) {
+            if (!allowed.has(family.trim().replace(/^["']|["']$/g, '').toLowerCase())) {
+              return false;
+            }
+          }
+          return origCheck.call(this, font, text);
+        }, 'check');
+      }
+    }`
+)
@@ -0,0 +1,95 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersonaValidate(t *testing.T) {
+	p, err := LoadPersona("testdata/persona-windows.json")
+	require.NoError(t, err)
+
+	linux := p
+	linux.Platform = "Linux x86_64"
+	require.ErrorIs(t, linux.Validate(), ErrInvalidPersona)
+
+	hints := *p.ClientHints
+	hints.Platform = "macOS"
+	mac := p
+	mac.ClientHints = &hints
+	require.ErrorIs(t, mac.Validate(), ErrInvalidPersona)
+
+	window := p
+	window.Window.Width = 2560
+	require.ErrorIs(t, window.Validate(), ErrInvalidPersona)
+
+	viewport := p
+	viewport.Viewport = &WindowSize{Width: 800, Height: 2000}
+	require.ErrorIs(t, viewport.Validate(), ErrInvalidPersona)
+
+	memory := p
+	memory.DeviceMemory = 16
+	require.ErrorIs(t, memory.Validate(), ErrInvalidPersona)
+
+	renderer := p
+	renderer.UserAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.0.0 Safari/537.36"
+	renderer.Platform = "Linux x86_64"
+	renderer.ClientHints = nil
+	require.ErrorIs(t, renderer.Validate(), ErrInvalidPersona)
+}
+
+func TestPersona(t *testing.T) {
+	p, err := LoadPersona("testdata/persona-windows.json")
+	require.NoError(t, err)
+
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(20*time.Second),
+			WithHeadless(),
+			WithPersona(p),
+		),
+		func(ctx context.Context) error {
+			var res struct {
+				UserAgent string `json:"userAgent"`
+				Platform  string `json:"platform"`
+				Hints     string `json:"hints"`
+				Timezone  string `json:"timezone"`
+				Screen    int    `json:"screen"`
+				Cores     int    `json:"cores"`
+				Native    bool   `json:"native"`
+			}
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate("https://www.example.com/"),
+				chromedp.Evaluate(`({
+					userAgent: navigator.userAgent,
+					platform: navigator.platform,
+					hints: navigator.userAgentData.platform,
+					timezone: Intl.DateTimeFormat().resolvedOptions().timeZone,
+					screen: screen.width,
+					cores: navigator.hardwareConcurrency,
+					native: [
+						Object.getOwnPropertyDescriptor(Navigator.prototype, 'platform').get,
+						Object.getOwnPropertyDescriptor(Screen.prototype, 'width').get,
+						FontFaceSet.prototype.check,
+					].every((fn) => /\{ \[native code\] \}$/.test(fn.toString())),
+				})`, &res),
+			); err != nil {
+				return err
+			}
+
+			if res.UserAgent != p.UserAgent || res.Platform != p.Platform || res.Hints != "Windows" ||
+				res.Timezone != p.Timezone || res.Screen != p.Screen.Width || res.Cores != p.HardwareConcurrency || !res.Native {
+				return fmt.Errorf("persona not applied: %+v", res)
+			}
+
+			return nil
+		},
+	)
+}
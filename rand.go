@@ -0,0 +1,60 @@
+package chromedpundetected
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// randKey is the context key of the random source of humanized actions.
+type randKey struct{}
+
+// lockedSource is a random source that is safe for concurrent use.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.src.Seed(seed)
+}
+
+// ContextWithRandomSeed returns a context whose humanized actions, e.g.
+// MoveMouseToPosition, Click, Scroll and Type, draw from a random source
+// seeded with the seed, so a run can be repeated exactly. Actions run in
+// contexts derived from it share the source, give concurrent tabs a source of
+// their own to keep them reproducible. See also WithRandomSeed.
+func ContextWithRandomSeed(ctx context.Context, seed int64) context.Context {
+	src := &lockedSource{src: rand.NewSource(seed).(rand.Source64)} //nolint:forcetypeassert,gosec
+
+	return context.WithValue(ctx, randKey{}, src)
+}
+
+// randFor returns the source of randomness for a humanized action in the
+// context. Without a seeded source every action gets its own, seeded with
+// the time.
+func randFor(ctx context.Context) *rand.Rand {
+	if src, ok := ctx.Value(randKey{}).(*lockedSource); ok {
+		return rand.New(src) //nolint:gosec
+	}
+
+	return rand.New(rand.NewSource(time.Now().UnixNano())) //nolint:gosec
+}
@@ -0,0 +1,62 @@
+package chromedpundetected
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandFor(t *testing.T) {
+	text := []rune("seeded runs type the same typos")
+
+	plan := func(ctx context.Context) ([]PathPoint, []keystroke) {
+		path := BezierPath{}.Path(PathRequest{From: Point{X: 10, Y: 10}, To: Point{X: 600, Y: 400}, Rand: randFor(ctx)})
+
+		options := defaultTypeOptions
+		options.typos = 0.2
+
+		return path, typingPlan(text, options, randFor(ctx))
+	}
+
+	path1, strokes1 := plan(ContextWithRandomSeed(context.Background(), 42))
+	path2, strokes2 := plan(ContextWithRandomSeed(context.Background(), 42))
+	require.Equal(t, path1, path2)
+	require.Equal(t, strokes1, strokes2)
+
+	path3, _ := plan(ContextWithRandomSeed(context.Background(), 43))
+	require.NotEqual(t, path1, path3)
+
+	// Actions draw from the same source in turn.
+	ctx := ContextWithRandomSeed(context.Background(), 42)
+	first := randFor(ctx).Int63()
+	require.NotEqual(t, first, randFor(ctx).Int63())
+
+	// Without a seed every action gets a source of its own.
+	require.NotNil(t, randFor(context.Background()))
+
+	cfg := NewConfig(WithRandomSeed(7))
+	require.Equal(t, int64(7), *cfg.RandomSeed)
+}
+
+func TestRandForConcurrent(t *testing.T) {
+	ctx := ContextWithRandomSeed(context.Background(), 1)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			rnd := randFor(ctx)
+			for j := 0; j < 1000; j++ {
+				rnd.Float64()
+			}
+		}()
+	}
+
+	wg.Wait()
+}
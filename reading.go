@@ -0,0 +1,452 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+)
+
+// ReadOptions contains options for simulated reading.
+type ReadOptions struct {
+	wpm         float64
+	duration    time.Duration
+	minDuration time.Duration
+	maxDuration time.Duration
+	selectText  bool
+	move        []MoveOptionSetter
+	scroll      []ScrollOptionSetter
+}
+
+// Default values for reading.
+var defaultReadOptions = ReadOptions{
+	wpm:         230,
+	minDuration: 2 * time.Second,
+	maxDuration: time.Minute,
+	selectText:  true,
+}
+
+// ReadOptionSetter defines a function type to set reading options.
+type ReadOptionSetter func(*ReadOptions)
+
+// WithReadingSpeed returns a ReadOptionSetter that sets the reading speed in
+// words per minute, which the reading time follows from. Defaults to 230.
+func WithReadingSpeed(wpm float64) ReadOptionSetter {
+	return func(opt *ReadOptions) {
+		opt.wpm = wpm
+	}
+}
+
+// WithReadDuration returns a ReadOptionSetter that sets the reading time,
+// instead of deriving it from the text of the page.
+func WithReadDuration(d time.Duration) ReadOptionSetter {
+	return func(opt *ReadOptions) {
+		opt.duration = d
+	}
+}
+
+// WithReadDurationRange returns a ReadOptionSetter that limits the reading
+// time derived from the text of the page. Defaults to 2s to 1m.
+func WithReadDurationRange(min, max time.Duration) ReadOptionSetter {
+	return func(opt *ReadOptions) {
+		opt.minDuration = min
+		opt.maxDuration = max
+	}
+}
+
+// WithReadTextSelection returns a ReadOptionSetter that sets whether the
+// reader now and then selects a piece of text with the mouse. Enabled by
+// default.
+func WithReadTextSelection(enabled bool) ReadOptionSetter {
+	return func(opt *ReadOptions) {
+		opt.selectText = enabled
+	}
+}
+
+// WithReadMoveOptions returns a ReadOptionSetter that sets the options of the
+// mouse movements, e.g. the path generator.
+func WithReadMoveOptions(setters ...MoveOptionSetter) ReadOptionSetter {
+	return func(opt *ReadOptions) {
+		opt.move = append(opt.move, setters...)
+	}
+}
+
+// WithReadScrollOptions returns a ReadOptionSetter that sets the options of
+// scrolling.
+func WithReadScrollOptions(setters ...ScrollOptionSetter) ReadOptionSetter {
+	return func(opt *ReadOptions) {
+		opt.scroll = append(opt.scroll, setters...)
+	}
+}
+
+// ReadPage simulates a person reading the page, for as long as reading the
+// visible text of the page takes at the reading speed of the options.
+//
+// Most of the time the reader sits still, now and then the cursor drifts a
+// little, the page is scrolled further, the cursor rests on a link or a
+// piece of text is selected, all with the humanized mouse and wheel actions.
+func ReadPage(setters ...ReadOptionSetter) chromedp.ActionFunc {
+	options := defaultReadOptions
+
+	for _, setter := range setters {
+		setter(&options)
+	}
+
+	return func(ctx context.Context) error {
+		d := options.duration
+		if d == 0 {
+			var words int
+			if err := EvaluateIsolated(pageWordsJS, &words).Do(ctx); err != nil {
+				return fmt.Errorf("count words: %w", err)
+			}
+
+			d = readingTime(words, options)
+		}
+
+		return newReader(ctx, options).read(ctx, time.Now().Add(d), nil)
+	}
+}
+
+// IdleInBackground simulates a person reading the page like ReadPage does,
+// in the background until stop is called, e.g. while waiting for the network
+// to become idle with NetworkIdleListener. No other actions should run in the
+// tab until then.
+//
+// Stop waits for the current movement to finish, and returns the error that
+// ended the simulation early, if any.
+func IdleInBackground(ctx context.Context, setters ...ReadOptionSetter) (stop func() error) {
+	options := defaultReadOptions
+
+	for _, setter := range setters {
+		setter(&options)
+	}
+
+	stopped := make(chan struct{})
+	done := make(chan error, 1)
+
+	go func() {
+		done <- chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			return newReader(ctx, options).read(ctx, time.Time{}, stopped)
+		}))
+	}()
+
+	var once sync.Once
+
+	return func() error {
+		once.Do(func() { close(stopped) })
+
+		err := <-done
+		done <- err
+
+		return err
+	}
+}
+
+// pageWordsJS counts the words of the rendered text of the page.
+const pageWordsJS = `(document.body ? document.body.innerText : '').split(/\s+/).filter(Boolean).length`
+
+// readingTime returns the time reading the number of words takes.
+func readingTime(words int, options ReadOptions) time.Duration {
+	d := time.Duration(float64(words) / options.wpm * float64(time.Minute))
+
+	if d < options.minDuration {
+		return options.minDuration
+	}
+
+	if options.maxDuration > 0 && d > options.maxDuration {
+		return options.maxDuration
+	}
+
+	return d
+}
+
+// readingViewJS describes what the reader can interact with in the viewport:
+// links to hover and lines of plain text to select.
+const readingViewJS = `(() => {
+	const vw = window.innerWidth, vh = window.innerHeight;
+	const inside = (r) => r.width > 0 && r.height > 0 && r.top >= 0 && r.left >= 0 && r.bottom <= vh && r.right <= vw;
+	const box = (r) => ({ x: r.left, y: r.top, width: r.width, height: r.height });
+
+	const links = [];
+	for (const a of document.querySelectorAll('a[href]')) {
+		const r = a.getBoundingClientRect();
+		if (inside(r)) {
+			links.push(box(r));
+		}
+		if (links.length >= 20) {
+			break;
+		}
+	}
+
+	const lines = [];
+	const root = document.body || document.documentElement;
+	const walker = document.createTreeWalker(root, NodeFilter.SHOW_TEXT);
+	const range = document.createRange();
+	const interactive = 'a, button, input, textarea, select, label, summary, [contenteditable], [onclick], [role=button], [role=link], [draggable=true]';
+
+	while (lines.length < 40 && walker.nextNode()) {
+		const node = walker.currentNode;
+		const parent = node.parentElement;
+		if (node.textContent.trim().length < 20 || !parent || parent.closest(interactive)) {
+			continue;
+		}
+
+		range.selectNodeContents(node);
+		for (const r of range.getClientRects()) {
+			if (r.width >= 40 && inside(r)) {
+				lines.push(box(r));
+			}
+		}
+	}
+
+	const scroller = document.scrollingElement || document.documentElement;
+
+	return {
+		links: links,
+		lines: lines,
+		scrollTop: scroller.scrollTop,
+		scrollMax: scroller.scrollHeight - scroller.clientHeight,
+	};
+})()`
+
+// readingView is what the reader can interact with in the viewport.
+type readingView struct {
+	Links     []Box   `json:"links"`
+	Lines     []Box   `json:"lines"`
+	ScrollTop float64 `json:"scrollTop"`
+	ScrollMax float64 `json:"scrollMax"`
+}
+
+// reader simulates a person reading a page.
+type reader struct {
+	options ReadOptions
+	move    MouseMoveOptions
+	scroll  ScrollOptions
+	rnd     *rand.Rand
+}
+
+func newReader(ctx context.Context, options ReadOptions) *reader {
+	r := &reader{
+		options: options,
+		move:    defaultMouseMoveOptions,
+		scroll:  defaultScrollOptions,
+		rnd:     randFor(ctx),
+	}
+
+	for _, setter := range options.move {
+		setter(&r.move)
+	}
+
+	r.scroll.move = options.move
+	for _, setter := range options.scroll {
+		setter(&r.scroll)
+	}
+
+	return r
+}
+
+// read rests and acts in turn until the deadline, or until stopped. A zero
+// deadline reads until stopped.
+func (r *reader) read(ctx context.Context, deadline time.Time, stop <-chan struct{}) error {
+	for {
+		rest := randDuration(r.rnd, 400*time.Millisecond, 2500*time.Millisecond)
+
+		if !deadline.IsZero() {
+			left := time.Until(deadline)
+			if left <= 0 {
+				return nil
+			}
+
+			if rest > left {
+				rest = left
+			}
+		}
+
+		if stopped, err := restUntil(ctx, rest, stop); stopped || err != nil {
+			return err
+		}
+
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return nil
+		}
+
+		var view readingView
+		if err := EvaluateIsolated(readingViewJS, &view).Do(ctx); err != nil {
+			return fmt.Errorf("reading view: %w", err)
+		}
+
+		if err := r.act(ctx, view); err != nil {
+			return err
+		}
+	}
+}
+
+// restUntil sleeps for the duration, and reports whether it was stopped
+// before.
+func restUntil(ctx context.Context, d time.Duration, stop <-chan struct{}) (bool, error) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-stop:
+		return true, nil
+	case <-t.C:
+		return false, nil
+	}
+}
+
+// readingAction is something the reader does between resting, with the
+// relative chance of doing it.
+type readingAction struct {
+	weight float64
+	run    func(context.Context, readingView) error
+}
+
+// act does one random thing the view allows.
+func (r *reader) act(ctx context.Context, view readingView) error {
+	actions := []readingAction{
+		{40, r.drift},
+		// Sit still and keep reading.
+		{12, func(context.Context, readingView) error { return nil }},
+	}
+
+	if view.ScrollMax-view.ScrollTop > 10 || view.ScrollTop > 0 {
+		actions = append(actions, readingAction{25, r.scrollOn})
+	}
+
+	if len(view.Links) > 0 {
+		actions = append(actions, readingAction{15, r.hoverLink})
+	}
+
+	if r.options.selectText && len(view.Lines) > 0 {
+		actions = append(actions, readingAction{8, r.selectText})
+	}
+
+	return pickReadingAction(actions, r.rnd).run(ctx, view)
+}
+
+// pickReadingAction picks a random action by weight.
+func pickReadingAction(actions []readingAction, rnd *rand.Rand) readingAction {
+	var total float64
+	for _, a := range actions {
+		total += a.weight
+	}
+
+	pick := rnd.Float64() * total
+	for _, a := range actions {
+		if pick < a.weight {
+			return a
+		}
+
+		pick -= a.weight
+	}
+
+	return actions[len(actions)-1]
+}
+
+// drift moves the cursor a little, like a hand resting on the mouse.
+func (r *reader) drift(ctx context.Context, _ readingView) error {
+	x, y := CursorPosition(ctx)
+
+	move := r.move
+	move.targetWidth = 100
+
+	return moveMouse(ctx, Point{X: x + r.rnd.NormFloat64()*30, Y: y + r.rnd.NormFloat64()*20}, move)
+}
+
+// scrollOn scrolls further down the page, or now and then back up a bit.
+func (r *reader) scrollOn(ctx context.Context, view readingView) error {
+	down := view.ScrollMax - view.ScrollTop
+
+	dy := math.Min(down, 150+r.rnd.Float64()*350)
+	if view.ScrollTop > 0 && (down <= 10 || r.rnd.Float64() < 0.15) {
+		dy = -math.Min(view.ScrollTop, 100+r.rnd.Float64()*200)
+	}
+
+	return wheel(ctx, 0, dy, r.scroll, r.rnd)
+}
+
+// hoverLink rests the cursor on a link for a moment.
+func (r *reader) hoverLink(ctx context.Context, view readingView) error {
+	if err := moveOnto(ctx, view.Links[r.rnd.Intn(len(view.Links))], r.scroll, r.rnd); err != nil {
+		return err
+	}
+
+	return sleepContext(ctx, randDuration(r.rnd, 300*time.Millisecond, 1200*time.Millisecond))
+}
+
+// selectText selects part of a line of text by dragging over it.
+func (r *reader) selectText(ctx context.Context, view readingView) (err error) {
+	line := view.Lines[r.rnd.Intn(len(view.Lines))]
+	y := line.Y + line.Height/2
+	from := Point{X: line.X + r.rnd.Float64()*line.Width*0.5, Y: y}
+	to := Point{X: math.Min(line.X+line.Width-1, from.X+(0.2+0.3*r.rnd.Float64())*line.Width), Y: y}
+
+	move := r.move
+	move.targetWidth = line.Height
+
+	if err := moveMouse(ctx, from, move); err != nil {
+		return err
+	}
+
+	if err := sleepContext(ctx, randDuration(r.rnd, 100*time.Millisecond, 300*time.Millisecond)); err != nil {
+		return err
+	}
+
+	press := input.DispatchMouseEvent(input.MousePressed, from.X, from.Y).
+		WithButton(input.Left).
+		WithButtons(buttonMask(input.Left)).
+		WithClickCount(1)
+
+	if err := DispatchMouseEvent(press).Do(ctx); err != nil {
+		return err
+	}
+
+	// Release the button where the cursor is when the selection fails halfway.
+	defer func() {
+		if err != nil {
+			x, y := cursorFor(ctx).get()
+			releaseLeft(ctx, Point{X: x, Y: y}) //nolint:errcheck,gosec
+		}
+	}()
+
+	path, err := mousePath(ctx, to, move, r.rnd)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range path {
+		if err := sleepContext(ctx, p.Delay); err != nil {
+			return err
+		}
+
+		moved := input.DispatchMouseEvent(input.MouseMoved, p.X, p.Y).
+			WithButton(input.Left).
+			WithButtons(buttonMask(input.Left))
+
+		if err := DispatchMouseEvent(moved).Do(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Release where the last move left the cursor.
+	x, y := cursorFor(ctx).get()
+
+	return releaseLeft(ctx, Point{X: x, Y: y})
+}
+
+// releaseLeft releases the left button at the point.
+func releaseLeft(ctx context.Context, p Point) error {
+	release := input.DispatchMouseEvent(input.MouseReleased, p.X, p.Y).
+		WithButton(input.Left).
+		WithClickCount(1)
+
+	return DispatchMouseEvent(release).Do(ctx)
+}
@@ -0,0 +1,125 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadingTime(t *testing.T) {
+	require.Equal(t, 30*time.Second, readingTime(115, defaultReadOptions))
+	require.Equal(t, 2*time.Second, readingTime(0, defaultReadOptions))
+	require.Equal(t, time.Minute, readingTime(10000, defaultReadOptions))
+
+	options := defaultReadOptions
+	options.wpm = 460
+	require.Equal(t, 15*time.Second, readingTime(115, options))
+
+	options.maxDuration = 0
+	require.Equal(t, 50*time.Minute, readingTime(23000, options))
+}
+
+func TestPickReadingAction(t *testing.T) {
+	counts := make([]int, 3)
+	actions := []readingAction{
+		{weight: 1, run: func(context.Context, readingView) error { counts[0]++; return nil }},
+		{weight: 3, run: func(context.Context, readingView) error { counts[1]++; return nil }},
+		{weight: 0, run: func(context.Context, readingView) error { counts[2]++; return nil }},
+	}
+
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+	for i := 0; i < 4000; i++ {
+		require.NoError(t, pickReadingAction(actions, rnd).run(context.Background(), readingView{}))
+	}
+
+	require.InDelta(t, 1000, counts[0], 100)
+	require.InDelta(t, 3000, counts[1], 100)
+	require.Zero(t, counts[2])
+}
+
+func TestRestUntil(t *testing.T) {
+	stop := make(chan struct{})
+
+	stopped, err := restUntil(context.Background(), time.Millisecond, stop)
+	require.NoError(t, err)
+	require.False(t, stopped)
+
+	close(stop)
+
+	stopped, err = restUntil(context.Background(), time.Hour, stop)
+	require.NoError(t, err)
+	require.True(t, stopped)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = restUntil(ctx, time.Hour, nil)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestReadPage(t *testing.T) {
+	text := strings.Repeat(`<p>Reading a page takes time, people drift with the mouse and scroll on while they read.</p>`, 80)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<html><body>
+			<a href="#top">top</a> <a href="#bottom">bottom</a>
+			%s
+			<script>
+				window.moves = 0;
+				document.addEventListener('mousemove', () => window.moves++);
+			</script>
+		</body></html>`, text)
+	}))
+	defer srv.Close()
+
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(60*time.Second),
+			WithHeadless(),
+		),
+		func(ctx context.Context) error {
+			var (
+				moves  int
+				scroll float64
+			)
+
+			start := time.Now()
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate(srv.URL),
+				ReadPage(WithReadDurationRange(time.Second, 8*time.Second)),
+				chromedp.Evaluate(`window.moves`, &moves),
+				chromedp.Evaluate(`window.scrollY`, &scroll),
+			); err != nil {
+				return err
+			}
+
+			// The page has far more text than can be read in 8 seconds.
+			if elapsed := time.Since(start); elapsed < 8*time.Second {
+				return fmt.Errorf("read for only %v", elapsed)
+			}
+
+			if moves == 0 && scroll == 0 {
+				return fmt.Errorf("sat still while reading")
+			}
+
+			stop := IdleInBackground(ctx)
+			time.Sleep(3 * time.Second)
+
+			if err := stop(); err != nil {
+				return fmt.Errorf("idle in background: %w", err)
+			}
+
+			return chromedp.Run(ctx, chromedp.Evaluate(`window.moves`, &moves))
+		},
+	)
+}
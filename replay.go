@@ -0,0 +1,228 @@
+package chromedpundetected
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+)
+
+// RecordedPath moves along trajectories recorded from a real person, see
+// InputRecorder. A trajectory of about the same length is picked, rotated and
+// scaled so it starts at the cursor and ends at the destination, and randomly
+// mirrored along the line between them. Its timing is scaled with the
+// movement time Fitts's law predicts for the new distance.
+type RecordedPath struct {
+	// Trajectories are the recorded movements, as returned by
+	// Recording.Trajectories.
+	Trajectories [][]PathPoint
+
+	// Fallback generates the path if there are no trajectories, or the
+	// cursor does not move. Defaults to FittsPath.
+	Fallback PathGenerator
+}
+
+// NewRecordedPath returns a RecordedPath moving along the trajectories of the
+// recordings.
+func NewRecordedPath(recordings ...*Recording) RecordedPath {
+	var p RecordedPath
+
+	for _, rec := range recordings {
+		p.Trajectories = append(p.Trajectories, rec.Trajectories()...)
+	}
+
+	return p
+}
+
+// Path implements PathGenerator.
+func (p RecordedPath) Path(req PathRequest) []PathPoint {
+	distance := math.Hypot(req.To.X-req.From.X, req.To.Y-req.From.Y)
+
+	trajectory := pickTrajectory(p.Trajectories, distance, req.Rand)
+	if trajectory == nil || distance < 1 {
+		fallback := p.Fallback
+		if fallback == nil {
+			fallback = FittsPath{}
+		}
+
+		return fallback.Path(req)
+	}
+
+	a, b := trajectory[0].Point, trajectory[len(trajectory)-1].Point
+	recorded := math.Hypot(b.X-a.X, b.Y-a.Y)
+	timeScale := float64(fittsDuration(100*time.Millisecond, 150*time.Millisecond, distance, req.TargetWidth)) /
+		float64(fittsDuration(100*time.Millisecond, 150*time.Millisecond, recorded, req.TargetWidth))
+
+	path := retarget(trajectory, req.From, req.To, timeScale, req.Rand.Intn(2) == 0)
+
+	if req.Steps > 0 && req.Steps < len(path) {
+		var duration time.Duration
+		for _, pp := range path {
+			duration += pp.Delay
+		}
+
+		points := make([]Point, 0, len(path))
+		for _, pp := range path {
+			points = append(points, pp.Point)
+		}
+
+		path = path[:0]
+		for _, pt := range resample(points, req.Steps) {
+			path = append(path, PathPoint{Point: pt, Delay: duration / time.Duration(req.Steps)})
+		}
+	}
+
+	return path
+}
+
+// pickTrajectory picks a random trajectory with a length between half and
+// twice the distance, or the one closest in length if there is none.
+// Trajectories of fewer than two points, or ending where they start, can not
+// be retargeted and are skipped. It returns nil if there are no usable
+// trajectories.
+func pickTrajectory(trajectories [][]PathPoint, distance float64, rnd *rand.Rand) []PathPoint {
+	var (
+		candidates [][]PathPoint
+		closest    []PathPoint
+		best       = math.Inf(1)
+	)
+
+	for _, t := range trajectories {
+		if len(t) < 2 {
+			continue
+		}
+
+		a, b := t[0].Point, t[len(t)-1].Point
+
+		length := math.Hypot(b.X-a.X, b.Y-a.Y)
+		if length == 0 {
+			continue
+		}
+
+		// How many times longer or shorter the trajectory is.
+		ratio := math.Abs(math.Log2(length / distance))
+		if ratio <= 1 {
+			candidates = append(candidates, t)
+		}
+
+		if ratio < best {
+			best, closest = ratio, t
+		}
+	}
+
+	if len(candidates) > 0 {
+		return candidates[rnd.Intn(len(candidates))]
+	}
+
+	return closest
+}
+
+// retarget maps the trajectory onto the line from one point to the other by
+// rotating and scaling it, optionally mirrored along its own line, and scales
+// its delays. The starting point is left out, as a path does not include it.
+func retarget(trajectory []PathPoint, from, to Point, timeScale float64, mirror bool) []PathPoint {
+	start, end := trajectory[0].Point, trajectory[len(trajectory)-1].Point
+	vx, vy := end.X-start.X, end.Y-start.Y
+	wx, wy := to.X-from.X, to.Y-from.Y
+
+	// Rotation and scaling as the complex number w / v.
+	norm := vx*vx + vy*vy
+	re := (wx*vx + wy*vy) / norm
+	im := (wy*vx - wx*vy) / norm
+
+	path := make([]PathPoint, 0, len(trajectory)-1)
+
+	for _, p := range trajectory[1:] {
+		x, y := p.X-start.X, p.Y-start.Y
+
+		if mirror {
+			dot := 2 * (x*vx + y*vy) / norm
+			x, y = dot*vx-x, dot*vy-y
+		}
+
+		path = append(path, PathPoint{
+			Point: Point{X: from.X + re*x - im*y, Y: from.Y + im*x + re*y},
+			Delay: time.Duration(float64(p.Delay) * timeScale),
+		})
+	}
+
+	path[len(path)-1].Point = to
+
+	return path
+}
+
+// Replay plays the mouse moves, clicks and scrolls of the recording back in
+// the current tab, at the recorded positions and with the recorded timing.
+// Key events are not replayed, as only their codes are recorded.
+func Replay(rec *Recording) chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		var (
+			last    time.Duration
+			buttons int64
+			click   replayClick
+		)
+
+		for _, e := range rec.Events {
+			if err := sleepContext(ctx, e.Time-last); err != nil {
+				return err
+			}
+
+			last = e.Time
+
+			var p *input.DispatchMouseEventParams
+
+			switch e.Type {
+			case InputMouseMove:
+				p = input.DispatchMouseEvent(input.MouseMoved, e.X, e.Y).WithButtons(e.Buttons)
+			case InputMouseDown:
+				buttons |= buttonMask(e.Button)
+				p = input.DispatchMouseEvent(input.MousePressed, e.X, e.Y).
+					WithButton(e.Button).
+					WithButtons(buttons).
+					WithClickCount(click.press(e))
+			case InputMouseUp:
+				buttons &^= buttonMask(e.Button)
+				p = input.DispatchMouseEvent(input.MouseReleased, e.X, e.Y).
+					WithButton(e.Button).
+					WithButtons(buttons).
+					WithClickCount(click.count)
+			case InputWheel:
+				p = input.DispatchMouseEvent(input.MouseWheel, e.X, e.Y).
+					WithDeltaX(e.DeltaX).
+					WithDeltaY(e.DeltaY)
+			default:
+				continue
+			}
+
+			if err := DispatchMouseEvent(p).Do(ctx); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// replayClick counts the clicks of double and triple clicks while replaying.
+type replayClick struct {
+	last  InputEvent
+	count int64
+}
+
+// press returns the click count of the mouse down event. Presses of the same
+// button within 500ms and a few pixels of the previous one continue it.
+func (c *replayClick) press(e InputEvent) int64 {
+	if c.count > 0 && e.Button == c.last.Button && e.Time-c.last.Time <= 500*time.Millisecond &&
+		math.Hypot(e.X-c.last.X, e.Y-c.last.Y) <= 5 {
+		c.count++
+	} else {
+		c.count = 1
+	}
+
+	c.last = e
+
+	return c.count
+}
@@ -0,0 +1,153 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+// testTrajectory is a movement to the right that bulges upwards.
+func testTrajectory(length float64) []PathPoint {
+	trajectory := []PathPoint{{Point: Point{X: 100, Y: 100}}}
+
+	for i := 1; i <= 10; i++ {
+		x := float64(i) / 10
+		trajectory = append(trajectory, PathPoint{
+			Point: Point{X: 100 + x*length, Y: 100 - math.Sin(x*math.Pi)*length/10},
+			Delay: 16 * time.Millisecond,
+		})
+	}
+
+	return trajectory
+}
+
+func TestRetarget(t *testing.T) {
+	trajectory := testTrajectory(100)
+
+	// Rotated by 90 degrees and scaled by two, the bulge ends up on the right.
+	path := retarget(trajectory, Point{X: 0, Y: 0}, Point{X: 0, Y: 200}, 2, false)
+	require.Len(t, path, 10)
+	require.Equal(t, Point{X: 0, Y: 200}, path[9].Point)
+	require.InDelta(t, 20, path[4].X, 0.5)
+	require.InDelta(t, 100, path[4].Y, 0.001)
+	require.Equal(t, 32*time.Millisecond, path[0].Delay)
+
+	// Mirrored the bulge is on the left.
+	path = retarget(trajectory, Point{X: 0, Y: 0}, Point{X: 0, Y: 200}, 1, true)
+	require.InDelta(t, -20, path[4].X, 0.5)
+	require.Equal(t, 16*time.Millisecond, path[0].Delay)
+}
+
+func TestRecordedPath(t *testing.T) {
+	short, long := testTrajectory(50), testTrajectory(800)
+	g := RecordedPath{Trajectories: [][]PathPoint{short, long}}
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+
+	req := PathRequest{From: Point{X: 10, Y: 10}, To: Point{X: 500, Y: 400}, Rand: rnd}
+
+	for i := 0; i < 10; i++ {
+		path := g.Path(req)
+		require.Len(t, path, 10)
+		require.Equal(t, req.To, path[len(path)-1].Point)
+
+		// The long trajectory is picked, with its timing sped up.
+		require.Less(t, path[0].Delay, 16*time.Millisecond)
+	}
+
+	require.Equal(t, short, pickTrajectory(g.Trajectories, 10, rnd))
+
+	req.Steps = 4
+	require.Len(t, g.Path(req), 4)
+
+	// Without trajectories the fallback is used.
+	req.Steps = 0
+	path := RecordedPath{}.Path(req)
+	require.Equal(t, req.To, path[len(path)-1].Point)
+	require.Nil(t, pickTrajectory(nil, 100, rnd))
+
+	// Trajectories that can't be retargeted are skipped.
+	unusable := [][]PathPoint{nil, {{Point: Point{X: 1, Y: 1}}}, {{Point: Point{X: 1, Y: 1}}, {Point: Point{X: 1, Y: 1}}}}
+	require.Nil(t, pickTrajectory(unusable, 100, rnd))
+
+	path = RecordedPath{Trajectories: unusable}.Path(req)
+	require.Equal(t, req.To, path[len(path)-1].Point)
+}
+
+func TestReplayClick(t *testing.T) {
+	var c replayClick
+
+	press := func(ms int, x float64) int64 {
+		return c.press(InputEvent{Time: time.Duration(ms) * time.Millisecond, X: x, Button: "left"})
+	}
+
+	require.Equal(t, int64(1), press(0, 10))
+	require.Equal(t, int64(2), press(200, 12))
+	require.Equal(t, int64(3), press(400, 12))
+	require.Equal(t, int64(1), press(1200, 12))
+	require.Equal(t, int64(1), press(1300, 40))
+}
+
+func TestReplay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body>
+			<button id="button" style="position: absolute; left: 100px; top: 100px; width: 100px; height: 40px">button</button>
+			<script>
+				window.clicks = 0;
+				window.moves = 0;
+				document.getElementById('button').addEventListener('click', () => window.clicks++);
+				document.addEventListener('mousemove', () => window.moves++);
+			</script>
+		</body></html>`)
+	}))
+	defer srv.Close()
+
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(60*time.Second),
+			WithHeadless(),
+		),
+		func(ctx context.Context) error {
+			rec := &Recording{}
+			for _, p := range testTrajectory(100) {
+				rec.Events = append(rec.Events, InputEvent{Time: time.Duration(len(rec.Events)) * 16 * time.Millisecond, Type: InputMouseMove, X: p.X, Y: p.Y + 20})
+			}
+
+			last := rec.Events[len(rec.Events)-1]
+			rec.Events = append(rec.Events,
+				InputEvent{Time: last.Time + 100*time.Millisecond, Type: InputMouseDown, X: 150, Y: 120, Button: "left"},
+				InputEvent{Time: last.Time + 180*time.Millisecond, Type: InputMouseUp, X: 150, Y: 120, Button: "left"},
+			)
+
+			var clicks, moves int
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate(srv.URL),
+				Replay(rec),
+				MoveMouseToPosition(400, 300, WithPathGenerator(NewRecordedPath(rec))),
+				chromedp.Evaluate(`window.clicks`, &clicks),
+				chromedp.Evaluate(`window.moves`, &moves),
+			); err != nil {
+				return err
+			}
+
+			if clicks != 1 || moves < 15 {
+				return fmt.Errorf("got %d clicks and %d moves", clicks, moves)
+			}
+
+			if x, y := CursorPosition(ctx); x != 400 || y != 300 {
+				return fmt.Errorf("cursor at %v, %v", x, y)
+			}
+
+			return nil
+		},
+	)
+}
@@ -0,0 +1,412 @@
+package chromedpundetected
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"golang.org/x/exp/slog"
+)
+
+// Errors.
+var (
+	ErrRecorderRunning    = errors.New("recorder already running")
+	ErrRecorderNotRunning = errors.New("recorder not running")
+	ErrNoFrames           = errors.New("no frames recorded")
+)
+
+// Frame is a single screencast frame.
+type Frame struct {
+	// Timestamp is the time at which the frame was captured by the browser.
+	Timestamp time.Time
+
+	// Width and Height are the dimensions of the viewport when the frame was
+	// captured, in device independent pixels, the unit of input events. The
+	// image itself may be scaled down to the maximum size of the options.
+	Width  float64
+	Height float64
+
+	// Data is the JPEG encoded image.
+	Data []byte
+}
+
+// screencastOptions contains the options for a screencast.
+type screencastOptions struct {
+	fps       float64
+	maxWidth  int64
+	maxHeight int64
+	quality   int64
+	maxFrames int
+}
+
+// Default values for screencasts.
+var defaultScreencastOptions = screencastOptions{
+	fps:       5,
+	maxWidth:  1280,
+	maxHeight: 720,
+	quality:   60,
+	maxFrames: 600,
+}
+
+// ScreencastOption defines a function type to set screencast options.
+type ScreencastOption func(*screencastOptions)
+
+// WithScreencastFPS returns a ScreencastOption that sets the maximum number of
+// frames per second that are kept. Frames arriving faster are dropped.
+func WithScreencastFPS(fps float64) ScreencastOption {
+	return func(opt *screencastOptions) {
+		opt.fps = fps
+	}
+}
+
+// WithScreencastSize returns a ScreencastOption that sets the maximum frame
+// resolution. Frames are scaled down by the browser to fit.
+func WithScreencastSize(maxWidth, maxHeight int) ScreencastOption {
+	return func(opt *screencastOptions) {
+		opt.maxWidth = int64(maxWidth)
+		opt.maxHeight = int64(maxHeight)
+	}
+}
+
+// WithScreencastQuality returns a ScreencastOption that sets the JPEG quality
+// of the frames, between 0 and 100.
+func WithScreencastQuality(quality int) ScreencastOption {
+	return func(opt *screencastOptions) {
+		opt.quality = int64(quality)
+	}
+}
+
+// WithScreencastMaxFrames returns a ScreencastOption that sets the maximum
+// number of frames a Recorder keeps. When the limit is reached the oldest
+// frames are dropped, so a recorder can be left running and still hold the
+// last moments of a session. Zero means no limit.
+func WithScreencastMaxFrames(n int) ScreencastOption {
+	return func(opt *screencastOptions) {
+		opt.maxFrames = n
+	}
+}
+
+// startScreencast starts a screencast on the target of the context, and calls
+// onFrame for every frame, limited to the configured frame rate. The returned
+// function stops the screencast. The browser only sends the next frame once
+// the last one is acknowledged, so frames are acknowledged for as long as the
+// tab lives, not just as long as the context.
+func startScreencast(ctx context.Context, options screencastOptions, onFrame func(Frame)) (func(context.Context) error, error) {
+	lctx, cancel := context.WithCancel(tabContext(ctx))
+
+	var (
+		mu   sync.Mutex
+		last time.Time
+	)
+
+	interval := time.Duration(0)
+	if options.fps > 0 {
+		interval = time.Duration(float64(time.Second) / options.fps)
+	}
+
+	chromedp.ListenTarget(lctx, func(ev interface{}) {
+		e, ok := ev.(*page.EventScreencastFrame)
+		if !ok {
+			return
+		}
+
+		// Frames have to be acknowledged for the browser to send the next
+		// one. Listeners block the event loop, so do it in the background.
+		go func() {
+			if err := page.ScreencastFrameAck(e.SessionID).Do(lctx); err != nil && lctx.Err() == nil {
+				slog.Error("failed to acknowledge screencast frame", err)
+			}
+		}()
+
+		ts := time.Now()
+		if e.Metadata.Timestamp != nil {
+			ts = e.Metadata.Timestamp.Time()
+		}
+
+		mu.Lock()
+		if interval > 0 && !last.IsZero() && ts.Sub(last) < interval {
+			mu.Unlock()
+			return
+		}
+		last = ts
+		mu.Unlock()
+
+		data, err := base64.StdEncoding.DecodeString(e.Data)
+		if err != nil {
+			slog.Error("failed to decode screencast frame", err)
+			return
+		}
+
+		onFrame(Frame{
+			Timestamp: ts,
+			Width:     e.Metadata.DeviceWidth,
+			Height:    e.Metadata.DeviceHeight,
+			Data:      data,
+		})
+	})
+
+	if err := page.StartScreencast().
+		WithFormat(page.ScreencastFormatJpeg).
+		WithQuality(options.quality).
+		WithMaxWidth(options.maxWidth).
+		WithMaxHeight(options.maxHeight).
+		Do(ctx); err != nil {
+		cancel()
+
+		return nil, fmt.Errorf("start screencast: %w", err)
+	}
+
+	stop := func(ctx context.Context) error {
+		defer cancel()
+
+		return page.StopScreencast().Do(ctx)
+	}
+
+	return stop, nil
+}
+
+// Recorder records the screen of a tab through the DevTools screencast.
+//
+// Start and stop it as part of a chromedp run, then write the frames out with
+// WriteMJPEG, WriteGIF or WriteFrameDir:
+//
+//	rec := NewRecorder(WithScreencastFPS(2))
+//	err := chromedp.Run(ctx, rec.Start(), chromedp.Navigate(url), rec.Stop())
+//	err = rec.WriteFrameDir("recording")
+type Recorder struct {
+	options screencastOptions
+
+	mu       sync.Mutex
+	frames   []Frame
+	stop     func(context.Context) error
+	starting bool
+}
+
+// NewRecorder creates a new screencast recorder.
+func NewRecorder(setters ...ScreencastOption) *Recorder {
+	options := defaultScreencastOptions
+
+	for _, setter := range setters {
+		setter(&options)
+	}
+
+	return &Recorder{options: options}
+}
+
+// Start starts recording the current tab.
+func (r *Recorder) Start() chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		r.mu.Lock()
+		if r.stop != nil || r.starting {
+			r.mu.Unlock()
+			return ErrRecorderRunning
+		}
+		r.starting = true
+		r.mu.Unlock()
+
+		// Frames may arrive before the screencast has started, and addFrame
+		// locks the mutex on the event loop, so it must not be held here.
+		stop, err := startScreencast(ctx, r.options, r.addFrame)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.starting = false
+		if err != nil {
+			return err
+		}
+
+		r.stop = stop
+
+		return nil
+	}
+}
+
+// Stop stops recording. The recorded frames are kept, a subsequent Start
+// appends to them.
+func (r *Recorder) Stop() chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		r.mu.Lock()
+		stop := r.stop
+		r.stop = nil
+		r.mu.Unlock()
+
+		if stop == nil {
+			return ErrRecorderNotRunning
+		}
+
+		return stop(ctx)
+	}
+}
+
+func (r *Recorder) addFrame(frame Frame) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.frames = append(r.frames, frame)
+
+	if limit := r.options.maxFrames; limit > 0 && len(r.frames) > limit {
+		r.frames = append(r.frames[:0:0], r.frames[len(r.frames)-limit:]...)
+	}
+}
+
+// Frames returns a copy of the recorded frames.
+func (r *Recorder) Frames() []Frame {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Frame(nil), r.frames...)
+}
+
+// Reset drops all recorded frames.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.frames = nil
+}
+
+// WriteMJPEG writes the frames as a Motion JPEG stream, i.e. the concatenated
+// JPEG images, which can be played by e.g. ffplay or VLC.
+func (r *Recorder) WriteMJPEG(w io.Writer) error {
+	frames := r.Frames()
+	if len(frames) == 0 {
+		return ErrNoFrames
+	}
+
+	for _, frame := range frames {
+		if _, err := w.Write(frame.Data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteGIF writes the frames as an animated GIF, with the frame delays taken
+// from the capture timestamps.
+func (r *Recorder) WriteGIF(w io.Writer) error {
+	frames := r.Frames()
+	if len(frames) == 0 {
+		return ErrNoFrames
+	}
+
+	var (
+		anim   gif.GIF
+		bounds image.Rectangle
+	)
+
+	for i, frame := range frames {
+		img, err := jpeg.Decode(bytes.NewReader(frame.Data))
+		if err != nil {
+			return fmt.Errorf("decode frame %d: %w", i, err)
+		}
+
+		// All images of a GIF share the bounds of the first frame, frames of
+		// another size, e.g. after a resize of the window, are scaled to fit.
+		if i == 0 {
+			bounds = img.Bounds()
+		} else if img.Bounds().Size() != bounds.Size() {
+			img = fitImage(img, bounds)
+		}
+
+		paletted := image.NewPaletted(bounds, palette.WebSafe)
+		draw.FloydSteinberg.Draw(paletted, bounds, img, img.Bounds().Min)
+
+		// GIF delays are in 100ths of a second.
+		delay := 10
+		if i+1 < len(frames) {
+			delay = int(frames[i+1].Timestamp.Sub(frame.Timestamp) / (10 * time.Millisecond))
+		}
+
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, delay)
+	}
+
+	return gif.EncodeAll(w, &anim)
+}
+
+// fitImage scales the image to fit the bounds, keeping its aspect ratio, and
+// centers it on a black background.
+func fitImage(img image.Image, bounds image.Rectangle) image.Image {
+	src := img.Bounds()
+	scale := math.Min(float64(bounds.Dx())/float64(src.Dx()), float64(bounds.Dy())/float64(src.Dy()))
+	w, h := int(float64(src.Dx())*scale), int(float64(src.Dy())*scale)
+	offset := bounds.Min.Add(image.Pt((bounds.Dx()-w)/2, (bounds.Dy()-h)/2))
+
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, image.Black, image.Point{}, draw.Src)
+
+	// Nearest neighbour scaling, the GIF palette loses more detail anyway.
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dst.Set(offset.X+x, offset.Y+y, img.At(src.Min.X+int(float64(x)/scale), src.Min.Y+int(float64(y)/scale)))
+		}
+	}
+
+	return dst
+}
+
+// frameIndexEntry describes a frame in the index of a frame directory.
+type frameIndexEntry struct {
+	File      string    `json:"file"`
+	Timestamp time.Time `json:"timestamp"`
+	Offset    float64   `json:"offset"`
+	Width     float64   `json:"width"`
+	Height    float64   `json:"height"`
+}
+
+// WriteFrameDir writes every frame as a JPEG file to the directory, together
+// with an index.json file holding the timestamp and offset in seconds from
+// the first frame of each file.
+func (r *Recorder) WriteFrameDir(dir string) error {
+	frames := r.Frames()
+	if len(frames) == 0 {
+		return ErrNoFrames
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil { //nolint:gosec
+		return err
+	}
+
+	index := make([]frameIndexEntry, 0, len(frames))
+
+	for i, frame := range frames {
+		name := fmt.Sprintf("frame-%05d.jpg", i)
+
+		if err := os.WriteFile(filepath.Join(dir, name), frame.Data, 0o644); err != nil { //nolint:gosec
+			return err
+		}
+
+		index = append(index, frameIndexEntry{
+			File:      name,
+			Timestamp: frame.Timestamp,
+			Offset:    frame.Timestamp.Sub(frames[0].Timestamp).Seconds(),
+			Width:     frame.Width,
+			Height:    frame.Height,
+		})
+	}
+
+	b, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, "index.json"), b, 0o644) //nolint:gosec
+}
@@ -0,0 +1,102 @@
+package chromedpundetected
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+func testFrame(t *testing.T, ts time.Time) Frame {
+	t.Helper()
+
+	return testFrameSize(t, ts, 16, 9)
+}
+
+func testFrameSize(t *testing.T, ts time.Time, width, height int) Frame {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)), nil))
+
+	return Frame{Timestamp: ts, Width: float64(width), Height: float64(height), Data: buf.Bytes()}
+}
+
+func TestRecorderWrite(t *testing.T) {
+	rec := NewRecorder(WithScreencastMaxFrames(3))
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		rec.addFrame(testFrame(t, start.Add(time.Duration(i)*200*time.Millisecond)))
+	}
+
+	frames := rec.Frames()
+	require.Len(t, frames, 3)
+	require.Equal(t, start.Add(400*time.Millisecond), frames[0].Timestamp)
+
+	dir := t.TempDir()
+	require.NoError(t, rec.WriteFrameDir(dir))
+
+	b, err := os.ReadFile(filepath.Join(dir, "index.json"))
+	require.NoError(t, err)
+
+	var index []frameIndexEntry
+	require.NoError(t, json.Unmarshal(b, &index))
+	require.Len(t, index, 3)
+	require.InDelta(t, 0.4, index[2].Offset, 0.001)
+	require.FileExists(t, filepath.Join(dir, index[2].File))
+
+	var buf bytes.Buffer
+	require.NoError(t, rec.WriteGIF(&buf))
+
+	anim, err := gif.DecodeAll(&buf)
+	require.NoError(t, err)
+	require.Len(t, anim.Image, 3)
+	require.Equal(t, 20, anim.Delay[0])
+
+	// Frames of another size, as after a resize, fit the first frame.
+	rec.addFrame(testFrameSize(t, start.Add(time.Second), 32, 24))
+	buf.Reset()
+	require.NoError(t, rec.WriteGIF(&buf))
+
+	anim, err = gif.DecodeAll(&buf)
+	require.NoError(t, err)
+	require.Len(t, anim.Image, 3)
+	require.Equal(t, image.Rect(0, 0, 16, 9), anim.Image[2].Bounds())
+
+	rec.Reset()
+	require.ErrorIs(t, rec.WriteMJPEG(&buf), ErrNoFrames)
+}
+
+func TestRecorder(t *testing.T) {
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(20*time.Second),
+			WithHeadless(),
+		),
+		func(ctx context.Context) error {
+			rec := NewRecorder(WithScreencastFPS(2))
+
+			if err := chromedp.Run(ctx,
+				rec.Start(),
+				chromedp.Navigate("https://www.example.com/"),
+				chromedp.Sleep(2*time.Second),
+				rec.Stop(),
+			); err != nil {
+				return err
+			}
+
+			return rec.WriteFrameDir(t.TempDir())
+		},
+	)
+}
@@ -0,0 +1,430 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+)
+
+// ScrollOptions contains options for scrolling with the mouse wheel.
+type ScrollOptions struct {
+	pauseMin  time.Duration
+	pauseMax  time.Duration
+	overshoot float64
+	move      []MoveOptionSetter
+	query     []chromedp.QueryOption
+}
+
+// Default values for scrolling.
+var defaultScrollOptions = ScrollOptions{
+	pauseMin:  150 * time.Millisecond,
+	pauseMax:  600 * time.Millisecond,
+	overshoot: 0.15,
+}
+
+// ScrollOptionSetter defines a function type to set scroll options.
+type ScrollOptionSetter func(*ScrollOptions)
+
+// WithScrollPause returns a ScrollOptionSetter that sets the range of the
+// pause between two bursts of wheel ticks.
+func WithScrollPause(min, max time.Duration) ScrollOptionSetter {
+	return func(opt *ScrollOptions) {
+		opt.pauseMin = min
+		opt.pauseMax = max
+	}
+}
+
+// WithScrollOvershoot returns a ScrollOptionSetter that sets the probability,
+// between 0 and 1, of scrolling a little too far and back on longer scrolls.
+// Defaults to 0.15.
+func WithScrollOvershoot(probability float64) ScrollOptionSetter {
+	return func(opt *ScrollOptions) {
+		opt.overshoot = probability
+	}
+}
+
+// WithScrollMoveOptions returns a ScrollOptionSetter that sets the options of
+// the mouse movements onto nested scroll containers.
+func WithScrollMoveOptions(setters ...MoveOptionSetter) ScrollOptionSetter {
+	return func(opt *ScrollOptions) {
+		opt.move = append(opt.move, setters...)
+	}
+}
+
+// WithScrollQueryOptions returns a ScrollOptionSetter that sets the options of
+// the element query, e.g. chromedp.ByQuery.
+func WithScrollQueryOptions(opts ...chromedp.QueryOption) ScrollOptionSetter {
+	return func(opt *ScrollOptions) {
+		opt.query = append(opt.query, opts...)
+	}
+}
+
+// Scroll scrolls by the distance in CSS pixels with the mouse wheel, at the
+// current cursor position. Positive values scroll down and to the right.
+//
+// Like a human flicking the wheel, the distance is covered in bursts of ticks
+// that slow down like inertia, with random pauses between the bursts, and a
+// longer scroll occasionally goes a little too far and comes back.
+func Scroll(dx, dy float64, setters ...ScrollOptionSetter) chromedp.ActionFunc {
+	options := defaultScrollOptions
+
+	for _, setter := range setters {
+		setter(&options)
+	}
+
+	return func(ctx context.Context) error {
+		return wheel(ctx, dx, dy, options, randFor(ctx))
+	}
+}
+
+// ScrollIntoView scrolls the first element matching the selector into view
+// with the mouse wheel, like Scroll does, if it is not fully visible.
+//
+// Elements inside nested scroll containers are scrolled into view from the
+// outside in: the page is scrolled until the outermost container is visible,
+// then the cursor moves onto that container to scroll it, and so on, until
+// the element itself is visible.
+func ScrollIntoView(sel any, setters ...ScrollOptionSetter) chromedp.ActionFunc {
+	options := defaultScrollOptions
+
+	for _, setter := range setters {
+		setter(&options)
+	}
+
+	return func(ctx context.Context) error {
+		nodeID, err := queryNode(ctx, sel, options.query)
+		if err != nil {
+			return err
+		}
+
+		_, err = scrollNodeIntoView(ctx, nodeID, options, randFor(ctx))
+
+		return err
+	}
+}
+
+// scrollContainer is the client area of a scrollable element, relative to
+// the viewport, and the directions it can scroll in.
+type scrollContainer struct {
+	Box
+	Horizontal bool `json:"horizontal"`
+	Vertical   bool `json:"vertical"`
+}
+
+// scrollLayout is the box of an element and of its scrollable ancestors.
+type scrollLayout struct {
+	// Containers are the scrollable ancestors, outermost first.
+	Containers []scrollContainer `json:"containers"`
+	Target     Box               `json:"target"`
+}
+
+// scrollLayoutJS returns the scrollLayout of the element it is called on.
+const scrollLayoutJS = `function() {
+	const el = this.nodeType === Node.ELEMENT_NODE ? this : this.parentElement;
+	const scrolls = (overflow) => overflow === 'auto' || overflow === 'scroll' || overflow === 'overlay';
+	const containers = [];
+
+	for (let p = el.parentElement; p && p !== document.body && p !== document.documentElement; p = p.parentElement) {
+		const style = getComputedStyle(p);
+		const horizontal = scrolls(style.overflowX) && p.scrollWidth > p.clientWidth;
+		const vertical = scrolls(style.overflowY) && p.scrollHeight > p.clientHeight;
+		if (!horizontal && !vertical) continue;
+
+		// The client area excludes the borders and scrollbars.
+		const r = p.getBoundingClientRect();
+		containers.unshift({
+			x: r.left + p.clientLeft,
+			y: r.top + p.clientTop,
+			width: p.clientWidth,
+			height: p.clientHeight,
+			horizontal,
+			vertical,
+		});
+	}
+
+	const r = el.getBoundingClientRect();
+	return { containers, target: { x: r.left, y: r.top, width: r.width, height: r.height } };
+}`
+
+// scrollStep is a scroll of a single container towards the target.
+type scrollStep struct {
+	// area is the visible part of the container, nested is false for the
+	// page itself.
+	area   Box
+	nested bool
+	dx, dy float64
+}
+
+// nextScroll returns the first scroll needed to bring the target into view,
+// from the outside in: each container is scrolled until the next one, or the
+// target, is visible in the part of it that is itself visible. It returns
+// false if the target is in view.
+func nextScroll(layout scrollLayout, viewport Box) (scrollStep, bool) {
+	containers := append([]scrollContainer{{Box: viewport, Horizontal: true, Vertical: true}}, layout.Containers...)
+	area := viewport
+
+	for i, c := range containers {
+		area = c.Box.intersect(area)
+
+		target := layout.Target
+		if i < len(layout.Containers) {
+			target = layout.Containers[i].Box
+		}
+
+		relative := Box{X: target.X - area.X, Y: target.Y - area.Y, Width: target.Width, Height: target.Height}
+
+		dx, dy := scrollDelta(relative, area.Width, area.Height)
+		if !c.Horizontal {
+			dx = 0
+		}
+
+		if !c.Vertical {
+			dy = 0
+		}
+
+		if dx != 0 || dy != 0 {
+			return scrollStep{area: area, nested: i > 0, dx: dx, dy: dy}, true
+		}
+	}
+
+	return scrollStep{}, false
+}
+
+// visible returns the part of the target not clipped by the containers or
+// the viewport.
+func (l scrollLayout) visible(viewport Box) Box {
+	box := l.Target.intersect(viewport)
+	for _, c := range l.Containers {
+		box = box.intersect(c.Box)
+	}
+
+	return box
+}
+
+// nodeScrollLayout returns the scroll layout of the node. Nodes the isolated
+// world of the main frame can not resolve, e.g. in iframes, are treated as
+// if they have no scrollable ancestors.
+func nodeScrollLayout(ctx context.Context, nodeID cdp.NodeID) (scrollLayout, error) {
+	var layout scrollLayout
+	if err := callIsolatedOn(ctx, nodeID, scrollLayoutJS, &layout); err == nil {
+		return layout, nil
+	}
+
+	box, err := nodeBox(ctx, nodeID)
+	if err != nil {
+		return scrollLayout{}, err
+	}
+
+	return scrollLayout{Target: box}, nil
+}
+
+// scrollNodeIntoView scrolls the node into view with the mouse wheel, moving
+// the cursor onto nested scroll containers as needed, and returns the visible
+// part of the node.
+func scrollNodeIntoView(ctx context.Context, nodeID cdp.NodeID, options ScrollOptions, rnd *rand.Rand) (Box, error) {
+	return bringIntoView(ctx, nodeID, rnd, func(step scrollStep) error {
+		if step.nested {
+			if err := moveOnto(ctx, step.area, options, rnd); err != nil {
+				return err
+			}
+		}
+
+		return wheel(ctx, step.dx, step.dy, options, rnd)
+	})
+}
+
+// bringIntoView brings the node into view with the scroll steps, e.g. with
+// the wheel or by swiping, and returns the visible part of the node.
+func bringIntoView(ctx context.Context, nodeID cdp.NodeID, rnd *rand.Rand, scroll func(step scrollStep) error) (Box, error) {
+	const maxSteps = 30
+
+	for i := 0; i < maxSteps; i++ {
+		layout, err := nodeScrollLayout(ctx, nodeID)
+		if err != nil {
+			return Box{}, err
+		}
+
+		width, height, err := viewportSize(ctx)
+		if err != nil {
+			return Box{}, err
+		}
+
+		viewport := Box{Width: width, Height: height}
+
+		step, ok := nextScroll(layout, viewport)
+		if !ok {
+			if visible := layout.visible(viewport); visible.Width >= 1 && visible.Height >= 1 {
+				return visible, nil
+			}
+
+			break
+		}
+
+		// Input can not reach a container clipped from view.
+		if step.nested && (step.area.Width < 1 || step.area.Height < 1) {
+			break
+		}
+
+		if err := scroll(step); err != nil {
+			return Box{}, err
+		}
+
+		// Let smooth scrolling settle, as a human would glance at the result.
+		if err := sleepContext(ctx, randDuration(rnd, 100*time.Millisecond, 250*time.Millisecond)); err != nil {
+			return Box{}, err
+		}
+
+		// Stop if nothing scrolled, e.g. when scrolled to the end, or when the
+		// cursor is over another container that takes the wheel events.
+		if after, err := nodeScrollLayout(ctx, nodeID); err == nil && after.Target == layout.Target {
+			break
+		}
+	}
+
+	// Fall back to scrolling without input.
+	if err := dom.ScrollIntoViewIfNeeded().WithNodeID(nodeID).Do(ctx); err != nil {
+		return Box{}, fmt.Errorf("scroll into view: %w", err)
+	}
+
+	return visibleBox(ctx, nodeID)
+}
+
+// moveOnto moves the cursor to a random point in the area, unless it is in the
+// area already.
+func moveOnto(ctx context.Context, area Box, options ScrollOptions, rnd *rand.Rand) error {
+	x, y := CursorPosition(ctx)
+	if x >= area.X && x < area.X+area.Width && y >= area.Y && y < area.Y+area.Height {
+		return nil
+	}
+
+	move := defaultMouseMoveOptions
+	move.targetWidth = math.Min(area.Width, area.Height)
+
+	for _, setter := range options.move {
+		setter(&move)
+	}
+
+	return moveMouse(ctx, randomPointIn(area, rnd), move)
+}
+
+// wheel scrolls by the distance with bursts of wheel ticks at the cursor,
+// vertically first.
+func wheel(ctx context.Context, dx, dy float64, options ScrollOptions, rnd *rand.Rand) error {
+	for _, axis := range []struct {
+		distance   float64
+		horizontal bool
+	}{{dy, false}, {dx, true}} {
+		for _, tick := range wheelTicks(axis.distance, options, rnd) {
+			if err := sleepContext(ctx, tick.delay); err != nil {
+				return err
+			}
+
+			x, y := CursorPosition(ctx)
+			params := input.DispatchMouseEvent(input.MouseWheel, x, y)
+
+			if axis.horizontal {
+				params = params.WithDeltaX(tick.delta).WithDeltaY(0)
+			} else {
+				params = params.WithDeltaX(0).WithDeltaY(tick.delta)
+			}
+
+			if err := DispatchMouseEvent(params).Do(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// wheelTick is a single wheel event, with the time to wait before it.
+type wheelTick struct {
+	delta float64
+	delay time.Duration
+}
+
+// minOvershootScroll is the distance below which scrolls do not overshoot.
+const minOvershootScroll = 300
+
+// wheelTicks splits the distance into bursts of wheel ticks, that add up to
+// the rounded distance. With the overshoot probability of the options, longer
+// distances are overshot by a little and scrolled back after a pause.
+func wheelTicks(distance float64, options ScrollOptions, rnd *rand.Rand) []wheelTick {
+	distance = math.Round(distance)
+	if distance == 0 {
+		return nil
+	}
+
+	sign := math.Copysign(1, distance)
+	distance = math.Abs(distance)
+
+	var back float64
+	if distance >= minOvershootScroll && rnd.Float64() < options.overshoot {
+		back = math.Round(20 + rnd.Float64()*math.Min(100, distance/10))
+	}
+
+	ticks := wheelBursts(nil, distance+back, sign, options, rnd)
+	if back > 0 {
+		ticks = wheelBursts(ticks, back, -sign, options, rnd)
+	}
+
+	return ticks
+}
+
+// wheelBursts appends bursts of ticks covering the distance to the ticks.
+// Every burst starts fast and slows down like a flicked wheel or a touchpad
+// with inertia, and is followed by a pause.
+func wheelBursts(ticks []wheelTick, distance, sign float64, options ScrollOptions, rnd *rand.Rand) []wheelTick {
+	// Ticks slower than this end a burst.
+	const minSpeed = 4
+
+	for remaining := distance; remaining > 0; {
+		speed := 80 + rnd.Float64()*80
+		decay := 0.75 + rnd.Float64()*0.15
+
+		for first := true; remaining > 0 && speed >= minSpeed; first = false {
+			delay := randDuration(rnd, 12*time.Millisecond, 24*time.Millisecond)
+			if first && len(ticks) > 0 {
+				delay = randDuration(rnd, options.pauseMin, options.pauseMax)
+			}
+
+			delta := math.Min(remaining, math.Round(speed))
+			ticks = append(ticks, wheelTick{delta: sign * delta, delay: delay})
+
+			remaining -= delta
+			speed *= decay
+		}
+	}
+
+	return ticks
+}
+
+// scrollDelta returns the distance to scroll to bring the box into the
+// viewport of the size, centering it if it has to move at all.
+func scrollDelta(box Box, width, height float64) (float64, float64) {
+	delta := func(pos, size, viewport float64) float64 {
+		if pos >= 0 && pos+size <= viewport {
+			return 0
+		}
+
+		// Elements larger than the viewport only need their start in view.
+		if size >= viewport {
+			if pos >= 0 && pos < viewport/2 {
+				return 0
+			}
+
+			return pos
+		}
+
+		return math.Round(pos + size/2 - viewport/2)
+	}
+
+	return delta(box.X, box.Width, width), delta(box.Y, box.Height, height)
+}
@@ -0,0 +1,201 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWheelTicks(t *testing.T) {
+	options := defaultScrollOptions
+	overshot := 0
+
+	for seed := int64(0); seed < 50; seed++ {
+		for _, distance := range []float64{1, 40, 99.6, 350, 1200, -800, -5000} {
+			ticks := wheelTicks(distance, options, rand.New(rand.NewSource(seed))) //nolint:gosec
+			require.NotEmpty(t, ticks)
+
+			var sum, peak float64
+			for _, tick := range ticks {
+				require.NotZero(t, tick.delta)
+				require.GreaterOrEqual(t, tick.delay, time.Duration(0))
+
+				sum += tick.delta
+				peak = math.Max(peak, sum*math.Copysign(1, distance))
+			}
+
+			require.Equal(t, math.Round(distance), sum)
+
+			if peak > math.Abs(math.Round(distance)) {
+				require.GreaterOrEqual(t, math.Abs(distance), float64(minOvershootScroll))
+				overshot++
+			}
+		}
+	}
+
+	require.Greater(t, overshot, 0)
+
+	require.Empty(t, wheelTicks(0.2, options, rand.New(rand.NewSource(1)))) //nolint:gosec
+}
+
+func TestWheelBursts(t *testing.T) {
+	options := defaultScrollOptions
+	options.overshoot = 0
+
+	ticks := wheelTicks(3000, options, rand.New(rand.NewSource(1))) //nolint:gosec
+
+	bursts := 0
+
+	for i, tick := range ticks {
+		if tick.delay >= options.pauseMin {
+			bursts++
+
+			continue
+		}
+
+		// Within a burst the ticks slow down, except for the last one that
+		// may be cut short.
+		if i > 0 && i < len(ticks)-1 {
+			require.LessOrEqual(t, tick.delta, ticks[i-1].delta)
+		}
+	}
+
+	require.Greater(t, bursts, 1)
+
+	options.overshoot = 1
+	ticks = wheelTicks(1000, options, rand.New(rand.NewSource(1))) //nolint:gosec
+	require.Negative(t, ticks[len(ticks)-1].delta)
+}
+
+func TestScrollDelta(t *testing.T) {
+	dx, dy := scrollDelta(Box{X: 10, Y: 10, Width: 50, Height: 50}, 800, 600)
+	require.Zero(t, dx)
+	require.Zero(t, dy)
+
+	dx, dy = scrollDelta(Box{X: 10, Y: 2000, Width: 50, Height: 50}, 800, 600)
+	require.Zero(t, dx)
+	require.Equal(t, 1725.0, dy)
+
+	dx, dy = scrollDelta(Box{X: 10, Y: -500, Width: 50, Height: 50}, 800, 600)
+	require.Zero(t, dx)
+	require.Equal(t, -775.0, dy)
+
+	// Elements larger than the viewport only need their start in view.
+	_, dy = scrollDelta(Box{Y: 100, Width: 50, Height: 5000}, 800, 600)
+	require.Zero(t, dy)
+
+	_, dy = scrollDelta(Box{Y: 900, Width: 50, Height: 5000}, 800, 600)
+	require.Equal(t, 900.0, dy)
+}
+
+func TestNextScroll(t *testing.T) {
+	viewport := Box{Width: 800, Height: 600}
+	container := scrollContainer{Box: Box{X: 100, Y: 1000, Width: 300, Height: 200}, Vertical: true}
+
+	// The page scrolls first to bring the container into view.
+	step, ok := nextScroll(scrollLayout{
+		Containers: []scrollContainer{container},
+		Target:     Box{X: 120, Y: 1500, Width: 50, Height: 20},
+	}, viewport)
+	require.True(t, ok)
+	require.False(t, step.nested)
+	require.Equal(t, 800.0, step.dy)
+
+	// Then the container, with the cursor over its visible part.
+	container.Y = 200
+	step, ok = nextScroll(scrollLayout{
+		Containers: []scrollContainer{container},
+		Target:     Box{X: 120, Y: 700, Width: 50, Height: 20},
+	}, viewport)
+	require.True(t, ok)
+	require.True(t, step.nested)
+	require.Equal(t, container.Box, step.area)
+	require.Equal(t, 410.0, step.dy)
+	require.Zero(t, step.dx)
+
+	// Containers that do not scroll horizontally are left alone, and clip
+	// the target.
+	layout := scrollLayout{
+		Containers: []scrollContainer{container},
+		Target:     Box{X: 380, Y: 250, Width: 50, Height: 20},
+	}
+	_, ok = nextScroll(layout, viewport)
+	require.False(t, ok)
+	require.Equal(t, Box{X: 380, Y: 250, Width: 20, Height: 20}, layout.visible(viewport))
+}
+
+func TestScrollIntoView(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body>
+			<div style="height: 2000px"></div>
+			<div id="outer" style="height: 300px; width: 400px; overflow: auto">
+				<div style="height: 800px"></div>
+				<div id="inner" style="height: 150px; overflow: auto">
+					<div style="height: 600px"></div>
+					<button id="target">target</button>
+					<div style="height: 600px"></div>
+				</div>
+				<div style="height: 800px"></div>
+			</div>
+			<div style="height: 2000px"></div>
+			<script>
+				window.wheels = 0;
+				window.trusted = true;
+				document.addEventListener('wheel', (e) => {
+					window.wheels++;
+					window.trusted = window.trusted && e.isTrusted;
+				}, { capture: true, passive: true });
+			</script>
+		</body></html>`)
+	}))
+	defer srv.Close()
+
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(60*time.Second),
+			WithHeadless(),
+		),
+		func(ctx context.Context) error {
+			var (
+				scrolled, wheels float64
+				trusted, visible bool
+			)
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate(srv.URL),
+				Scroll(0, 500, WithScrollOvershoot(0)),
+				chromedp.Sleep(500*time.Millisecond),
+				chromedp.Evaluate(`window.scrollY`, &scrolled),
+				ScrollIntoView(`#target`, WithScrollQueryOptions(chromedp.ByQuery)),
+				chromedp.Evaluate(`window.wheels`, &wheels),
+				chromedp.Evaluate(`window.trusted`, &trusted),
+				chromedp.Evaluate(`(() => {
+					const r = document.getElementById('target').getBoundingClientRect();
+					const inner = document.getElementById('inner').getBoundingClientRect();
+					return r.top >= inner.top && r.bottom <= inner.bottom && r.top >= 0 && r.bottom <= window.innerHeight;
+				})()`, &visible),
+			); err != nil {
+				return err
+			}
+
+			if math.Abs(scrolled-500) > 5 {
+				return fmt.Errorf("scrolled %v pixels, want 500", scrolled)
+			}
+
+			if !visible || !trusted || wheels < 10 {
+				return fmt.Errorf("visible %v, trusted %v after %v wheel events", visible, trusted, wheels)
+			}
+
+			return nil
+		},
+	)
+}
@@ -0,0 +1,93 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"golang.org/x/exp/slog"
+)
+
+// targetSetupKey is the context key under which New stores the actions that
+// prepare a new tab.
+type targetSetupKey struct{}
+
+// targetSetup returns the actions to run on every new tab, as configured by
+// the config.
+func (c Config) targetSetup(langs []weightedLanguage) (chromedp.Tasks, error) {
+	var tasks chromedp.Tasks
+
+	if c.Persona != nil {
+		if err := c.Persona.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.validateGeo(); err != nil {
+		return nil, err
+	}
+
+	evasions, err := evasionSetup(c)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks = append(tasks, evasions...)
+	tasks = append(tasks, localeSetup(langs)...)
+	tasks = append(tasks, geoSetup(c)...)
+	tasks = append(tasks, headlessFixes(c, langs)...)
+	tasks = append(tasks, personaSetup(c, langs)...)
+
+	return tasks, nil
+}
+
+// withTargetSetup stores the tab setup actions in the context.
+func withTargetSetup(ctx context.Context, tasks chromedp.Tasks) context.Context {
+	return context.WithValue(ctx, targetSetupKey{}, tasks)
+}
+
+// targetSetupFromContext returns the tab setup actions stored in the context.
+func targetSetupFromContext(ctx context.Context) chromedp.Tasks {
+	tasks, _ := ctx.Value(targetSetupKey{}).(chromedp.Tasks) //nolint:errcheck
+
+	return tasks
+}
+
+// NewTab creates a new tab in the browser of a context created by New, and
+// applies the same setup New applied to the first tab, e.g. the headless
+// patches. Use it instead of chromedp.NewContext to open additional tabs.
+//
+// The options are passed on to chromedp.NewContext, so it can also be used to
+// attach to an existing target with chromedp.WithTargetID.
+func NewTab(ctx context.Context, opts ...chromedp.ContextOption) (context.Context, context.CancelFunc, error) {
+	ctx, cancel := chromedp.NewContext(ctx, opts...)
+
+	if err := chromedp.Run(ctx, targetSetupFromContext(ctx)); err != nil {
+		cancel()
+
+		return nil, func() {}, fmt.Errorf("setup tab: %w", err)
+	}
+
+	return ctx, cancel, nil
+}
+
+// setupPopups applies the tab setup to every popup opened by the pages of the
+// browser. Popups are patched on a best effort basis, the first document of
+// a popup might load before the setup is complete.
+func setupPopups(ctx context.Context) {
+	chromedp.ListenBrowser(ctx, func(ev interface{}) {
+		e, ok := ev.(*target.EventTargetCreated)
+		if !ok || e.TargetInfo.Type != "page" || e.TargetInfo.OpenerID == "" {
+			return
+		}
+
+		// Listeners block the event loop, so attach in the background. The
+		// popup context is not cancelled, as that would close the popup.
+		go func(id target.ID) {
+			if _, _, err := NewTab(ctx, chromedp.WithTargetID(id)); err != nil && ctx.Err() == nil {
+				slog.Error("failed to setup popup", err, "target", id)
+			}
+		}(e.TargetInfo.TargetID)
+	})
+}
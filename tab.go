@@ -0,0 +1,72 @@
+package chromedpundetected
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// tabContexts maps a chromedp target to the context of its lifetime.
+var tabContexts sync.Map //nolint:gochecknoglobals
+
+// tabContext returns a context that lasts as long as the tab of the context,
+// instead of as long as the context itself. Listeners and background work that
+// have to outlive the action that starts them, like the ack of screencast
+// frames, use it. The context carries the chromedp values of ctx, and is
+// cancelled when the tab is closed or the connection to the browser is lost.
+func tabContext(ctx context.Context) context.Context {
+	c := chromedp.FromContext(ctx)
+	if c == nil || c.Target == nil || c.Browser == nil {
+		return ctx
+	}
+
+	if v, ok := tabContexts.Load(c.Target); ok {
+		return v.(context.Context) //nolint:forcetypeassert
+	}
+
+	tctx, cancel := context.WithCancel(detachedContext{ctx})
+
+	v, loaded := tabContexts.LoadOrStore(c.Target, tctx)
+	if loaded {
+		cancel()
+
+		return v.(context.Context) //nolint:forcetypeassert
+	}
+
+	id := c.Target.TargetID
+
+	chromedp.ListenBrowser(tctx, func(ev interface{}) {
+		if e, ok := ev.(*target.EventTargetDestroyed); ok && e.TargetID == id {
+			cancel()
+		}
+	})
+
+	go func() {
+		select {
+		case <-tctx.Done():
+		case <-c.Browser.LostConnection:
+			cancel()
+		}
+
+		tabContexts.Delete(c.Target)
+	}()
+
+	return tctx
+}
+
+// detachedContext carries the values of its parent, but not its deadline or
+// cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+func (detachedContext) Err() error { return nil }
+
+func (d detachedContext) Value(key any) any { return d.parent.Value(key) }
@@ -0,0 +1,438 @@
+package chromedpundetected
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+)
+
+// TouchOptions contains options for touch gestures.
+type TouchOptions struct {
+	radiusMin    float64
+	radiusMax    float64
+	forceMin     float64
+	forceMax     float64
+	tapMin       time.Duration
+	tapMax       time.Duration
+	longPressMin time.Duration
+	longPressMax time.Duration
+	swipeMin     time.Duration
+	swipeMax     time.Duration
+	fling        bool
+	query        []chromedp.QueryOption
+}
+
+// Default values for touch gestures.
+var defaultTouchOptions = TouchOptions{
+	radiusMin:    6,
+	radiusMax:    11,
+	forceMin:     0.3,
+	forceMax:     0.8,
+	tapMin:       40 * time.Millisecond,
+	tapMax:       110 * time.Millisecond,
+	longPressMin: 600 * time.Millisecond,
+	longPressMax: time.Second,
+	swipeMin:     180 * time.Millisecond,
+	swipeMax:     400 * time.Millisecond,
+	fling:        true,
+}
+
+// TouchOptionSetter defines a function type to set touch options.
+type TouchOptionSetter func(*TouchOptions)
+
+// WithTouchRadius returns a TouchOptionSetter that sets the range of the
+// radius of the finger contact area in CSS pixels. The contact area is an
+// ellipse, somewhat longer than it is wide.
+func WithTouchRadius(min, max float64) TouchOptionSetter {
+	return func(opt *TouchOptions) {
+		opt.radiusMin = min
+		opt.radiusMax = max
+	}
+}
+
+// WithTouchForce returns a TouchOptionSetter that sets the range of the
+// normalized pressure of the finger, between 0 and 1.
+func WithTouchForce(min, max float64) TouchOptionSetter {
+	return func(opt *TouchOptions) {
+		opt.forceMin = min
+		opt.forceMax = max
+	}
+}
+
+// WithTapHold returns a TouchOptionSetter that sets the range of the time the
+// finger touches the screen when tapping.
+func WithTapHold(min, max time.Duration) TouchOptionSetter {
+	return func(opt *TouchOptions) {
+		opt.tapMin = min
+		opt.tapMax = max
+	}
+}
+
+// WithLongPressDuration returns a TouchOptionSetter that sets the range of
+// the time the finger touches the screen for a long press.
+func WithLongPressDuration(min, max time.Duration) TouchOptionSetter {
+	return func(opt *TouchOptions) {
+		opt.longPressMin = min
+		opt.longPressMax = max
+	}
+}
+
+// WithSwipeDuration returns a TouchOptionSetter that sets the range of the
+// time a swipe takes.
+func WithSwipeDuration(min, max time.Duration) TouchOptionSetter {
+	return func(opt *TouchOptions) {
+		opt.swipeMin = min
+		opt.swipeMax = max
+	}
+}
+
+// WithSwipeFling returns a TouchOptionSetter that sets whether the finger
+// leaves the screen while still moving, so the page keeps scrolling on its
+// own, or comes to a stop first. Defaults to true.
+func WithSwipeFling(fling bool) TouchOptionSetter {
+	return func(opt *TouchOptions) {
+		opt.fling = fling
+	}
+}
+
+// WithTouchQueryOptions returns a TouchOptionSetter that sets the options of
+// the element query, e.g. chromedp.ByQuery.
+func WithTouchQueryOptions(opts ...chromedp.QueryOption) TouchOptionSetter {
+	return func(opt *TouchOptions) {
+		opt.query = append(opt.query, opts...)
+	}
+}
+
+// Tap taps the first element matching the selector with a finger, for pages
+// emulating a touch screen, see MobilePersona.
+//
+// The element is swiped into view if needed, and tapped at a random point in
+// its visible part, most likely near its center. The finger touches the
+// screen briefly, with its contact area and pressure growing as it lands.
+func Tap(sel any, setters ...TouchOptionSetter) chromedp.ActionFunc {
+	return touchNode(sel, setters, func(o TouchOptions) (time.Duration, time.Duration) {
+		return o.tapMin, o.tapMax
+	})
+}
+
+// TapAt taps the point of the viewport with a finger, like Tap.
+func TapAt(x, y float64, setters ...TouchOptionSetter) chromedp.ActionFunc {
+	options := defaultTouchOptions
+
+	for _, setter := range setters {
+		setter(&options)
+	}
+
+	return func(ctx context.Context) error {
+		rnd := randFor(ctx)
+
+		return press(ctx, Point{X: x, Y: y}, randDuration(rnd, options.tapMin, options.tapMax), options, rnd)
+	}
+}
+
+// LongPress touches the first element matching the selector and holds the
+// finger on it, e.g. to open a context menu. Like a real finger, it drifts
+// slightly while pressing, staying within the distance that would turn the
+// press into a scroll.
+func LongPress(sel any, setters ...TouchOptionSetter) chromedp.ActionFunc {
+	return touchNode(sel, setters, func(o TouchOptions) (time.Duration, time.Duration) {
+		return o.longPressMin, o.longPressMax
+	})
+}
+
+// Swipe moves a finger over the screen from one point of the viewport to
+// another, e.g. to scroll the page or to page through a carousel. Swiping
+// up scrolls down.
+//
+// The finger follows a slight arc, accelerating smoothly. By default it is
+// lifted while still moving, as a flick, see WithSwipeFling.
+func Swipe(from, to Point, setters ...TouchOptionSetter) chromedp.ActionFunc {
+	options := defaultTouchOptions
+
+	for _, setter := range setters {
+		setter(&options)
+	}
+
+	return func(ctx context.Context) error {
+		rnd := randFor(ctx)
+
+		return swipe(ctx, from, to, randDuration(rnd, options.swipeMin, options.swipeMax), options.fling, options, rnd)
+	}
+}
+
+// Pinch pinches with two fingers around the point of the viewport, zooming
+// by the scale: fingers spreading apart for a scale above 1, and moving
+// together for a scale below 1.
+func Pinch(center Point, scale float64, setters ...TouchOptionSetter) chromedp.ActionFunc {
+	options := defaultTouchOptions
+
+	for _, setter := range setters {
+		setter(&options)
+	}
+
+	return func(ctx context.Context) error {
+		rnd := randFor(ctx)
+
+		width, height, err := viewportSize(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Zooming in starts with the fingers close together, zooming out
+		// with them apart.
+		start := 60 + 40*rnd.Float64()
+		if scale < 1 {
+			start = 200 + 60*rnd.Float64()
+		}
+
+		end := math.Max(30, start*scale)
+
+		// The fingers are roughly on a diagonal, as thumb and index finger.
+		angle := math.Pi/4 + rnd.NormFloat64()*0.2
+		dir := Point{X: math.Cos(angle), Y: -math.Sin(angle)}
+
+		at := func(distance, side float64) Point {
+			return clampPoint(Point{
+				X: center.X + side*dir.X*distance/2,
+				Y: center.Y + side*dir.Y*distance/2,
+			}, width, height)
+		}
+
+		fingers := [2]finger{newFinger(1, options, rnd), newFinger(2, options, rnd)}
+		duration := 2 * randDuration(rnd, options.swipeMin, options.swipeMax)
+
+		steps := int(duration / pathInterval)
+		if steps < 2 {
+			steps = 2
+		}
+
+		for i := 0; i <= steps; i++ {
+			t := float64(i) / float64(steps)
+			distance := start + (end-start)*minimumJerk(t)
+
+			typ := input.TouchMove
+			if i == 0 {
+				typ = input.TouchStart
+			} else if err := sleepContext(ctx, duration/time.Duration(steps)); err != nil {
+				return err
+			}
+
+			pressure := math.Min(1, 0.5+t*4)
+			points := []*input.TouchPoint{
+				fingers[0].at(at(distance, 1), pressure),
+				fingers[1].at(at(distance, -1), pressure),
+			}
+
+			if err := input.DispatchTouchEvent(typ, points).Do(ctx); err != nil {
+				return err
+			}
+		}
+
+		return input.DispatchTouchEvent(input.TouchEnd, []*input.TouchPoint{}).Do(ctx)
+	}
+}
+
+// touchNode returns an action that swipes the node into view, and presses it
+// for a random duration in the range hold returns.
+func touchNode(sel any, setters []TouchOptionSetter, hold func(TouchOptions) (time.Duration, time.Duration)) chromedp.ActionFunc {
+	options := defaultTouchOptions
+
+	for _, setter := range setters {
+		setter(&options)
+	}
+
+	return func(ctx context.Context) error {
+		rnd := randFor(ctx)
+
+		nodeID, err := queryNode(ctx, sel, options.query)
+		if err != nil {
+			return err
+		}
+
+		box, err := touchIntoView(ctx, nodeID, options, rnd)
+		if err != nil {
+			return err
+		}
+
+		min, max := hold(options)
+
+		return press(ctx, randomPointIn(box, rnd), randDuration(rnd, min, max), options, rnd)
+	}
+}
+
+// finger is a finger touching the screen.
+type finger struct {
+	id      float64
+	radiusX float64
+	radiusY float64
+	angle   float64
+	force   float64
+}
+
+// newFinger returns a finger with a random contact area and pressure.
+func newFinger(id float64, options TouchOptions, rnd *rand.Rand) finger {
+	radius := options.radiusMin + rnd.Float64()*(options.radiusMax-options.radiusMin)
+
+	return finger{
+		id:      id,
+		radiusX: radius,
+		radiusY: radius * (1.1 + 0.3*rnd.Float64()),
+		angle:   rnd.Float64() * 45,
+		force:   options.forceMin + rnd.Float64()*(options.forceMax-options.forceMin),
+	}
+}
+
+// at returns the touch point of the finger at the point. The pressure, between
+// 0 and 1, scales the force and the contact area, as a finger flattens when
+// pressed harder.
+func (f finger) at(p Point, pressure float64) *input.TouchPoint {
+	size := 0.8 + 0.2*pressure
+
+	return &input.TouchPoint{
+		X:             p.X,
+		Y:             p.Y,
+		RadiusX:       f.radiusX * size,
+		RadiusY:       f.radiusY * size,
+		RotationAngle: f.angle,
+		Force:         f.force * pressure,
+		ID:            f.id,
+	}
+}
+
+// press touches the screen at the point for the duration. The finger lands
+// with growing pressure and drifts by a pixel or so while it rests.
+func press(ctx context.Context, at Point, hold time.Duration, options TouchOptions, rnd *rand.Rand) error {
+	// Fingers report their position at about the rate of a mouse.
+	steps := int(hold / pathInterval)
+	if steps < 1 {
+		steps = 1
+	}
+
+	f := newFinger(1, options, rnd)
+
+	if err := input.DispatchTouchEvent(input.TouchStart, []*input.TouchPoint{f.at(at, 0.5)}).Do(ctx); err != nil {
+		return err
+	}
+
+	drift := at
+
+	for i := 1; i <= steps; i++ {
+		if err := sleepContext(ctx, hold/time.Duration(steps)); err != nil {
+			return err
+		}
+
+		drift = Point{
+			X: at.X + math.Max(-1.5, math.Min(1.5, drift.X-at.X+rnd.NormFloat64()*0.3)),
+			Y: at.Y + math.Max(-1.5, math.Min(1.5, drift.Y-at.Y+rnd.NormFloat64()*0.3)),
+		}
+
+		if err := input.DispatchTouchEvent(input.TouchMove, []*input.TouchPoint{f.at(drift, 1)}).Do(ctx); err != nil {
+			return err
+		}
+	}
+
+	return input.DispatchTouchEvent(input.TouchEnd, []*input.TouchPoint{}).Do(ctx)
+}
+
+// swipe moves a finger from one point to the other in the duration. A fling
+// lifts the finger while moving, otherwise it comes to rest before lifting.
+func swipe(ctx context.Context, from, to Point, duration time.Duration, fling bool, options TouchOptions, rnd *rand.Rand) error {
+	f := newFinger(1, options, rnd)
+
+	if err := input.DispatchTouchEvent(input.TouchStart, []*input.TouchPoint{f.at(from, 0.5)}).Do(ctx); err != nil {
+		return err
+	}
+
+	for _, p := range swipePoints(from, to, duration, fling, rnd) {
+		if err := sleepContext(ctx, p.Delay); err != nil {
+			return err
+		}
+
+		if err := input.DispatchTouchEvent(input.TouchMove, []*input.TouchPoint{f.at(p.Point, 1)}).Do(ctx); err != nil {
+			return err
+		}
+	}
+
+	if !fling {
+		if err := sleepContext(ctx, randDuration(rnd, 60*time.Millisecond, 120*time.Millisecond)); err != nil {
+			return err
+		}
+	}
+
+	return input.DispatchTouchEvent(input.TouchEnd, []*input.TouchPoint{}).Do(ctx)
+}
+
+// flingCutoff is the part of a minimum jerk movement a fling covers before
+// the finger leaves the screen, still at speed.
+const flingCutoff = 0.8
+
+// swipePoints returns the points of a swipe along a slight arc. A fling keeps
+// accelerating longer and ends at speed, a drag slows down to a stop.
+func swipePoints(from, to Point, duration time.Duration, fling bool, rnd *rand.Rand) []PathPoint {
+	c1, c2 := bezierControls(from, to, 0.12, rnd)
+
+	steps := int(duration / pathInterval)
+	if steps < 2 {
+		steps = 2
+	}
+
+	points := make([]PathPoint, 0, steps)
+
+	for i := 1; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+
+		progress := minimumJerk(t)
+		if fling {
+			progress = minimumJerk(t*flingCutoff) / minimumJerk(flingCutoff)
+		}
+
+		points = append(points, PathPoint{
+			Point: bezierCubic(from, c1, c2, to, progress),
+			Delay: duration / time.Duration(steps),
+		})
+	}
+
+	points[len(points)-1].Point = to
+
+	return points
+}
+
+// touchIntoView swipes the node into view, swiping in nested scroll
+// containers as needed, and returns the visible part of the node.
+func touchIntoView(ctx context.Context, nodeID cdp.NodeID, options TouchOptions, rnd *rand.Rand) (Box, error) {
+	return bringIntoView(ctx, nodeID, rnd, func(step scrollStep) error {
+		from, to := scrollSwipe(step, rnd)
+
+		// Dragging the content takes longer than a flick, and ends at rest
+		// so the content stops where it should.
+		duration := randDuration(rnd, options.swipeMin, options.swipeMax) * 3 / 2
+
+		return swipe(ctx, from, to, duration, false, options, rnd)
+	})
+}
+
+// scrollSwipe returns the start and end of a swipe scrolling the area of the
+// step towards its distance, at most 60% of the area at a time. The finger
+// moves against the scroll direction.
+func scrollSwipe(step scrollStep, rnd *rand.Rand) (Point, Point) {
+	limit := func(delta, size float64) float64 {
+		return math.Max(-0.6*size, math.Min(0.6*size, delta))
+	}
+
+	dx, dy := limit(step.dx, step.area.Width), limit(step.dy, step.area.Height)
+	c := step.area.Center()
+
+	// Off the center line a little, as fingers rarely swipe straight.
+	jitter := func(size float64) float64 {
+		return math.Max(-0.1*size, math.Min(0.1*size, rnd.NormFloat64()*size*0.05))
+	}
+
+	from := Point{X: c.X + dx/2 + jitter(step.area.Width), Y: c.Y + dy/2 + jitter(step.area.Height)}
+
+	return from, Point{X: from.X - dx, Y: from.Y - dy}
+}
@@ -0,0 +1,147 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMobilePersona(t *testing.T) {
+	p := MobilePersona("116.0.5845.188")
+	require.NoError(t, p.Validate())
+	require.Contains(t, p.UserAgent, "Chrome/116.0.0.0 Mobile")
+	require.True(t, p.ClientHints.Mobile)
+	require.Equal(t, "Android", p.ClientHints.Platform)
+	require.Equal(t, "Linux armv8l", p.Platform)
+
+	noTouch := p
+	noTouch.MaxTouchPoints = 0
+	require.ErrorIs(t, noTouch.Validate(), ErrInvalidPersona)
+}
+
+func TestSwipePoints(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+	from, to := Point{X: 200, Y: 600}, Point{X: 210, Y: 200}
+
+	speed := func(points []PathPoint, i int) float64 {
+		a, b := points[i-1].Point, points[i].Point
+
+		return math.Hypot(b.X-a.X, b.Y-a.Y)
+	}
+
+	for _, fling := range []bool{false, true} {
+		points := swipePoints(from, to, 300*time.Millisecond, fling, rnd)
+		require.Len(t, points, 18)
+		require.Equal(t, to, points[len(points)-1].Point)
+
+		last := speed(points, len(points)-1)
+		if fling {
+			// A fling leaves the screen at speed.
+			require.Greater(t, last, 15.0)
+		} else {
+			require.Less(t, last, 2.0)
+		}
+	}
+}
+
+func TestFinger(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+	f := newFinger(3, defaultTouchOptions, rnd)
+
+	light, firm := f.at(Point{X: 10, Y: 20}, 0.5), f.at(Point{X: 10, Y: 20}, 1)
+	require.Equal(t, 3.0, firm.ID)
+	require.InDelta(t, f.force, firm.Force, 1e-9)
+	require.Less(t, light.Force, firm.Force)
+	require.Less(t, light.RadiusX, firm.RadiusX)
+	require.Greater(t, firm.RadiusY, firm.RadiusX)
+	require.GreaterOrEqual(t, firm.RadiusX, defaultTouchOptions.radiusMin)
+	require.LessOrEqual(t, firm.RadiusX, defaultTouchOptions.radiusMax)
+}
+
+func TestScrollSwipe(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+	area := Box{Width: 400, Height: 800}
+
+	from, to := scrollSwipe(scrollStep{area: area, dy: 2000}, rnd)
+	require.InDelta(t, -480, to.Y-from.Y, 1e-9)
+	require.Equal(t, from.X, to.X)
+	require.Greater(t, from.Y, 400.0)
+	require.Less(t, from.Y, 800.0)
+
+	from, to = scrollSwipe(scrollStep{area: area, dx: -100}, rnd)
+	require.InDelta(t, 100, to.X-from.X, 1e-9)
+}
+
+func TestTouch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><meta name="viewport" content="width=device-width"></head><body>
+			<div style="height: 3000px">top</div>
+			<button id="button" style="width: 120px; height: 48px">tap me</button>
+			<div style="height: 1000px"></div>
+			<script>
+				window.taps = 0;
+				window.radius = 0;
+				window.trusted = true;
+				document.addEventListener('touchstart', (e) => {
+					window.radius = Math.max(window.radius, e.touches[0].radiusX);
+					window.trusted = window.trusted && e.isTrusted;
+				});
+				document.getElementById('button').addEventListener('click', () => window.taps++);
+			</script>
+		</body></html>`)
+	}))
+	defer srv.Close()
+
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(60*time.Second),
+			WithHeadless(),
+			WithPersona(MobilePersona("116.0.5845.188")),
+		),
+		func(ctx context.Context) error {
+			var (
+				taps, touchPoints int
+				radius, scroll    float64
+				trusted           bool
+			)
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate(srv.URL),
+				Tap(`#button`, WithTouchQueryOptions(chromedp.ByQuery)),
+				Swipe(Point{X: 200, Y: 300}, Point{X: 205, Y: 600}),
+				chromedp.Sleep(time.Second),
+				chromedp.Evaluate(`window.taps`, &taps),
+				chromedp.Evaluate(`window.radius`, &radius),
+				chromedp.Evaluate(`window.trusted`, &trusted),
+				chromedp.Evaluate(`window.scrollY`, &scroll),
+				chromedp.Evaluate(`navigator.maxTouchPoints`, &touchPoints),
+			); err != nil {
+				return err
+			}
+
+			if taps != 1 || !trusted {
+				return fmt.Errorf("got %d taps, trusted %v", taps, trusted)
+			}
+
+			if radius < defaultTouchOptions.radiusMin*0.8 || touchPoints != 5 {
+				return fmt.Errorf("got radius %v, %d touch points", radius, touchPoints)
+			}
+
+			// The button is far down, swiping down scrolls back up a little.
+			if scroll < 1000 {
+				return fmt.Errorf("scrolled to %v", scroll)
+			}
+
+			return nil
+		},
+	)
+}
@@ -0,0 +1,382 @@
+package chromedpundetected
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// Errors.
+var (
+	ErrClipboardUnavailable = errors.New("clipboard not available, the page needs a secure origin")
+)
+
+// TypeOptions contains options for typing.
+type TypeOptions struct {
+	wpm    float64
+	typos  float64
+	paste  bool
+	layout *KeyboardLayout
+}
+
+// Default values for typing.
+var defaultTypeOptions = TypeOptions{
+	wpm:    60,
+	layout: LayoutUS,
+}
+
+// TypeOptionSetter defines a function type to set typing options.
+type TypeOptionSetter func(*TypeOptions)
+
+// WithWPM returns a TypeOptionSetter that sets the typing speed in words, of
+// five characters, per minute. Defaults to 60.
+func WithWPM(wpm float64) TypeOptionSetter {
+	return func(opt *TypeOptions) {
+		opt.wpm = wpm
+	}
+}
+
+// WithTypos returns a TypeOptionSetter that sets the probability, between 0
+// and 1, of hitting a key next to the intended one for every letter. Typos
+// are noticed after a moment and corrected with backspace. Defaults to 0.
+func WithTypos(rate float64) TypeOptionSetter {
+	return func(opt *TypeOptions) {
+		opt.typos = rate
+	}
+}
+
+// WithKeyboardLayout returns a TypeOptionSetter that sets the keyboard layout
+// that decides which keys, modifiers and dead keys type a character, e.g.
+// LayoutDE. Defaults to LayoutUS.
+func WithKeyboardLayout(layout *KeyboardLayout) TypeOptionSetter {
+	return func(opt *TypeOptions) {
+		opt.layout = layout
+	}
+}
+
+// WithPaste returns a TypeOptionSetter that pastes the text at once with the
+// paste shortcut of the platform, instead of typing it key by key. The text is
+// put on the clipboard of the browser, so pasting needs a secure origin.
+func WithPaste() TypeOptionSetter {
+	return func(opt *TypeOptions) {
+		opt.paste = true
+	}
+}
+
+// Type types the text into the focused element like a human would, with key
+// events for every character.
+//
+// The time between key presses follows the typing speed, varying per key:
+// common letter pairs and keys typed with alternating hands are faster,
+// shifted characters, digits and symbols are slower, and there are pauses
+// between words and sentences. Special keys of the kb package, like kb.Enter,
+// can be part of the text.
+//
+// Characters are typed with the keys and modifiers of the keyboard layout,
+// using dead keys for accents the layout has no key for. Chinese, Japanese
+// and Korean text is entered with IME compositions, and other characters the
+// layout can not type are inserted as an emoji picker does.
+func Type(text string, setters ...TypeOptionSetter) chromedp.ActionFunc {
+	options := defaultTypeOptions
+
+	for _, setter := range setters {
+		setter(&options)
+	}
+
+	if options.layout == nil {
+		options.layout = defaultTypeOptions.layout
+	}
+
+	return func(ctx context.Context) error {
+		rnd := randFor(ctx)
+
+		if options.paste {
+			return paste(ctx, text, options.layout, rnd)
+		}
+
+		k := keyboard{layout: options.layout, rnd: rnd}
+
+		for _, stroke := range typingPlan([]rune(text), options, rnd) {
+			if err := sleepContext(ctx, stroke.delay); err != nil {
+				return err
+			}
+
+			if err := k.typeRune(ctx, stroke); err != nil {
+				return err
+			}
+		}
+
+		return k.finish(ctx)
+	}
+}
+
+// keystroke is a key press of the typing plan.
+type keystroke struct {
+	r rune
+
+	// delay is the time to wait before pressing the key, hold the time the
+	// key stays down.
+	delay time.Duration
+	hold  time.Duration
+}
+
+// paste puts the text on the clipboard and presses the paste shortcut of the
+// platform, Meta+V on Apple devices and Control+V elsewhere. The key runs the
+// paste command of the browser, so the page gets a trusted paste event with
+// the clipboard data, as with a paste by the user.
+func paste(ctx context.Context, text string, layout *KeyboardLayout, rnd *rand.Rand) error {
+	var apple bool
+	if err := EvaluateIsolated(appleDeviceJS, &apple).Do(ctx); err != nil {
+		return fmt.Errorf("get platform: %w", err)
+	}
+
+	shortcut := "Control+V"
+	if apple {
+		shortcut = "Meta+V"
+	}
+
+	c, err := parseChord(shortcut, layout)
+	if err != nil {
+		return err
+	}
+
+	// Chrome does not map shortcuts of key events to editing commands on
+	// macOS, so the command is sent with the key. It replaces the mapping on
+	// other platforms.
+	c.key.Commands = []string{"paste"}
+
+	if err := writeClipboard(ctx, text); err != nil {
+		return err
+	}
+
+	// Reaching for the shortcut.
+	if err := sleepContext(ctx, randDuration(rnd, 60*time.Millisecond, 180*time.Millisecond)); err != nil {
+		return err
+	}
+
+	return pressChord(ctx, c, layout, rnd, nil)
+}
+
+// writeClipboard puts the text on the clipboard, from the isolated world so
+// the page doesn't notice. The page is granted permission to write the
+// clipboard first, which needs a secure context.
+func writeClipboard(ctx context.Context, text string) error {
+	// Permissions are granted by the browser, not the tab.
+	if c := chromedp.FromContext(ctx); c != nil && c.Browser != nil {
+		grant := browser.SetPermission(&browser.PermissionDescriptor{Name: "clipboard-write"}, browser.PermissionSettingGranted)
+		if c.BrowserContextID != "" {
+			grant = grant.WithBrowserContextID(c.BrowserContextID)
+		}
+
+		if err := grant.Do(cdp.WithExecutor(ctx, c.Browser)); err != nil {
+			return fmt.Errorf("grant clipboard permission: %w", err)
+		}
+	}
+
+	b, err := json.Marshal(text)
+	if err != nil {
+		return err
+	}
+
+	var written bool
+	if err := EvaluateIsolated(fmt.Sprintf(writeClipboardJS, b), &written, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+		return p.WithAwaitPromise(true).WithUserGesture(true)
+	}).Do(ctx); err != nil {
+		return fmt.Errorf("write clipboard: %w", err)
+	}
+
+	if !written {
+		return ErrClipboardUnavailable
+	}
+
+	return nil
+}
+
+// typingPlan returns the keystrokes to type the text with, including typos
+// and their corrections.
+func typingPlan(text []rune, options TypeOptions, rnd *rand.Rand) []keystroke {
+	wpm := options.wpm
+	if wpm <= 0 {
+		wpm = defaultTypeOptions.wpm
+	}
+
+	if options.layout == nil {
+		options.layout = defaultTypeOptions.layout
+	}
+
+	// The average time per character, at five characters per word.
+	base := time.Duration(float64(time.Minute) / (wpm * 5))
+
+	var (
+		strokes []keystroke
+		prev    rune
+	)
+
+	stroke := func(r rune) {
+		strokes = append(strokes, newKeystroke(prev, r, base, options.layout, rnd))
+		prev = r
+	}
+
+	for i, r := range text {
+		if wrong, ok := typo(r, options.typos, options.layout, rnd); ok {
+			stroke(wrong)
+			typed := 1
+
+			// Sometimes the typo is only noticed after the next key.
+			if i+1 < len(text) && unicode.IsLetter(text[i+1]) && rnd.Float64() < 0.3 {
+				stroke(text[i+1])
+				typed++
+			}
+
+			for j := 0; j < typed; j++ {
+				stroke('\b')
+
+				if j == 0 {
+					strokes[len(strokes)-1].delay += randDuration(rnd, 250*time.Millisecond, 700*time.Millisecond)
+				}
+			}
+		}
+
+		stroke(r)
+	}
+
+	return strokes
+}
+
+// newKeystroke returns the keystroke for the rune typed after prev, with the
+// timing scaled from the base interval.
+func newKeystroke(prev, r rune, base time.Duration, layout *KeyboardLayout, rnd *rand.Rand) keystroke {
+	// Log-normal noise, keystroke intervals are skewed to the right.
+	interval := float64(base) * keyFactor(prev, r, layout) * math.Exp(rnd.NormFloat64()*0.3)
+	interval += float64(contextPause(prev, rnd))
+
+	hold := keyHold(rnd)
+
+	// The interval is between two key presses, the hold time is part of it.
+	delay := time.Duration(interval) - hold
+	if delay < 10*time.Millisecond {
+		delay = 10 * time.Millisecond
+	}
+
+	return keystroke{r: r, delay: delay, hold: hold}
+}
+
+// keyHold returns the time a key is held down.
+func keyHold(rnd *rand.Rand) time.Duration {
+	return time.Duration(float64(85*time.Millisecond) * math.Exp(rnd.NormFloat64()*0.2))
+}
+
+// commonBigrams are the most frequent letter pairs in English, typed faster
+// from practice.
+var commonBigrams = map[string]bool{ //nolint:gochecknoglobals
+	"th": true, "he": true, "in": true, "er": true, "an": true, "re": true,
+	"on": true, "at": true, "en": true, "nd": true, "ti": true, "es": true,
+	"or": true, "te": true, "of": true, "ed": true, "is": true, "it": true,
+	"al": true, "ar": true, "st": true, "to": true, "nt": true, "ng": true,
+	"se": true, "ha": true, "as": true, "ou": true, "io": true, "le": true,
+	"ve": true, "co": true, "me": true, "de": true, "hi": true, "ri": true,
+	"ro": true, "ic": true, "ne": true, "ea": true, "ra": true, "ce": true,
+}
+
+// keyFactor returns the factor of the base interval to type r after prev.
+func keyFactor(prev, r rune, layout *KeyboardLayout) float64 {
+	factor := 1.0
+
+	switch {
+	case prev == 0 || prev == ' ' || prev == '\b':
+	case unicode.ToLower(prev) == unicode.ToLower(r):
+		// Double letters are typed with a quick repeat of the same finger.
+		factor = 0.8
+	case commonBigrams[strings.ToLower(string([]rune{prev, r}))]:
+		factor = 0.7
+	case layout.leftHand(prev) != layout.leftHand(r):
+		factor = 0.85
+	default:
+		factor = 1.1
+	}
+
+	switch keys, _ := layout.strokes(r); {
+	case unicode.IsDigit(r), unicode.IsPunct(r), unicode.IsSymbol(r):
+		factor *= 1.4
+	case len(keys) > 1:
+		// A dead key first.
+		factor *= 1.5
+	case len(keys) == 1 && keys[0].level != levelBase:
+		factor *= 1.25
+	}
+
+	if prev == ' ' {
+		// Words start a little slower.
+		factor *= 1.15
+	}
+
+	return factor
+}
+
+// contextPause returns the extra pause before typing a key after prev: after
+// sentences, clauses and lines, and now and then between words to think.
+func contextPause(prev rune, rnd *rand.Rand) time.Duration {
+	switch prev {
+	case '.', '!', '?':
+		return randDuration(rnd, 300*time.Millisecond, 1000*time.Millisecond)
+	case ',', ';', ':':
+		return randDuration(rnd, 100*time.Millisecond, 300*time.Millisecond)
+	case '\n', '\r':
+		return randDuration(rnd, 300*time.Millisecond, 800*time.Millisecond)
+	case ' ':
+		if rnd.Float64() < 0.05 {
+			return randDuration(rnd, 300*time.Millisecond, 900*time.Millisecond)
+		}
+	}
+
+	return 0
+}
+
+// typo returns a key next to the letter r on the layout, with the probability
+// of the rate.
+func typo(r rune, rate float64, layout *KeyboardLayout, rnd *rand.Rand) (rune, bool) {
+	if rate <= 0 || !unicode.IsLetter(r) || rnd.Float64() >= rate {
+		return 0, false
+	}
+
+	neighbors := layout.adjacent(unicode.ToLower(r))
+	if len(neighbors) == 0 {
+		return 0, false
+	}
+
+	wrong := neighbors[rnd.Intn(len(neighbors))]
+	if unicode.IsUpper(r) {
+		wrong = unicode.ToUpper(wrong)
+	}
+
+	return wrong, true
+}
+
+const (
+	// appleDeviceJS returns whether the platform uses the Meta key for
+	// shortcuts.
+	appleDeviceJS = `/^(Mac|iPhone|iPad|iPod)/.test(navigator.platform)`
+
+	// writeClipboardJS writes the text to the clipboard, and returns false if
+	// the page has no clipboard access, as on insecure origins.
+	writeClipboardJS = `(async (text) => {
+	if (!navigator.clipboard) {
+		return false;
+	}
+
+	await navigator.clipboard.writeText(text);
+
+	return true;
+})(%s)`
+)
@@ -0,0 +1,182 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+// applyPlan returns the text the keystrokes leave in an input.
+func applyPlan(strokes []keystroke) string {
+	var typed []rune
+
+	for _, s := range strokes {
+		if s.r == '\b' {
+			typed = typed[:len(typed)-1]
+			continue
+		}
+
+		typed = append(typed, s.r)
+	}
+
+	return string(typed)
+}
+
+func planDuration(strokes []keystroke) time.Duration {
+	var d time.Duration
+	for _, s := range strokes {
+		d += s.delay + s.hold
+	}
+
+	return d
+}
+
+func TestTypingPlan(t *testing.T) {
+	text := []rune("The quick brown fox jumps over the lazy dog. Pack my box with FIVE dozen liquor jugs!")
+
+	for seed := int64(0); seed < 20; seed++ {
+		options := defaultTypeOptions
+		options.typos = 0.1
+
+		strokes := typingPlan(text, options, rand.New(rand.NewSource(seed))) //nolint:gosec
+		require.Equal(t, string(text), applyPlan(strokes))
+		require.Greater(t, len(strokes), len(text))
+
+		for _, s := range strokes {
+			require.GreaterOrEqual(t, s.delay, 10*time.Millisecond)
+			require.Greater(t, s.hold, time.Duration(0))
+		}
+	}
+
+	strokes := typingPlan(text, defaultTypeOptions, rand.New(rand.NewSource(1))) //nolint:gosec
+	require.Len(t, strokes, len(text))
+
+	slow := planDuration(typingPlan(text, TypeOptions{wpm: 30}, rand.New(rand.NewSource(1))))  //nolint:gosec
+	fast := planDuration(typingPlan(text, TypeOptions{wpm: 120}, rand.New(rand.NewSource(1)))) //nolint:gosec
+	require.Greater(t, slow, 2*fast)
+
+	// About 60 words per minute on average.
+	var long []rune
+	for i := 0; i < 50; i++ {
+		long = append(long, []rune("typing is mostly letters and a few spaces ")...)
+	}
+
+	wpm := float64(len(long)) / 5 / planDuration(typingPlan(long, defaultTypeOptions, rand.New(rand.NewSource(1)))).Minutes() //nolint:gosec
+	require.InDelta(t, 60, wpm, 15)
+}
+
+func TestKeyFactor(t *testing.T) {
+	us := LayoutUS
+	require.Less(t, keyFactor('t', 'h', us), keyFactor('a', 'j', us))
+	require.Less(t, keyFactor('a', 'j', us), keyFactor('a', 'f', us))
+	require.Greater(t, keyFactor('a', 'S', us), keyFactor('a', 's', us))
+	require.Greater(t, keyFactor('a', '7', us), keyFactor('a', 's', us))
+	require.Equal(t, 1.0, keyFactor(0, 'a', us))
+
+	// Alternating hands depends on the layout, z is typed with the right
+	// hand on a German keyboard.
+	require.Less(t, keyFactor('a', 'z', LayoutDE), keyFactor('a', 'z', us))
+}
+
+func TestTypo(t *testing.T) {
+	require.ElementsMatch(t, []rune("rtdgcv"), LayoutUS.adjacent('f'))
+	require.ElementsMatch(t, []rune("wa"), LayoutUS.adjacent('q'))
+	require.ElementsMatch(t, []rune("zugjbn"), LayoutDE.adjacent('h'))
+	require.Empty(t, LayoutUS.adjacent('é'))
+
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+
+	wrong, ok := typo('F', 1, LayoutUS, rnd)
+	require.True(t, ok)
+	require.Contains(t, "RTDGCV", string(wrong))
+
+	_, ok = typo('f', 0, LayoutUS, rnd)
+	require.False(t, ok)
+
+	_, ok = typo('.', 1, LayoutUS, rnd)
+	require.False(t, ok)
+}
+
+func TestType(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body>
+			<input id="name">
+			<textarea id="notes"></textarea>
+			<script>
+				window.keys = 0;
+				window.trusted = true;
+				document.addEventListener('keydown', (e) => {
+					window.keys++;
+					window.trusted = window.trusted && e.isTrusted;
+				});
+				window.pastes = [];
+				document.addEventListener('paste', (e) => {
+					window.pastes.push({ text: e.clipboardData.getData('text/plain'), trusted: e.isTrusted });
+				});
+			</script>
+		</body></html>`)
+	}))
+	defer srv.Close()
+
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(60*time.Second),
+			WithHeadless(),
+		),
+		func(ctx context.Context) error {
+			var (
+				name, notes string
+				keys        int
+				trusted     bool
+				pastes      []struct {
+					Text    string `json:"text"`
+					Trusted bool   `json:"trusted"`
+				}
+			)
+
+			start := time.Now()
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate(srv.URL),
+				SendKeys(`#name`, "Hello, World!", chromedp.ByQuery),
+				chromedp.Focus(`#notes`, chromedp.ByQuery),
+				Type("typos get fixed", WithTypos(0.3), WithWPM(200)),
+				Type(" and pasted text", WithPaste()),
+				chromedp.Value(`#name`, &name, chromedp.ByQuery),
+				chromedp.Value(`#notes`, &notes, chromedp.ByQuery),
+				chromedp.Evaluate(`window.keys`, &keys),
+				chromedp.Evaluate(`window.trusted`, &trusted),
+				chromedp.Evaluate(`window.pastes`, &pastes),
+			); err != nil {
+				return err
+			}
+
+			if name != "Hello, World!" || notes != "typos get fixed and pasted text" {
+				return fmt.Errorf("typed %q and %q", name, notes)
+			}
+
+			if len(pastes) != 1 || pastes[0].Text != " and pasted text" || !pastes[0].Trusted {
+				return fmt.Errorf("paste events %+v", pastes)
+			}
+
+			// Keys include Shift presses, typos and the paste shortcut.
+			if !trusted || keys < len("Hello, World!typos get fixed")+2 {
+				return fmt.Errorf("got %d keys, trusted %v", keys, trusted)
+			}
+
+			if time.Since(start) < 2*time.Second {
+				return fmt.Errorf("typing took only %v", time.Since(start))
+			}
+
+			return nil
+		},
+	)
+}
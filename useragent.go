@@ -0,0 +1,247 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"golang.org/x/exp/slog"
+)
+
+// UserAgentOption defines a function type to set user agent override options.
+type UserAgentOption func(*emulation.SetUserAgentOverrideParams)
+
+// WithUAAcceptLanguage returns a UserAgentOption that sets the Accept-Language
+// header, e.g. "en-US,en;q=0.9".
+func WithUAAcceptLanguage(acceptLanguage string) UserAgentOption {
+	return func(p *emulation.SetUserAgentOverrideParams) {
+		p.AcceptLanguage = acceptLanguage
+	}
+}
+
+// WithUAPlatform returns a UserAgentOption that sets navigator.platform, e.g.
+// "Win32".
+func WithUAPlatform(platform string) UserAgentOption {
+	return func(p *emulation.SetUserAgentOverrideParams) {
+		p.Platform = platform
+	}
+}
+
+// WithUAMetadata returns a UserAgentOption that sets the User-Agent Client
+// Hints, reported through navigator.userAgentData and the Sec-CH-UA headers.
+func WithUAMetadata(metadata *emulation.UserAgentMetadata) UserAgentOption {
+	return func(p *emulation.SetUserAgentOverrideParams) {
+		p.UserAgentMetadata = metadata
+	}
+}
+
+// UserAgentOverride overwrites the Chrome user agent.
+//
+// It's better to use this method than emulation.UserAgentOverride.
+//
+// Without metadata, navigator.userAgentData and the Sec-CH-UA headers still
+// report the real browser, so set it with WithUAMetadata or use
+// AutoUserAgentOverride. The override is also applied to workers the tab
+// starts afterwards, which are held until it is in place.
+func UserAgentOverride(userAgent string, opts ...UserAgentOption) chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		params := emulation.SetUserAgentOverride(userAgent)
+
+		for _, opt := range opts {
+			opt(params)
+		}
+
+		if err := cdp.Execute(ctx, "Network.setUserAgentOverride", params, nil); err != nil {
+			return err
+		}
+
+		return overrideWorkers(ctx, params)
+	}
+}
+
+// AutoUserAgentOverride overwrites the Chrome user agent with the one reported
+// by the browser, stripped of headless markers, together with client hints
+// derived from it.
+func AutoUserAgentOverride(opts ...UserAgentOption) chromedp.ActionFunc {
+	return func(ctx context.Context) error {
+		_, product, _, userAgent, _, err := browser.GetVersion().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("get browser version: %w", err)
+		}
+
+		userAgent = stripHeadless(userAgent)
+
+		fullVersion := product[strings.LastIndex(product, "/")+1:]
+
+		opts = append([]UserAgentOption{WithUAMetadata(userAgentMetadata(userAgent, fullVersion))}, opts...)
+
+		return UserAgentOverride(userAgent, opts...).Do(ctx)
+	}
+}
+
+// stripHeadless removes the headless marker from a user agent string.
+func stripHeadless(userAgent string) string {
+	return strings.ReplaceAll(userAgent, "HeadlessChrome", "Chrome")
+}
+
+// userAgentMetadata derives the client hints a Chrome with the user agent and
+// full version reports.
+func userAgentMetadata(userAgent, fullVersion string) *emulation.UserAgentMetadata {
+	major := fullVersion
+	if i := strings.Index(fullVersion, "."); i >= 0 {
+		major = fullVersion[:i]
+	}
+
+	brands := func(version, grease string) []*emulation.UserAgentBrandVersion {
+		return []*emulation.UserAgentBrandVersion{
+			{Brand: "Chromium", Version: version},
+			{Brand: "Not)A;Brand", Version: grease},
+			{Brand: "Google Chrome", Version: version},
+		}
+	}
+
+	metadata := &emulation.UserAgentMetadata{
+		Brands:          brands(major, "24"),
+		FullVersionList: brands(fullVersion, "24.0.0.0"),
+		Architecture:    "x86",
+		Bitness:         "64",
+		Mobile:          strings.Contains(userAgent, " Mobile"),
+	}
+
+	for _, o := range personaOSes {
+		if strings.Contains(userAgent, o.uaMarker) {
+			metadata.Platform = o.hintsPlatform[0]
+			break
+		}
+	}
+
+	switch metadata.Platform {
+	case "Windows":
+		metadata.PlatformVersion = "10.0.0"
+	case "Android":
+		metadata.Architecture = ""
+		metadata.Bitness = ""
+	}
+
+	if strings.Contains(userAgent, "aarch64") || strings.Contains(userAgent, "arm") {
+		metadata.Architecture = "arm"
+	}
+
+	return metadata
+}
+
+// workerOverride holds the user agent override applied to new workers of a
+// tab.
+type workerOverride struct {
+	mu     sync.Mutex
+	params *emulation.SetUserAgentOverrideParams
+}
+
+// workerOverrides maps a tab to its worker override.
+var workerOverrides sync.Map //nolint:gochecknoglobals
+
+// workerFilter matches the worker targets a tab auto-attaches to.
+var workerFilter = target.Filter{ //nolint:gochecknoglobals
+	{Type: "worker"},
+	{Type: "shared_worker"},
+	{Type: "service_worker"},
+}
+
+// overrideWorkers applies the user agent override to workers started by the
+// tab of the context from now on. New workers are paused until the override
+// is set, so their scripts never see the real user agent. Only the first call
+// for a tab starts auto-attaching, later calls replace the override.
+func overrideWorkers(ctx context.Context, params *emulation.SetUserAgentOverrideParams) error {
+	c := chromedp.FromContext(ctx)
+	if c == nil || c.Target == nil {
+		return nil
+	}
+
+	v, loaded := workerOverrides.LoadOrStore(c.Target, &workerOverride{})
+	override := v.(*workerOverride) //nolint:forcetypeassert
+
+	override.mu.Lock()
+	override.params = params
+	override.mu.Unlock()
+
+	if loaded {
+		return nil
+	}
+
+	// Workers stay paused on start as long as the tab auto-attaches, so the
+	// listener that resumes them lasts as long as the tab, not as the action.
+	tctx := tabContext(ctx)
+
+	go func() {
+		<-tctx.Done()
+		workerOverrides.Delete(c.Target)
+	}()
+
+	chromedp.ListenTarget(tctx, func(ev interface{}) {
+		e, ok := ev.(*target.EventAttachedToTarget)
+		if !ok || !e.WaitingForDebugger {
+			return
+		}
+
+		override.mu.Lock()
+		params := override.params
+		override.mu.Unlock()
+
+		// Listeners block the event loop, so attach in the background.
+		go resumeWorker(tctx, e.TargetInfo.TargetID, params)
+	})
+
+	// The filter limits auto-attaching, and so pausing, to workers.
+	if err := target.SetAutoAttach(true, true).
+		WithFlatten(true).
+		WithFilter(workerFilter).
+		Do(ctx); err != nil {
+		workerOverrides.Delete(c.Target)
+
+		return fmt.Errorf("auto attach workers: %w", err)
+	}
+
+	return nil
+}
+
+// resumeWorker sets the user agent override of a worker paused on start, and
+// resumes it. The auto-attached session can not be used from chromedp, so a
+// session of its own is attached to the worker, and detached again once the
+// worker runs. The worker is resumed even if the override fails, a paused
+// worker would stall the page.
+func resumeWorker(ctx context.Context, id target.ID, params *emulation.SetUserAgentOverrideParams) {
+	wctx, cancel := chromedp.NewContext(ctx, chromedp.WithTargetID(id))
+
+	defer func() {
+		// Cancelling a context closes its target, unless chromedp doesn't
+		// know the target ID. Forget it, so only the session is detached and
+		// the worker keeps running.
+		if c := chromedp.FromContext(wctx); c.Target != nil {
+			c.Target.TargetID = ""
+		}
+
+		cancel()
+	}()
+
+	if err := chromedp.Run(wctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		err := cdp.Execute(ctx, "Network.setUserAgentOverride", params, nil)
+		if err != nil {
+			err = fmt.Errorf("override worker user agent: %w", err)
+		}
+
+		if rerr := runtime.RunIfWaitingForDebugger().Do(ctx); rerr != nil && err == nil {
+			err = fmt.Errorf("resume worker: %w", rerr)
+		}
+
+		return err
+	})); err != nil && ctx.Err() == nil {
+		slog.Error("failed to override worker user agent", err, "target", id)
+	}
+}
@@ -0,0 +1,96 @@
+package chromedpundetected
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserAgentMetadata(t *testing.T) {
+	ua := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.0.0 Safari/537.36"
+
+	md := userAgentMetadata(ua, "116.0.5845.96")
+	require.Equal(t, "Windows", md.Platform)
+	require.False(t, md.Mobile)
+	require.True(t, hasBrandVersion(md.Brands, "116", false))
+	require.True(t, hasBrandVersion(md.FullVersionList, "116", true))
+
+	p := Persona{
+		UserAgent:   ua,
+		ClientHints: md,
+		Platform:    "Win32",
+		Screen:      Screen{Width: 1920, Height: 1080},
+		Window:      WindowSize{Width: 1920, Height: 1040},
+	}
+	require.NoError(t, p.Validate())
+}
+
+func TestAutoUserAgentOverride(t *testing.T) {
+	// Only the new headless mode reports "HeadlessChrome", headful Chrome on a
+	// virtual display has nothing to override.
+	testRun(t,
+		n,
+		NewConfig(
+			WithTimeout(20*time.Second),
+			WithHeadlessMode(HeadlessNew),
+		),
+		func(ctx context.Context) error {
+			type agent struct {
+				UserAgent string `json:"userAgent"`
+				Brands    string `json:"brands"`
+			}
+
+			var res struct {
+				Page   agent `json:"page"`
+				Worker agent `json:"worker"`
+			}
+
+			// Workers started after the context of the override ended still
+			// get resumed.
+			overrideCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+
+			if err := chromedp.Run(overrideCtx,
+				AutoUserAgentOverride(WithUAAcceptLanguage("nl-NL,nl;q=0.9")),
+			); err != nil {
+				return err
+			}
+
+			cancel()
+
+			if err := chromedp.Run(ctx,
+				chromedp.Navigate("https://www.example.com/"),
+				chromedp.Evaluate(`(async () => {
+					const agent = () => ({
+						userAgent: navigator.userAgent,
+						brands: navigator.userAgentData.brands.map((b) => b.brand + '/' + b.version).join(','),
+					});
+					const blob = new Blob(['postMessage((' + agent + ')())'], { type: 'text/javascript' });
+					const worker = new Worker(URL.createObjectURL(blob));
+					const got = await new Promise((resolve) => { worker.onmessage = (e) => resolve(e.data); });
+					return { page: agent(), worker: got };
+				})()`, &res, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+					return p.WithAwaitPromise(true)
+				}),
+			); err != nil {
+				return err
+			}
+
+			if strings.Contains(res.Page.UserAgent, "Headless") || !strings.Contains(res.Page.Brands, "Google Chrome") {
+				return fmt.Errorf("override not applied: %+v", res.Page)
+			}
+
+			if res.Worker != res.Page {
+				return fmt.Errorf("worker reports %+v, page %+v", res.Worker, res.Page)
+			}
+
+			return nil
+		},
+	)
+}
@@ -0,0 +1,374 @@
+// Package mmdb provides a minimal reader for MaxMind DB files, such as the
+// GeoLite2 and GeoIP2 City databases.
+//
+// See https://maxmind.github.io/MaxMind-DB/ for the format specification.
+package mmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"os"
+)
+
+// Errors.
+var (
+	ErrInvalidDatabase = errors.New("invalid MaxMind DB")
+	ErrNotFound        = errors.New("address not found")
+	ErrIPv6InIPv4      = errors.New("IPv6 address in IPv4 database")
+)
+
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// Metadata is the metadata of a database.
+type Metadata struct {
+	NodeCount    uint
+	RecordSize   uint
+	IPVersion    uint
+	DatabaseType string
+	BuildEpoch   uint64
+}
+
+// Reader reads a MaxMind DB.
+type Reader struct {
+	Metadata Metadata
+
+	buf        []byte
+	nodeBytes  uint
+	dataOffset uint
+}
+
+// Open reads the database file into memory.
+func Open(path string) (*Reader, error) {
+	b, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	return New(b)
+}
+
+// New creates a reader from the contents of a database file.
+func New(buf []byte) (*Reader, error) {
+	i := bytes.LastIndex(buf, metadataMarker)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: metadata not found", ErrInvalidDatabase)
+	}
+
+	start := uint(i + len(metadataMarker))
+
+	raw, _, err := (&decoder{buf: buf[start:]}).decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode metadata: %s", ErrInvalidDatabase, err.Error())
+	}
+
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata is not a map", ErrInvalidDatabase)
+	}
+
+	r := &Reader{buf: buf}
+	r.Metadata.NodeCount = uint(toUint(m["node_count"]))
+	r.Metadata.RecordSize = uint(toUint(m["record_size"]))
+	r.Metadata.IPVersion = uint(toUint(m["ip_version"]))
+	r.Metadata.BuildEpoch = toUint(m["build_epoch"])
+	r.Metadata.DatabaseType, _ = m["database_type"].(string) //nolint:errcheck
+
+	switch r.Metadata.RecordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("%w: unsupported record size %d", ErrInvalidDatabase, r.Metadata.RecordSize)
+	}
+
+	r.nodeBytes = r.Metadata.RecordSize / 4
+	r.dataOffset = r.Metadata.NodeCount*r.nodeBytes + 16
+
+	if r.dataOffset > start {
+		return nil, fmt.Errorf("%w: search tree exceeds file", ErrInvalidDatabase)
+	}
+
+	return r, nil
+}
+
+// Lookup returns the record of the network containing the IP address,
+// decoded into maps, slices and scalar values.
+func (r *Reader) Lookup(ip net.IP) (any, error) {
+	bits, err := r.addressBits(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	node := uint(0)
+	count := r.Metadata.NodeCount
+
+	for i := 0; i < len(bits)*8 && node < count; i++ {
+		bit := (bits[i/8] >> (7 - uint(i%8))) & 1
+		node = r.record(node, uint(bit))
+	}
+
+	switch {
+	case node == count:
+		return nil, ErrNotFound
+	case node < count:
+		return nil, fmt.Errorf("%w: search tree too deep", ErrInvalidDatabase)
+	}
+
+	offset := node - count - 16
+
+	v, _, err := (&decoder{buf: r.buf[r.dataOffset:]}).decode(offset)
+
+	return v, err
+}
+
+func (r *Reader) addressBits(ip net.IP) ([]byte, error) {
+	if v4 := ip.To4(); v4 != nil {
+		if r.Metadata.IPVersion == 6 {
+			// IPv4 addresses are stored in the ::/96 subtree of IPv6 databases.
+			return append(make([]byte, 12), v4...), nil
+		}
+
+		return v4, nil
+	}
+
+	if r.Metadata.IPVersion == 4 {
+		return nil, ErrIPv6InIPv4
+	}
+
+	if v6 := ip.To16(); v6 != nil {
+		return v6, nil
+	}
+
+	return nil, fmt.Errorf("invalid IP address %q", ip)
+}
+
+// record returns the left (0) or right (1) record of a node.
+func (r *Reader) record(node, bit uint) uint {
+	b := r.buf[node*r.nodeBytes : (node+1)*r.nodeBytes]
+
+	switch r.Metadata.RecordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// Data types.
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// decoder decodes values from a data section.
+type decoder struct {
+	buf []byte
+}
+
+func (d *decoder) bytes(offset, n uint) ([]byte, error) {
+	if offset+n > uint(len(d.buf)) {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidDatabase)
+	}
+
+	return d.buf[offset : offset+n], nil
+}
+
+// decode decodes the value at the offset, and returns the offset after it.
+func (d *decoder) decode(offset uint) (any, uint, error) { //nolint:gocyclo,cyclop,funlen
+	b, err := d.bytes(offset, 1)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ctrl := b[0]
+	offset++
+
+	typ := uint(ctrl >> 5)
+
+	if typ == typePointer {
+		return d.decodePointer(ctrl, offset)
+	}
+
+	if typ == typeExtended {
+		b, err := d.bytes(offset, 1)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		typ = 7 + uint(b[0])
+		offset++
+	}
+
+	size := uint(ctrl & 0x1f)
+
+	if size >= 29 {
+		n := size - 28
+
+		b, err := d.bytes(offset, n)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		offset += n
+
+		switch n {
+		case 1:
+			size = 29 + uint(b[0])
+		case 2:
+			size = 285 + (uint(b[0])<<8 | uint(b[1]))
+		default:
+			size = 65821 + (uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]))
+		}
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]any, size)
+
+		for i := uint(0); i < size; i++ {
+			k, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("%w: map key is not a string", ErrInvalidDatabase)
+			}
+
+			v, next, err := d.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			m[key] = v
+			offset = next
+		}
+
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, 0, size)
+
+		for i := uint(0); i < size; i++ {
+			v, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			a = append(a, v)
+			offset = next
+		}
+
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	b, err = d.bytes(offset, size)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset += size
+
+	switch typ {
+	case typeString:
+		return string(b), offset, nil
+	case typeBytes:
+		return append([]byte(nil), b...), offset, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("%w: invalid double size %d", ErrInvalidDatabase, size)
+		}
+
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), offset, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("%w: invalid float size %d", ErrInvalidDatabase, size)
+		}
+
+		return math.Float32frombits(binary.BigEndian.Uint32(b)), offset, nil
+	case typeUint16, typeUint32, typeUint64:
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+
+		return v, offset, nil
+	case typeInt32:
+		var v uint32
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+
+		return int64(int32(v)), offset, nil
+	case typeUint128:
+		return new(big.Int).SetBytes(b), offset, nil
+	default:
+		return nil, 0, fmt.Errorf("%w: unsupported data type %d", ErrInvalidDatabase, typ)
+	}
+}
+
+// decodePointer decodes a pointer, and the value it points to.
+func (d *decoder) decodePointer(ctrl byte, offset uint) (any, uint, error) {
+	n := uint((ctrl>>3)&0x3) + 1
+
+	b, err := d.bytes(offset, n)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	vvv := uint(ctrl & 0x7)
+
+	var ptr uint
+
+	switch n {
+	case 1:
+		ptr = vvv<<8 | uint(b[0])
+	case 2:
+		ptr = (vvv<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+	case 3:
+		ptr = (vvv<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+	default:
+		ptr = uint(binary.BigEndian.Uint32(b))
+	}
+
+	v, _, err := d.decode(ptr)
+
+	return v, offset + n, err
+}
+
+func toUint(v any) uint64 {
+	switch v := v.(type) {
+	case uint64:
+		return v
+	case int64:
+		return uint64(v)
+	default:
+		return 0
+	}
+}
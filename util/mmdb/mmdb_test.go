@@ -0,0 +1,320 @@
+package mmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/big"
+	"net"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// pointer encodes a pointer to an offset in the data section.
+type pointer uint
+
+// encode writes a value in the MaxMind DB data format.
+func encode(buf *bytes.Buffer, v any) { //nolint:gocyclo,cyclop
+	ctrl := func(typ int, size int) {
+		c := byte(0)
+		if typ <= typeMap {
+			c = byte(typ << 5)
+		}
+
+		var ext []byte
+
+		switch {
+		case size < 29:
+			c |= byte(size)
+		case size < 285:
+			c |= 29
+			ext = []byte{byte(size - 29)}
+		case size < 65821:
+			c |= 30
+			ext = binary.BigEndian.AppendUint16(nil, uint16(size-285))
+		default:
+			c |= 31
+			ext = binary.BigEndian.AppendUint32(nil, uint32(size-65821))[1:]
+		}
+
+		buf.WriteByte(c)
+
+		if typ > typeMap {
+			buf.WriteByte(byte(typ - 7))
+		}
+
+		buf.Write(ext)
+	}
+
+	uintBytes := func(v uint64) []byte {
+		b := binary.BigEndian.AppendUint64(nil, v)
+		for len(b) > 0 && b[0] == 0 {
+			b = b[1:]
+		}
+
+		return b
+	}
+
+	switch v := v.(type) {
+	case pointer:
+		b := binary.BigEndian.AppendUint32(nil, uint32(v-2048))
+		buf.WriteByte(typePointer<<5 | 1<<3 | b[1]&0x7)
+		buf.Write(b[2:])
+	case string:
+		ctrl(typeString, len(v))
+		buf.WriteString(v)
+	case []byte:
+		ctrl(typeBytes, len(v))
+		buf.Write(v)
+	case float64:
+		ctrl(typeDouble, 8)
+		buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(v)))
+	case float32:
+		ctrl(typeFloat, 4)
+		buf.Write(binary.BigEndian.AppendUint32(nil, math.Float32bits(v)))
+	case uint16:
+		b := uintBytes(uint64(v))
+		ctrl(typeUint16, len(b))
+		buf.Write(b)
+	case uint32:
+		b := uintBytes(uint64(v))
+		ctrl(typeUint32, len(b))
+		buf.Write(b)
+	case uint64:
+		b := uintBytes(v)
+		ctrl(typeUint64, len(b))
+		buf.Write(b)
+	case int32:
+		ctrl(typeInt32, 4)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(v)))
+	case *big.Int:
+		ctrl(typeUint128, len(v.Bytes()))
+		buf.Write(v.Bytes())
+	case bool:
+		size := 0
+		if v {
+			size = 1
+		}
+
+		ctrl(typeBool, size)
+	case []any:
+		ctrl(typeArray, len(v))
+
+		for _, e := range v {
+			encode(buf, e)
+		}
+	case map[string]any:
+		ctrl(typeMap, len(v))
+
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			encode(buf, k)
+			encode(buf, v[k])
+		}
+	default:
+		panic("unsupported type")
+	}
+}
+
+// network is a network with its record.
+type network struct {
+	cidr   string
+	record any
+}
+
+// build creates an IPv6 database with the networks.
+func build(t *testing.T, recordSize int, networks ...network) []byte {
+	t.Helper()
+
+	const empty = -1
+
+	var (
+		nodes   = [][2]int{{empty, empty}}
+		data    bytes.Buffer
+		leaves  = map[[2]int]int{}
+		offsets []int
+	)
+
+	// Pad the data section so pointers in records need two bytes.
+	data.Write(make([]byte, 2048))
+
+	for i, n := range networks {
+		_, ipNet, err := net.ParseCIDR(n.cidr)
+		require.NoError(t, err, n.cidr)
+
+		ip := ipNet.IP.To16()
+		ones, _ := ipNet.Mask.Size()
+
+		if ipNet.IP.To4() != nil {
+			ones += 96
+			ip = append(make([]byte, 12), ipNet.IP.To4()...)
+		}
+
+		offsets = append(offsets, data.Len())
+		encode(&data, n.record)
+
+		node := 0
+
+		for b := 0; b < ones; b++ {
+			bit := int(ip[b/8]>>(7-b%8)) & 1
+
+			if b == ones-1 {
+				leaves[[2]int{node, bit}] = i
+				break
+			}
+
+			if nodes[node][bit] == empty {
+				nodes = append(nodes, [2]int{empty, empty})
+				nodes[node][bit] = len(nodes) - 1
+			}
+
+			node = nodes[node][bit]
+		}
+	}
+
+	var out bytes.Buffer
+
+	count := len(nodes)
+
+	for i, n := range nodes {
+		var records [2]uint32
+
+		for bit, child := range n {
+			switch leaf, ok := leaves[[2]int{i, bit}]; {
+			case ok:
+				records[bit] = uint32(count + 16 + offsets[leaf])
+			case child == empty:
+				records[bit] = uint32(count)
+			default:
+				records[bit] = uint32(child)
+			}
+		}
+
+		l, r := records[0], records[1]
+
+		switch recordSize {
+		case 24:
+			out.Write([]byte{byte(l >> 16), byte(l >> 8), byte(l), byte(r >> 16), byte(r >> 8), byte(r)})
+		case 28:
+			out.Write([]byte{byte(l >> 16), byte(l >> 8), byte(l), byte(l>>24)<<4 | byte(r>>24)&0xf, byte(r >> 16), byte(r >> 8), byte(r)})
+		default:
+			out.Write(binary.BigEndian.AppendUint32(nil, l))
+			out.Write(binary.BigEndian.AppendUint32(nil, r))
+		}
+	}
+
+	out.Write(make([]byte, 16))
+	out.Write(data.Bytes())
+	out.Write(metadataMarker)
+	encode(&out, map[string]any{
+		"node_count":                  uint32(count),
+		"record_size":                 uint16(recordSize),
+		"ip_version":                  uint16(6),
+		"database_type":               "Test-City",
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1690000000),
+		"languages":                   []any{"en"},
+		"description":                 map[string]any{"en": "Test database"},
+	})
+
+	return out.Bytes()
+}
+
+func TestLookup(t *testing.T) {
+	nl := map[string]any{
+		"country": map[string]any{"iso_code": "NL"},
+		"location": map[string]any{
+			"latitude":        52.3824,
+			"longitude":       4.8995,
+			"accuracy_radius": uint16(20),
+			"time_zone":       "Europe/Amsterdam",
+		},
+		"is_anycast": false,
+	}
+
+	us := map[string]any{
+		"country": map[string]any{"iso_code": "US"},
+		"location": map[string]any{
+			"latitude":  float64(37),
+			"longitude": float64(-122),
+			"time_zone": "America/Los_Angeles",
+		},
+		"alias":     pointer(2048 + 1),
+		"geoname":   uint32(5332921),
+		"offset":    int32(-8),
+		"big":       new(big.Int).Lsh(big.NewInt(1), 100),
+		"ratio":     float32(0.5),
+		"tags":      []any{"a", "b"},
+		"raw":       []byte{1, 2},
+		"anycast":   true,
+		"long_name": string(bytes.Repeat([]byte("x"), 300)),
+	}
+
+	for _, size := range []int{24, 28, 32} {
+		db := build(t, size,
+			network{cidr: "81.2.69.0/24", record: nl},
+			network{cidr: "2001:db8::/32", record: us},
+		)
+
+		r, err := New(db)
+		require.NoError(t, err, "record size %d", size)
+		require.Equal(t, uint(size), r.Metadata.RecordSize)
+		require.Equal(t, uint(6), r.Metadata.IPVersion)
+		require.Equal(t, "Test-City", r.Metadata.DatabaseType)
+		require.Equal(t, uint64(1690000000), r.Metadata.BuildEpoch)
+
+		v, err := r.Lookup(net.ParseIP("81.2.69.160"))
+		require.NoError(t, err, "record size %d", size)
+		require.Equal(t, map[string]any{
+			"country": map[string]any{"iso_code": "NL"},
+			"location": map[string]any{
+				"latitude":        52.3824,
+				"longitude":       4.8995,
+				"accuracy_radius": uint64(20),
+				"time_zone":       "Europe/Amsterdam",
+			},
+			"is_anycast": false,
+		}, v)
+
+		v, err = r.Lookup(net.ParseIP("2001:db8::1"))
+		require.NoError(t, err, "record size %d", size)
+
+		m, ok := v.(map[string]any)
+		require.True(t, ok)
+		require.Equal(t, "America/Los_Angeles", m["location"].(map[string]any)["time_zone"])
+		require.Equal(t, uint64(5332921), m["geoname"])
+		require.Equal(t, int64(-8), m["offset"])
+		require.Equal(t, 0, new(big.Int).Lsh(big.NewInt(1), 100).Cmp(m["big"].(*big.Int)))
+		require.Equal(t, float32(0.5), m["ratio"])
+		require.Equal(t, []any{"a", "b"}, m["tags"])
+		require.Equal(t, []byte{1, 2}, m["raw"])
+		require.Equal(t, true, m["anycast"])
+		require.Len(t, m["long_name"], 300)
+		// The pointer points to the first key of the first record.
+		require.Equal(t, "country", m["alias"])
+
+		_, err = r.Lookup(net.ParseIP("8.8.8.8"))
+		require.ErrorIs(t, err, ErrNotFound)
+	}
+}
+
+func TestInvalidDatabase(t *testing.T) {
+	_, err := New([]byte("not a database"))
+	require.ErrorIs(t, err, ErrInvalidDatabase)
+
+	var b bytes.Buffer
+	b.Write(metadataMarker)
+	encode(&b, map[string]any{"node_count": uint32(1), "record_size": uint16(12), "ip_version": uint16(4)})
+
+	_, err = New(b.Bytes())
+	require.ErrorIs(t, err, ErrInvalidDatabase)
+}